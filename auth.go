@@ -0,0 +1,536 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/NikhilSharmaWe/marketplace/proto"
+	"github.com/dgrijalva/jwt-go"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+
+	// tokens tell what they are for in their audience, which go-api-boot
+	// otherwise uses for the tenant
+	accessTokenAudience  = "access"
+	refreshTokenAudience = "refresh"
+
+	minPasswordLength = 8
+
+	// shorter secrets, such as the placeholders left in config files, can be
+	// guessed to forge tokens
+	minAccessSecretLength = 32
+)
+
+type callerKey struct{}
+
+// tokenClaims are the claims of the tokens issued to users. Version is the
+// token version of the user when the token was issued, which changing the
+// password or the role of the user bumps to revoke the tokens issued before.
+type tokenClaims struct {
+	jwt.StandardClaims
+	Version int64 `json:"ver"`
+}
+
+// publicMethods can be called without logging in. A token sent to them is
+// still checked, so that they know who is calling.
+var publicMethods = map[string]bool{
+	proto.MarketplaceService_Login_FullMethodName:                         true,
+	proto.MarketplaceService_RefreshToken_FullMethodName:                  true,
+	proto.MarketplaceService_CreateUser_FullMethodName:                    true,
+	proto.MarketplaceService_GetShopByID_FullMethodName:                   true,
+	proto.MarketplaceService_GetShopsByServiceableProducts_FullMethodName: true,
+	proto.MarketplaceService_SearchNearbyShops_FullMethodName:             true,
+	proto.MarketplaceService_SearchShopsInArea_FullMethodName:             true,
+	proto.MarketplaceService_IsShopOpen_FullMethodName:                    true,
+	proto.MarketplaceService_GetDeliveryZones_FullMethodName:              true,
+	proto.MarketplaceService_GetProductByID_FullMethodName:                true,
+	proto.MarketplaceService_SearchProducts_FullMethodName:                true,
+	proto.MarketplaceService_ListCategories_FullMethodName:                true,
+	proto.MarketplaceService_ListProductsInCategory_FullMethodName:        true,
+	proto.MarketplaceService_GetServiceableProducts_FullMethodName:        true,
+	proto.MarketplaceService_GetKNearestShops_FullMethodName:              true,
+}
+
+var (
+	anyRole      = []string{RoleCustomer, RoleMerchant, RoleAdmin}
+	merchantRole = []string{RoleMerchant, RoleAdmin}
+	adminRole    = []string{RoleAdmin}
+
+	// shopRole is for the methods managing a shop, which its staff call
+	// whatever their role; authorizeShop checks they belong to the shop
+	shopRole = anyRole
+)
+
+// methodRoles lists the roles that may call each method needing a login.
+// Methods taking a user or a shop also check that it is the caller's own, and
+// methods left out cannot be called at all.
+var methodRoles = map[string][]string{
+	proto.MarketplaceService_CreateShop_FullMethodName:        merchantRole,
+	proto.MarketplaceService_GetShopForUser_FullMethodName:    anyRole,
+	proto.MarketplaceService_SetDeliveryZones_FullMethodName:  merchantRole,
+	proto.MarketplaceService_SetOperatingHours_FullMethodName: merchantRole,
+
+	proto.MarketplaceService_CreateProduct_FullMethodName:        adminRole,
+	proto.MarketplaceService_SetProductCategories_FullMethodName: adminRole,
+	proto.MarketplaceService_AddProductVariant_FullMethodName:    adminRole,
+	proto.MarketplaceService_CreateCategory_FullMethodName:       adminRole,
+
+	proto.MarketplaceService_UpdateInventory_FullMethodName:        shopRole,
+	proto.MarketplaceService_GetInventory_FullMethodName:           shopRole,
+	proto.MarketplaceService_ListInventoryMovements_FullMethodName: shopRole,
+	proto.MarketplaceService_GetStockAt_FullMethodName:             shopRole,
+	proto.MarketplaceService_TransferStock_FullMethodName:          shopRole,
+	proto.MarketplaceService_SetReorderThreshold_FullMethodName:    shopRole,
+	proto.MarketplaceService_ListLowStock_FullMethodName:           shopRole,
+	proto.MarketplaceService_ListShopInventory_FullMethodName:      shopRole,
+	proto.MarketplaceService_BulkUpdateInventory_FullMethodName:    shopRole,
+
+	proto.MarketplaceService_ReserveStock_FullMethodName:       shopRole,
+	proto.MarketplaceService_CommitReservation_FullMethodName:  shopRole,
+	proto.MarketplaceService_ReleaseReservation_FullMethodName: shopRole,
+
+	proto.MarketplaceService_AddServiceableProduct_FullMethodName: shopRole,
+	proto.MarketplaceService_SetShopPrice_FullMethodName:          shopRole,
+
+	proto.MarketplaceService_GetUserByID_FullMethodName: anyRole,
+	proto.MarketplaceService_SetUserRole_FullMethodName: adminRole,
+	proto.MarketplaceService_SetPassword_FullMethodName: anyRole,
+
+	proto.MarketplaceService_GetNearestNeighbour_FullMethodName: adminRole,
+	proto.MarketplaceService_GetKNearestUsers_FullMethodName:    adminRole,
+
+	proto.MarketplaceService_PlaceOrder_FullMethodName:        anyRole,
+	proto.MarketplaceService_GetOrder_FullMethodName:          anyRole,
+	proto.MarketplaceService_ListOrdersForUser_FullMethodName: anyRole,
+	proto.MarketplaceService_ListOrdersForShop_FullMethodName: shopRole,
+	proto.MarketplaceService_UpdateOrderStatus_FullMethodName: anyRole,
+
+	proto.MarketplaceService_AddToCart_FullMethodName:      anyRole,
+	proto.MarketplaceService_RemoveFromCart_FullMethodName: anyRole,
+	proto.MarketplaceService_GetCart_FullMethodName:        anyRole,
+	proto.MarketplaceService_Checkout_FullMethodName:       anyRole,
+
+	proto.MarketplaceService_CreatePromotion_FullMethodName: shopRole,
+	proto.MarketplaceService_ValidateCoupon_FullMethodName:  anyRole,
+
+	proto.MarketplaceService_SetTaxRule_FullMethodName:    adminRole,
+	proto.MarketplaceService_ListTaxRules_FullMethodName:  adminRole,
+	proto.MarketplaceService_DeleteTaxRule_FullMethodName: adminRole,
+
+	proto.MarketplaceService_SchedulePriceChange_FullMethodName: shopRole,
+	proto.MarketplaceService_GetPriceHistory_FullMethodName:     shopRole,
+
+	proto.MarketplaceService_InviteStaff_FullMethodName:            merchantRole,
+	proto.MarketplaceService_RevokeStaffInvitation_FullMethodName:  merchantRole,
+	proto.MarketplaceService_ListMyInvitations_FullMethodName:      anyRole,
+	proto.MarketplaceService_AcceptStaffInvitation_FullMethodName:  anyRole,
+	proto.MarketplaceService_DeclineStaffInvitation_FullMethodName: anyRole,
+	proto.MarketplaceService_ListShopStaff_FullMethodName:          merchantRole,
+	proto.MarketplaceService_SetStaffPermissions_FullMethodName:    merchantRole,
+	proto.MarketplaceService_RemoveStaff_FullMethodName:            shopRole,
+	proto.MarketplaceService_ListMyShops_FullMethodName:            shopRole,
+}
+
+// setupAuth makes sure tokens can be signed, lets users be found by email and
+// gives the users created before logins existed the customer role. When
+// ADMIN-EMAIL and ADMIN-PASSWORD are set, an admin with those credentials is
+// created unless a user with the email exists.
+func (app *application) setupAuth() error {
+	if len(os.Getenv("ACCESS-SECRET")) < minAccessSecretLength {
+		return fmt.Errorf("ACCESS-SECRET must be set to at least %d characters to sign access tokens", minAccessSecretLength)
+	}
+
+	collection := getCollection(&app.userRepo.AbstractRepository)
+	_, err := collection.Indexes().CreateOne(app.ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "email", Value: 1}},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+			"email": bson.M{"$type": "string"},
+		}),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateMany(app.ctx,
+		bson.M{"role": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"role": RoleCustomer}},
+	)
+	if err != nil {
+		return err
+	}
+
+	email, password := os.Getenv("ADMIN-EMAIL"), os.Getenv("ADMIN-PASSWORD")
+	if email == "" || password == "" {
+		return nil
+	}
+
+	admin, err := newCredentials(email, password)
+	if err != nil {
+		return err
+	}
+	admin.ID = primitive.NewObjectID().Hex()
+	admin.Name = "admin"
+	admin.Role = RoleAdmin
+	admin.Position = newGeoPoint(admin.Coordinates)
+
+	res, err := collection.UpdateOne(app.ctx,
+		bson.M{"email": admin.Email}, bson.M{"$setOnInsert": admin}, options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return err
+	}
+
+	if res.UpsertedCount > 0 {
+		app.logger.Printf("created admin %s", admin.Email)
+	}
+	return nil
+}
+
+// AuthFuncOverride authenticates every call with the bearer token it carries
+// and checks that the role of the caller may call the method. The caller is
+// kept in the context for the methods to scope what they do to it.
+func (s *GRPCMarketPlaceServer) AuthFuncOverride(ctx context.Context, fullMethodName string) (context.Context, error) {
+	public := publicMethods[fullMethodName]
+	if public && len(metadata.ValueFromIncomingContext(ctx, "authorization")) == 0 {
+		return ctx, nil
+	}
+
+	token, err := bearerToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.tokenUser(token, accessTokenAudience)
+	if err != nil {
+		return nil, err
+	}
+	ctx = context.WithValue(ctx, callerKey{}, user)
+
+	if public {
+		return ctx, nil
+	}
+
+	for _, role := range methodRoles[fullMethodName] {
+		if role == user.Role {
+			return ctx, nil
+		}
+	}
+	return nil, status.Errorf(codes.PermissionDenied, "%s cannot call %s", strings.ToLower(user.Role), fullMethodName)
+}
+
+// callerFrom returns the user calling, nil when a public method is called
+// without logging in.
+func callerFrom(ctx context.Context) *User {
+	user, _ := ctx.Value(callerKey{}).(*User)
+	return user
+}
+
+// authorizeUser checks that the caller may act for the user: it must be the
+// user itself or an admin.
+func authorizeUser(ctx context.Context, userId string) error {
+	caller := callerFrom(ctx)
+	if caller == nil {
+		return status.Error(codes.Unauthenticated, "login required")
+	}
+
+	if caller.ID != userId && caller.Role != RoleAdmin {
+		return status.Errorf(codes.PermissionDenied, "cannot act for user[%s]", userId)
+	}
+	return nil
+}
+
+// authorizeShop checks that the caller may manage the shop with the given
+// permission: it must be its owner, a member of its staff holding the
+// permission or an admin.
+func (s *GRPCMarketPlaceServer) authorizeShop(ctx context.Context, shopId, permission string) error {
+	shop, caller, err := s.shopAndCaller(ctx, shopId)
+	if err != nil || shop == nil {
+		return err
+	}
+
+	if shop.OwnerID == caller.ID {
+		return nil
+	}
+
+	if staff := shop.staffMember(caller.ID); staff != nil && hasPermission(staff.Permissions, permission) {
+		return nil
+	}
+	return status.Errorf(codes.PermissionDenied, "%s is required to manage shop[%s]", permission, shopId)
+}
+
+// authorizeShopOwner checks that the caller owns the shop or is an admin, for
+// what is left to owners such as the settings of the shop and its staff.
+func (s *GRPCMarketPlaceServer) authorizeShopOwner(ctx context.Context, shopId string) error {
+	shop, caller, err := s.shopAndCaller(ctx, shopId)
+	if err != nil || shop == nil {
+		return err
+	}
+
+	if shop.OwnerID != caller.ID {
+		return status.Errorf(codes.PermissionDenied, "only the owner can manage shop[%s]", shopId)
+	}
+	return nil
+}
+
+// shopAndCaller loads the shop for authorizing the caller. The shop is nil for
+// admins, who may manage every shop.
+func (s *GRPCMarketPlaceServer) shopAndCaller(ctx context.Context, shopId string) (*Shop, *User, error) {
+	caller := callerFrom(ctx)
+	if caller == nil {
+		return nil, nil, status.Error(codes.Unauthenticated, "login required")
+	}
+
+	if caller.Role == RoleAdmin {
+		return nil, caller, nil
+	}
+
+	shop, err := getItemOrError(s.svc.shopRepo.FindOneById(shopId))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, nil, status.Errorf(codes.NotFound, "shop[%s] does not exists", shopId)
+	}
+	return shop, caller, nil
+}
+
+// isCaller reports whether the user is the one calling.
+func isCaller(ctx context.Context, userId string) bool {
+	caller := callerFrom(ctx)
+	return caller != nil && caller.ID == userId
+}
+
+// requireAdmin checks that the caller is an admin, for what a method allows
+// merchants to do for their shops but only admins to do for the marketplace.
+func requireAdmin(ctx context.Context, action string) error {
+	caller := callerFrom(ctx)
+	if caller == nil {
+		return status.Error(codes.Unauthenticated, "login required")
+	}
+
+	if caller.Role != RoleAdmin {
+		return status.Errorf(codes.PermissionDenied, "only admins can %s", action)
+	}
+	return nil
+}
+
+func (s *GRPCMarketPlaceServer) Login(ctx context.Context, req *proto.LoginRequest) (*proto.AuthTokens, error) {
+	user, err := getItemOrError(s.svc.userRepo.FindOne(bson.M{"email": normalizeEmail(req.Email)}))
+	if err != nil || user.PasswordHash == "" {
+		return nil, status.Error(codes.Unauthenticated, "invalid email or password")
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password))
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid email or password")
+	}
+
+	return s.issueTokens(user)
+}
+
+// RefreshToken exchanges a refresh token for new tokens, so that a user stays
+// logged in without sending the password again.
+func (s *GRPCMarketPlaceServer) RefreshToken(ctx context.Context, req *proto.RefreshTokenRequest) (*proto.AuthTokens, error) {
+	user, err := s.tokenUser(req.RefreshToken, refreshTokenAudience)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(user)
+}
+
+// tokenUser verifies a token issued for audience and returns the user it was
+// issued to, unless the tokens of the user were revoked since.
+func (s *GRPCMarketPlaceServer) tokenUser(token, audience string) (*User, error) {
+	claims := &tokenClaims{}
+	parsed, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return []byte(os.Getenv("ACCESS-SECRET")), nil
+	})
+	if err != nil || !parsed.Valid || claims.Audience != audience {
+		return nil, status.Errorf(codes.Unauthenticated, "invalid %s token", audience)
+	}
+
+	user, err := getItemOrError(s.svc.userRepo.FindOneById(claims.Id))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, status.Error(codes.Unauthenticated, "user does not exists")
+	}
+
+	if claims.Version != user.TokenVersion {
+		return nil, status.Error(codes.Unauthenticated, "token was revoked, log in again")
+	}
+	return user, nil
+}
+
+// bearerToken returns the token sent in the authorization header of a call.
+func bearerToken(ctx context.Context) (string, error) {
+	values := metadata.ValueFromIncomingContext(ctx, "authorization")
+	if len(values) == 0 {
+		return "", status.Error(codes.Unauthenticated, "login required")
+	}
+
+	scheme, token, found := strings.Cut(values[0], " ")
+	if !found || !strings.EqualFold(scheme, "bearer") {
+		return "", status.Error(codes.Unauthenticated, "bearer token required")
+	}
+	return token, nil
+}
+
+// SetPassword changes the password of a user, who has to give the current one
+// to change their own. Admins set the password, and the email, of any other
+// user, which is how the users created before logins existed get to log in.
+// The tokens issued to the user before are revoked.
+func (s *GRPCMarketPlaceServer) SetPassword(ctx context.Context, req *proto.SetPasswordRequest) (*proto.User, error) {
+	err := authorizeUser(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Email != "" {
+		err = requireAdmin(ctx, "set emails")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	user, err := getItemOrError(s.svc.userRepo.FindOneById(req.UserId))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, status.Errorf(codes.NotFound, "user[%s] does not exists", req.UserId)
+	}
+
+	if isCaller(ctx, user.ID) {
+		err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword))
+		if err != nil {
+			return nil, status.Error(codes.PermissionDenied, "current password is wrong")
+		}
+	}
+
+	email := req.Email
+	if email == "" {
+		email = user.Email
+	}
+	if email == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "user[%s] has no email to log in with", user.ID)
+	}
+
+	credentials, err := newCredentials(email, req.NewPassword)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	_, err = getCollection(&s.svc.userRepo.AbstractRepository).UpdateOne(ctx,
+		bson.M{"_id": user.ID},
+		bson.M{
+			"$set": bson.M{
+				"email":         credentials.Email,
+				"password_hash": credentials.PasswordHash,
+				"updatedOn":     time.Now().Unix(),
+			},
+			"$inc": bson.M{"token_version": 1},
+		},
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, status.Error(codes.AlreadyExists, "email is already used by another user")
+	}
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to set password")
+	}
+
+	user.Email, user.PasswordHash = credentials.Email, credentials.PasswordHash
+	user.TokenVersion++
+	return parseUser(user), nil
+}
+
+func (s *GRPCMarketPlaceServer) issueTokens(user *User) (*proto.AuthTokens, error) {
+	now := time.Now()
+	tokens := &proto.AuthTokens{
+		AccessTokenExpiresAt: now.Add(accessTokenTTL).Unix(),
+		User:                 parseUser(user),
+	}
+
+	var err error
+	tokens.AccessToken, err = signToken(tokenClaims{
+		StandardClaims: jwt.StandardClaims{
+			Id:        user.ID,
+			Audience:  accessTokenAudience,
+			Subject:   user.Role,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: tokens.AccessTokenExpiresAt,
+		},
+		Version: user.TokenVersion,
+	})
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to issue access token")
+	}
+
+	tokens.RefreshToken, err = signToken(tokenClaims{
+		StandardClaims: jwt.StandardClaims{
+			Id:        user.ID,
+			Audience:  refreshTokenAudience,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(refreshTokenTTL).Unix(),
+		},
+		Version: user.TokenVersion,
+	})
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to issue refresh token")
+	}
+
+	return tokens, nil
+}
+
+// signToken signs claims with the user id as the token id.
+func signToken(claims tokenClaims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(os.Getenv("ACCESS-SECRET")))
+}
+
+// newCredentials validates the email and password a user logs in with,
+// returning a user holding them with the password hashed.
+func newCredentials(email, password string) (User, error) {
+	email = normalizeEmail(email)
+	if _, err := mail.ParseAddress(email); err != nil {
+		return User{}, errors.New("invalid email")
+	}
+
+	if len(password) < minPasswordLength {
+		return User{}, fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+
+	// bcrypt ignores anything past 72 bytes, which would let a longer
+	// password be logged in with a prefix of it
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err == bcrypt.ErrPasswordTooLong {
+		return User{}, errors.New("password cannot be longer than 72 bytes")
+	}
+	if err != nil {
+		return User{}, err
+	}
+
+	return User{Email: email, PasswordHash: string(hash)}, nil
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
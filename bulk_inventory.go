@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/NikhilSharmaWe/marketplace/proto"
+)
+
+const (
+	bulkModeSet    = "set"
+	bulkModeAdd    = "add"
+	bulkModeRemove = "remove"
+
+	bulkFormatCSV   = "csv"
+	bulkFormatJSONL = "jsonl"
+)
+
+// inventoryRow is the JSONL form of a row in a bulk inventory upload.
+type inventoryRow struct {
+	ShopID    string `json:"shopId"`
+	ProductID string `json:"productId"`
+	VariantID string `json:"variantId"`
+	Quantity  int32  `json:"quantity"`
+	Mode      string `json:"mode"`
+}
+
+// exportedInventory is the JSONL form of an inventory in an export. It can be
+// uploaded again as is, in which case every quantity is set.
+type exportedInventory struct {
+	ShopID           string `json:"shopId"`
+	ProductID        string `json:"productId"`
+	VariantID        string `json:"variantId,omitempty"`
+	Quantity         int32  `json:"quantity"`
+	Reserved         int32  `json:"reserved"`
+	Available        int32  `json:"available"`
+	ReorderThreshold int32  `json:"reorderThreshold"`
+}
+
+var inventoryExportColumns = []string{"shopId", "productId", "variantId", "quantity", "reserved", "available", "reorderThreshold"}
+
+// bulkFormat picks the format of a bulk inventory request from the format
+// query parameter, falling back to the Content-Type of the body.
+func bulkFormat(r *http.Request, fallback string) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		switch format {
+		case bulkFormatCSV, bulkFormatJSONL:
+			return format, nil
+		}
+		return "", fmt.Errorf("unsupported format %q, expected csv or jsonl", format)
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		if fallback != "" {
+			return fallback, nil
+		}
+		return "", errors.New("missing format, set the format query parameter or the Content-Type header")
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", err
+	}
+
+	switch mediaType {
+	case "text/csv":
+		return bulkFormatCSV, nil
+	case "application/jsonl", "application/x-ndjson", "application/x-jsonlines":
+		return bulkFormatJSONL, nil
+	}
+
+	if fallback != "" {
+		return fallback, nil
+	}
+	return "", fmt.Errorf("unsupported content type %q", mediaType)
+}
+
+// readInventoryRows parses a bulk upload and hands every row to send. Rows
+// that cannot be parsed are not sent but returned as failed results, so the
+// rest of the upload still goes through. The returned error is only set when
+// reading cannot continue.
+func readInventoryRows(format string, r io.Reader, send func(row *proto.BulkInventoryRow) error) ([]*proto.BulkInventoryResult, error) {
+	if format == bulkFormatCSV {
+		return readInventoryCSV(r, send)
+	}
+	return readInventoryJSONL(r, send)
+}
+
+// readInventoryCSV expects a header naming the shopId, productId, quantity and
+// optionally variantId and mode columns, in any order. Other columns are ignored.
+func readInventoryCSV(r io.Reader, send func(row *proto.BulkInventoryRow) error) ([]*proto.BulkInventoryResult, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	for _, name := range []string{"shopid", "productid", "quantity"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("csv header is missing the %s column", name)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rejected []*proto.BulkInventoryResult
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rejected, nil
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rejected = append(rejected, &proto.BulkInventoryResult{
+				Line:  int32(parseErr.Line),
+				Error: parseErr.Err.Error(),
+			})
+			continue
+		}
+		if err != nil {
+			return rejected, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := &proto.BulkInventoryRow{
+			ShopId:    field(record, "shopid"),
+			ProductId: field(record, "productid"),
+			VariantId: field(record, "variantid"),
+			Mode:      field(record, "mode"),
+			Line:      int32(line),
+		}
+
+		quantity, err := strconv.ParseInt(field(record, "quantity"), 10, 32)
+		if err != nil {
+			rejected = append(rejected, &proto.BulkInventoryResult{
+				Line:      row.Line,
+				ShopId:    row.ShopId,
+				ProductId: row.ProductId,
+				VariantId: row.VariantId,
+				Error:     fmt.Sprintf("invalid quantity %q", field(record, "quantity")),
+			})
+			continue
+		}
+		row.Quantity = int32(quantity)
+
+		if err := send(row); err != nil {
+			return rejected, err
+		}
+	}
+}
+
+func readInventoryJSONL(r io.Reader, send func(row *proto.BulkInventoryRow) error) ([]*proto.BulkInventoryResult, error) {
+	scanner := bufio.NewScanner(r)
+	var rejected []*proto.BulkInventoryResult
+	var line int32
+
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var parsed inventoryRow
+		if err := json.Unmarshal([]byte(text), &parsed); err != nil {
+			rejected = append(rejected, &proto.BulkInventoryResult{
+				Line:  line,
+				Error: err.Error(),
+			})
+			continue
+		}
+
+		err := send(&proto.BulkInventoryRow{
+			ShopId:    parsed.ShopID,
+			ProductId: parsed.ProductID,
+			VariantId: parsed.VariantID,
+			Quantity:  parsed.Quantity,
+			Mode:      parsed.Mode,
+			Line:      line,
+		})
+		if err != nil {
+			return rejected, err
+		}
+	}
+
+	return rejected, scanner.Err()
+}
+
+func writeInventoryCSV(w io.Writer, inventories []*proto.Inventory) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(inventoryExportColumns); err != nil {
+		return err
+	}
+
+	for _, inventory := range inventories {
+		err := writer.Write([]string{
+			inventory.ShopId,
+			inventory.ProductId,
+			inventory.VariantId,
+			strconv.Itoa(int(inventory.Quantity)),
+			strconv.Itoa(int(inventory.Reserved)),
+			strconv.Itoa(int(inventory.Available)),
+			strconv.Itoa(int(inventory.ReorderThreshold)),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeInventoryJSONL(w io.Writer, inventories []*proto.Inventory) error {
+	encoder := json.NewEncoder(w)
+	for _, inventory := range inventories {
+		err := encoder.Encode(exportedInventory{
+			ShopID:           inventory.ShopId,
+			ProductID:        inventory.ProductId,
+			VariantID:        inventory.VariantId,
+			Quantity:         inventory.Quantity,
+			Reserved:         inventory.Reserved,
+			Available:        inventory.Available,
+			ReorderThreshold: inventory.ReorderThreshold,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
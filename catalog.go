@@ -0,0 +1,48 @@
+package main
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// setupCatalogIndexes creates the indexes that product search and category
+// browsing depend on, along with the ones keeping variant skus and shop
+// prices unique.
+func (app *application) setupCatalogIndexes() error {
+	_, err := getCollection(&app.productRepo.AbstractRepository).Indexes().CreateMany(app.ctx, []mongo.IndexModel{
+		{
+			// matches in the name weigh more than matches in the description
+			Keys: bson.D{{Key: "name", Value: "text"}, {Key: "description", Value: "text"}},
+			Options: options.Index().
+				SetName("product_text").
+				SetWeights(bson.D{{Key: "name", Value: 10}, {Key: "description", Value: 2}}),
+		},
+		{
+			Keys: bson.D{{Key: "category_ids", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "variants.sku", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"variants.sku": bson.M{"$type": "string"}}),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = getCollection(&app.categoryRepo.AbstractRepository).Indexes().CreateOne(app.ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "ancestor_ids", Value: 1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = getCollection(&app.serviceableRepo.AbstractRepository).Indexes().CreateOne(app.ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "shop_id", Value: 1}, {Key: "product_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+
+	return err
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/NikhilSharmaWe/marketplace/proto"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// errInvalidGeometryCode is returned by mongo when a document cannot be added
+// to a 2dsphere index because its geometry is invalid, e.g. self-intersecting.
+const errInvalidGeometryCode = 16755
+
+// newDeliveryZone validates a delivery zone received over the api and converts
+// its boundary into a closed GeoJSON polygon.
+func newDeliveryZone(zone *proto.DeliveryZone) (DeliveryZone, error) {
+	deliveryFee, err := optionalMoney(zone.DeliveryFee)
+	if err != nil {
+		return DeliveryZone{}, fmt.Errorf("delivery fee of zone %q: %w", zone.Name, err)
+	}
+
+	minOrderValue, err := optionalMoney(zone.MinOrderValue)
+	if err != nil {
+		return DeliveryZone{}, fmt.Errorf("min order value of zone %q: %w", zone.Name, err)
+	}
+
+	if _, err := deliveryFee.Add(minOrderValue); err != nil {
+		return DeliveryZone{}, fmt.Errorf("delivery fee and min order value of zone %q: %w", zone.Name, err)
+	}
+
+	area, err := newGeoPolygon(zone.Boundary)
+	if err != nil {
+		return DeliveryZone{}, fmt.Errorf("boundary of zone %q: %w", zone.Name, err)
+	}
+
+	return DeliveryZone{
+		Name:          zone.Name,
+		Area:          area,
+		DeliveryFee:   deliveryFee,
+		MinOrderValue: minOrderValue,
+	}, nil
+}
+
+func parseDeliveryZone(zone *DeliveryZone) *proto.DeliveryZone {
+	return &proto.DeliveryZone{
+		Name:          zone.Name,
+		Boundary:      polygonBoundary(zone.Area),
+		DeliveryFee:   parseMoney(zone.DeliveryFee),
+		MinOrderValue: parseMoney(zone.MinOrderValue),
+	}
+}
+
+// deliveryZoneFor returns the zone of the shop that coordinates fall in,
+// preferring the cheapest one to deliver to when zones overlap, or nil if the
+// shop does not deliver there.
+func (shop *Shop) deliveryZoneFor(coordinates [2]float64) *DeliveryZone {
+	var match *DeliveryZone
+	for i := range shop.DeliveryZones {
+		zone := &shop.DeliveryZones[i]
+		if !polygonContains(zone.Area, coordinates) {
+			continue
+		}
+
+		if match == nil {
+			match = zone
+			continue
+		}
+
+		// the zones of a shop share a currency, see SetDeliveryZones
+		if cmp, _ := zone.DeliveryFee.Cmp(match.DeliveryFee); cmp < 0 {
+			match = zone
+		}
+	}
+
+	return match
+}
+
+// polygonContains reports whether the [latitude, longitude] coordinates lie
+// inside the outer ring of polygon and outside all of its holes. Edges are
+// treated as straight lines on a flat map rather than along great circles,
+// which makes no practical difference at the size of a delivery zone.
+func polygonContains(polygon GeoPolygon, coordinates [2]float64) bool {
+	if len(polygon.Coordinates) == 0 {
+		return false
+	}
+
+	lng, lat := coordinates[1], coordinates[0]
+	if !ringContains(polygon.Coordinates[0], lng, lat) {
+		return false
+	}
+
+	for _, hole := range polygon.Coordinates[1:] {
+		if ringContains(hole, lng, lat) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ringContains casts a ray from the point and counts how many edges of the
+// closed ring it crosses.
+func ringContains(ring [][2]float64, lng, lat float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a[1] > lat) != (b[1] > lat) && lng < (b[0]-a[0])*(lat-a[1])/(b[1]-a[1])+a[0] {
+			inside = !inside
+		}
+	}
+
+	return inside
+}
+
+// deliversTo builds a pipeline stage keeping the shops that have a delivery
+// zone containing coordinates. Shops without delivery zones are only kept when
+// includeUnzoned is set.
+func deliversTo(coordinates [2]float64, includeUnzoned bool) bson.D {
+	inZone := bson.M{"delivery_zones.area": bson.M{
+		"$geoIntersects": bson.M{"$geometry": newGeoPoint(coordinates)},
+	}}
+	if !includeUnzoned {
+		return bson.D{{Key: "$match", Value: inZone}}
+	}
+
+	return bson.D{{Key: "$match", Value: bson.M{"$or": bson.A{
+		bson.M{"delivery_zones.0": bson.M{"$exists": false}},
+		inZone,
+	}}}}
+}
+
+func isInvalidGeometry(err error) bool {
+	var writeErr mongo.WriteException
+	if !errors.As(err, &writeErr) {
+		return false
+	}
+
+	for _, e := range writeErr.WriteErrors {
+		if e.Code == errInvalidGeometryCode {
+			return true
+		}
+	}
+
+	return false
+}
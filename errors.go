@@ -3,6 +3,16 @@ package main
 import (
 	"fmt"
 	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// not having enough stock is a failure of the request rather than of the
+// server, so these reach clients as a failed precondition
+var (
+	errNegativeInventory = status.Error(codes.FailedPrecondition, "inventory's quantity cannot be negative")
+	errReservedInventory = status.Error(codes.FailedPrecondition, "inventory's quantity cannot drop below the reserved quantity")
 )
 
 func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, status int, message interface{}) {
@@ -25,3 +35,27 @@ func (app *application) methodNotAllowed(w http.ResponseWriter, r *http.Request)
 	message := fmt.Sprintf("the %s method is not supported for this resource", r.Method)
 	app.errorResponse(w, r, http.StatusMethodNotAllowed, message)
 }
+
+// grpcErrorResponse translates an error returned by the grpc client into a
+// response with the matching http status code.
+func (app *application) grpcErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	st := status.Convert(err)
+
+	httpStatus := http.StatusInternalServerError
+	switch st.Code() {
+	case codes.InvalidArgument:
+		httpStatus = http.StatusBadRequest
+	case codes.NotFound:
+		httpStatus = http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		httpStatus = http.StatusConflict
+	case codes.FailedPrecondition:
+		httpStatus = http.StatusUnprocessableEntity
+	case codes.Unauthenticated:
+		httpStatus = http.StatusUnauthorized
+	case codes.PermissionDenied:
+		httpStatus = http.StatusForbidden
+	}
+
+	app.errorResponse(w, r, httpStatus, st.Message())
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"github.com/NikhilSharmaWe/marketplace/proto"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// errBadValueCode is returned by mongo for queries with invalid arguments,
+// such as geometries it cannot use.
+const errBadValueCode = 2
+
+const (
+	// maxNearestResults caps the k of nearest neighbour queries.
+	maxNearestResults = 100
+
+	shopSortDistance     = "distance"
+	shopSortDistanceDesc = "-distance"
+	shopSortName         = "name"
+
+	// area searches matching more shops than the limit return clusters of
+	// them on a grid instead
+	defaultAreaShopLimit = 200
+	maxAreaShopLimit     = 1000
+
+	defaultClusterGridSize = 8
+	maxClusterGridSize     = 32
+)
+
+// nearby pairs a document with its distance from the point it was searched
+// from.
+type nearby[T any] struct {
+	Item         T       `bson:",inline"`
+	DistanceInKM float64 `bson:"distance_km"`
+}
+
+func newGeoPoint(coordinates [2]float64) GeoPoint {
+	return GeoPoint{
+		Type:        "Point",
+		Coordinates: [2]float64{coordinates[1], coordinates[0]},
+	}
+}
+
+// newGeoPolygon builds a GeoJSON polygon out of its vertices in order, closing
+// the ring if it is not already.
+func newGeoPolygon(vertices []*proto.Coordinates) (GeoPolygon, error) {
+	var ring [][2]float64
+	for _, vertex := range vertices {
+		if vertex.Latitude < -90 || vertex.Latitude > 90 || vertex.Longitude < -180 || vertex.Longitude > 180 {
+			return GeoPolygon{}, errors.New("invalid coordinates")
+		}
+
+		point := [2]float64{vertex.Longitude, vertex.Latitude}
+		if len(ring) > 0 && ring[len(ring)-1] == point {
+			continue
+		}
+		ring = append(ring, point)
+	}
+
+	if len(ring) > 1 && ring[0] == ring[len(ring)-1] {
+		ring = ring[:len(ring)-1]
+	}
+	if len(ring) < 3 {
+		return GeoPolygon{}, errors.New("at least 3 distinct vertices are needed")
+	}
+	ring = append(ring, ring[0])
+
+	if ringArea(ring) == 0 {
+		return GeoPolygon{}, errors.New("polygon must enclose an area")
+	}
+
+	return GeoPolygon{
+		Type:        "Polygon",
+		Coordinates: [][][2]float64{ring},
+	}, nil
+}
+
+// ringArea returns the area of a closed ring on the plane of its coordinates,
+// which is zero when all of its vertices lie on a line.
+func ringArea(ring [][2]float64) float64 {
+	var area float64
+	for i := 0; i < len(ring)-1; i++ {
+		area += ring[i][0]*ring[i+1][1] - ring[i+1][0]*ring[i][1]
+	}
+	return math.Abs(area) / 2
+}
+
+// isInvalidQueryGeometry reports whether mongo rejected a query because of
+// the geometry in it, e.g. a polygon crossing itself.
+func isInvalidQueryGeometry(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && cmdErr.Code == errBadValueCode
+}
+
+// boundingBoxPolygon turns a bounding box into a polygon. Its edges follow
+// great circles rather than parallels, so boxes are limited to less than half
+// the globe and may not cross the antimeridian.
+func boundingBoxPolygon(bbox *proto.BoundingBox) (GeoPolygon, error) {
+	sw, ne := bbox.SouthWest, bbox.NorthEast
+	if sw == nil || ne == nil {
+		return GeoPolygon{}, errors.New("bounding box needs both its south west and north east corners")
+	}
+
+	if sw.Latitude >= ne.Latitude || sw.Longitude >= ne.Longitude {
+		return GeoPolygon{}, errors.New("south west corner of the bounding box must be below and left of the north east one")
+	}
+
+	if ne.Longitude-sw.Longitude >= 180 {
+		return GeoPolygon{}, errors.New("bounding box must span less than 180 degrees of longitude")
+	}
+
+	return newGeoPolygon([]*proto.Coordinates{
+		sw,
+		{Latitude: sw.Latitude, Longitude: ne.Longitude},
+		ne,
+		{Latitude: ne.Latitude, Longitude: sw.Longitude},
+	})
+}
+
+// polygonBoundary returns the vertices of the outer ring of polygon the way
+// the api takes them, leaving out the one closing the ring.
+func polygonBoundary(polygon GeoPolygon) []*proto.Coordinates {
+	if len(polygon.Coordinates) == 0 {
+		return nil
+	}
+
+	var boundary []*proto.Coordinates
+	ring := polygon.Coordinates[0]
+	for _, vertex := range ring[:len(ring)-1] {
+		boundary = append(boundary, &proto.Coordinates{
+			Latitude:  vertex[1],
+			Longitude: vertex[0],
+		})
+	}
+	return boundary
+}
+
+// polygonBounds returns the south west and north east corners, in GeoJSON
+// order, of the smallest box containing the outer ring of polygon.
+func polygonBounds(polygon GeoPolygon) (sw, ne [2]float64) {
+	sw = [2]float64{math.Inf(1), math.Inf(1)}
+	ne = [2]float64{math.Inf(-1), math.Inf(-1)}
+	for _, vertex := range polygon.Coordinates[0] {
+		for i := range vertex {
+			sw[i] = math.Min(sw[i], vertex[i])
+			ne[i] = math.Max(ne[i], vertex[i])
+		}
+	}
+
+	return sw, ne
+}
+
+// setupGeoIndexes backfills the GeoJSON position of shops and users stored
+// before it existed and creates the 2dsphere indexes the proximity and
+// delivery zone queries depend on.
+func (app *application) setupGeoIndexes() error {
+	collections := []*mongo.Collection{
+		getCollection(&app.shopRepo.AbstractRepository),
+		getCollection(&app.userRepo.AbstractRepository),
+	}
+
+	backfill := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"position": bson.M{
+				"type": "Point",
+				"coordinates": bson.A{
+					bson.M{"$arrayElemAt": bson.A{"$coordinates", 1}},
+					bson.M{"$arrayElemAt": bson.A{"$coordinates", 0}},
+				},
+			},
+		}}},
+	}
+
+	// a position mongo cannot index would make creating the index fail, so
+	// documents with malformed coordinates are left without one
+	validCoordinates := bson.M{
+		"coordinates.0": bson.M{"$gte": -90, "$lte": 90},
+		"coordinates.1": bson.M{"$gte": -180, "$lte": 180},
+		"coordinates.2": bson.M{"$exists": false},
+	}
+
+	for _, collection := range collections {
+		res, err := collection.UpdateMany(app.ctx,
+			bson.M{"$and": bson.A{bson.M{"position": bson.M{"$exists": false}}, validCoordinates}},
+			backfill,
+		)
+		if err != nil {
+			return err
+		}
+		if res.ModifiedCount > 0 {
+			app.logger.Printf("backfilled position of %d documents in %s", res.ModifiedCount, collection.Name())
+		}
+
+		err = app.logUnpositioned(collection)
+		if err != nil {
+			return err
+		}
+
+		_, err = collection.Indexes().CreateOne(app.ctx, mongo.IndexModel{
+			Keys: bson.D{{Key: "position", Value: "2dsphere"}},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// besides speeding up delivery zone lookups, the index makes mongo reject
+	// zones with invalid polygons such as self-intersecting ones
+	_, err := collections[0].Indexes().CreateOne(app.ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "delivery_zones.area", Value: "2dsphere"}},
+	})
+
+	return err
+}
+
+// logUnpositioned logs the documents of collection that could not be given a
+// position, which proximity queries do not find.
+func (app *application) logUnpositioned(collection *mongo.Collection) error {
+	cursor, err := collection.Find(app.ctx,
+		bson.M{"position": bson.M{"$exists": false}},
+		options.Find().SetProjection(bson.M{"coordinates": 1}),
+	)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(app.ctx)
+
+	for cursor.Next(app.ctx) {
+		var doc struct {
+			ID          string      `bson:"_id"`
+			Coordinates interface{} `bson:"coordinates"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+		app.logger.Printf("skipping %s[%s]: malformed coordinates %v", collection.Name(), doc.ID, doc.Coordinates)
+	}
+
+	return cursor.Err()
+}
+
+// geoNear returns the documents of collection matching query, sorted by their
+// distance from coordinates and limited to maxDistanceInKM unless it is zero.
+// stages are appended to the pipeline after the distance is computed.
+func geoNear[T any](ctx context.Context, collection *mongo.Collection, coordinates [2]float64, query bson.M, maxDistanceInKM float64, stages ...bson.D) ([]nearby[T], error) {
+	near := bson.M{
+		"near":               newGeoPoint(coordinates),
+		"key":                "position",
+		"distanceField":      "distance_km",
+		"distanceMultiplier": 0.001,
+		"spherical":          true,
+	}
+	if query != nil {
+		near["query"] = query
+	}
+	if maxDistanceInKM > 0 {
+		near["maxDistance"] = maxDistanceInKM * 1000
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$geoNear", Value: near}},
+	}
+	pipeline = append(pipeline, stages...)
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []nearby[T]
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
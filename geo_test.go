@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/NikhilSharmaWe/marketplace/proto"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BenchmarkGetShopForUser compares the full scan GetShopForUser used to do,
+// loading every shop and measuring its distance in Go, with the 2dsphere
+// indexed query it makes now.
+func BenchmarkGetShopForUser(b *testing.B) {
+	s := newTestServer(b)
+
+	const (
+		shopCount     = 5000
+		maxDistanceKM = 5
+	)
+
+	err := s.svc.setupGeoIndexes()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	// shops are spread over about 100km around the user, so that only a few
+	// of them are within the searched distance
+	origin := [2]float64{12.97, 77.59}
+	user := User{
+		ID:          primitive.NewObjectID().Hex(),
+		Name:        "benchmark",
+		Coordinates: origin,
+		Position:    newGeoPoint(origin),
+		Role:        RoleCustomer,
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	shops := make([]interface{}, shopCount)
+	shopIds := make([]string, shopCount)
+	for i := range shops {
+		coordinates := [2]float64{
+			origin[0] + rng.Float64() - 0.5,
+			origin[1] + rng.Float64() - 0.5,
+		}
+		shopIds[i] = primitive.NewObjectID().Hex()
+		shops[i] = Shop{
+			ID:          shopIds[i],
+			Name:        "benchmark",
+			Coordinates: coordinates,
+			Position:    newGeoPoint(coordinates),
+		}
+	}
+
+	ctx := context.Background()
+	_, err = getCollection(&s.svc.userRepo.AbstractRepository).InsertOne(ctx, user)
+	if err != nil {
+		b.Fatal(err)
+	}
+	_, err = getCollection(&s.svc.shopRepo.AbstractRepository).InsertMany(ctx, shops)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Cleanup(func() {
+		getCollection(&s.svc.userRepo.AbstractRepository).DeleteOne(ctx, bson.M{"_id": user.ID})
+		getCollection(&s.svc.shopRepo.AbstractRepository).DeleteMany(ctx, bson.M{"_id": bson.M{"$in": shopIds}})
+	})
+
+	ctx = context.WithValue(ctx, callerKey{}, &user)
+
+	b.Run("scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			all, err := getItemOrError(s.svc.shopRepo.Find(nil, nil, 0, 0))
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			result := &proto.Shops{}
+			for j := range all {
+				if calculateDistance(user.Coordinates, all[j].Coordinates) < maxDistanceKM {
+					pshop, err := s.ParseShop(ctx, &all[j])
+					if err != nil {
+						b.Fatal(err)
+					}
+					result.Shops = append(result.Shops, pshop)
+				}
+			}
+		}
+	})
+
+	b.Run("2dsphere", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, err := s.GetShopForUser(ctx, &proto.GetShopForUserRequest{
+				UserId:          user.ID,
+				MaxDistanceInKM: maxDistanceKM,
+				// the full scan did not know about delivery zones either
+				IncludeShopsWithoutZones: true,
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
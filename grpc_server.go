@@ -3,16 +3,26 @@ package main
 import (
 	"context"
 	"errors"
+	"io"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/NikhilSharmaWe/marketplace/proto"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func (svc application) setupGRPCServer() {
 	grpcPriceFetcher := NewGRPCPriceFetcherServer(svc)
 	proto.RegisterMarketplaceServiceServer(svc.goApiBoot.GrpcServer, grpcPriceFetcher)
+
+	go grpcPriceFetcher.sweepExpiredReservations(reservationSweepInterval)
+	go grpcPriceFetcher.runPriceSchedules(priceScheduleInterval)
 }
 
 type GRPCMarketPlaceServer struct {
@@ -26,23 +36,55 @@ func NewGRPCPriceFetcherServer(svc application) *GRPCMarketPlaceServer {
 	}
 }
 
-func (u *GRPCMarketPlaceServer) AuthFuncOverride(ctx context.Context, fullMethodName string) (context.Context, error) {
-	return ctx, nil
-}
-
 func (s *GRPCMarketPlaceServer) CreateShop(ctx context.Context, req *proto.CreateShopRequest) (*proto.Shop, error) {
 	shop := Shop{
-		ID:             primitive.NewObjectID().Hex(),
-		Name:           req.Name,
-		Location:       req.Location,
-		OperationHours: req.Operationhours,
+		ID:       primitive.NewObjectID().Hex(),
+		Name:     req.Name,
+		Location: req.Location,
 		Coordinates: [2]float64{
 			req.Coordinates.Latitude,
 			req.Coordinates.Longitude,
 		},
+		OwnerID: req.OwnerId,
+	}
+	shop.Position = newGeoPoint(shop.Coordinates)
+
+	var err error
+	if shop.OwnerID == "" || isCaller(ctx, shop.OwnerID) {
+		shop.OwnerID = callerFrom(ctx).ID
+	} else {
+		err = requireAdmin(ctx, "create shops for other merchants")
+		if err != nil {
+			return nil, err
+		}
+
+		owner, err := getItemOrError(s.svc.userRepo.FindOneById(shop.OwnerID))
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return nil, status.Error(codes.NotFound, "owner does not exists")
+		}
+
+		if owner.Role != RoleMerchant {
+			return nil, status.Errorf(codes.FailedPrecondition, "user[%s] is not a merchant", owner.ID)
+		}
+	}
+
+	switch {
+	case req.OperatingHours != nil:
+		shop.OperatingHours, err = newOperatingHours(req.OperatingHours)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid operating hours: %v", err)
+		}
+	case req.Operationhours != "":
+		// free-form hours are kept as they were given when they do not parse,
+		// like the ones stored before hours were structured
+		shop.OperatingHours, err = parseHoursText(req.Operationhours, defaultTimeZone())
+		if err != nil {
+			shop.OperationHours = req.Operationhours
+		}
 	}
 
-	err := getErrorFromChan(s.svc.shopRepo.Save(shop))
+	err = getErrorFromChan(s.svc.shopRepo.Save(shop))
 	if err != nil {
 		s.svc.logger.Println("Error: ", err)
 		return nil, errors.New("failed to create shop")
@@ -53,391 +95,587 @@ func (s *GRPCMarketPlaceServer) CreateShop(ctx context.Context, req *proto.Creat
 		Name:           shop.Name,
 		Location:       shop.Location,
 		OperationHours: shop.OperationHours,
+		OperatingHours: parseOperatingHours(shop.OperatingHours),
 		Coordinates: &proto.Coordinates{
 			Latitude:  shop.Coordinates[0],
 			Longitude: shop.Coordinates[1],
 		},
+		OwnerId: shop.OwnerID,
 	}, nil
 }
 
 func (s *GRPCMarketPlaceServer) CreateProduct(ctx context.Context, req *proto.CreateProductRequest) (*proto.Product, error) {
+	price, err := newMoney(req.Price)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "price: %v", err)
+	}
+
+	categoryIds, err := s.checkCategories(req.CategoryIds)
+	if err != nil {
+		return nil, err
+	}
+
 	product := Product{
 		ID:          primitive.NewObjectID().Hex(),
 		Name:        req.Name,
 		Description: req.Description,
-		Price:       float64(req.Price),
+		Price:       price,
+		CategoryIDs: categoryIds,
+		// set here as the product is inserted in a transaction, which
+		// productRepo.Save cannot take part in
+		CreatedOn: time.Now().Unix(),
 	}
 
-	err := getErrorFromChan(s.svc.productRepo.Save(product))
-	if err != nil {
-		s.svc.logger.Println("Error: ", err)
-		return nil, errors.New("failed to create product")
-	}
+	skus := make(map[string]bool)
+	for _, pvariant := range req.Variants {
+		variant, err := newVariant(pvariant)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
 
-	return &proto.Product{
-		Id:          product.ID,
-		Name:        product.Name,
-		Description: product.Description,
-		Price:       float32(product.Price),
-	}, nil
-}
+		if skus[variant.SKU] {
+			return nil, status.Errorf(codes.InvalidArgument, "duplicate sku %s", variant.SKU)
+		}
+		skus[variant.SKU] = true
 
-func (s *GRPCMarketPlaceServer) CreateUser(ctx context.Context, req *proto.CreateUserRequest) (*proto.User, error) {
-	user := User{
-		ID:       primitive.NewObjectID().Hex(),
-		Name:     req.Name,
-		Location: req.Location,
-		Coordinates: [2]float64{
-			req.Coordinates.Latitude,
-			req.Coordinates.Longitude,
-		},
+		product.Variants = append(product.Variants, variant)
 	}
 
-	err := getErrorFromChan(s.svc.userRepo.Save(user))
+	err = s.svc.withTransaction(ctx, func(sessCtx mongo.SessionContext) error {
+		_, err := getCollection(&s.svc.productRepo.AbstractRepository).InsertOne(sessCtx, product)
+		if mongo.IsDuplicateKeyError(err) {
+			return status.Error(codes.AlreadyExists, "sku is already used by another product")
+		}
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return errors.New("failed to create product")
+		}
+
+		changes := []PriceChange{{ProductID: product.ID, NewPrice: &product.Price}}
+		for i := range product.Variants {
+			changes = append(changes, PriceChange{
+				ProductID: product.ID,
+				VariantID: product.Variants[i].ID,
+				NewPrice:  &product.Variants[i].Price,
+			})
+		}
+
+		for _, change := range changes {
+			change.Reason = PriceChangeReasonCreated
+			err = s.recordPriceChange(sessCtx, change)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		s.svc.logger.Println("Error: ", err)
-		return nil, errors.New("failed to create user")
+		return nil, err
 	}
 
-	return &proto.User{
-		Id:       user.ID,
-		Name:     user.Name,
-		Location: user.Location,
-		Coordinates: &proto.Coordinates{
-			Latitude:  user.Coordinates[0],
-			Longitude: user.Coordinates[1],
-		},
-	}, nil
+	return parseProduct(&product), nil
 }
 
-func (s *GRPCMarketPlaceServer) GetShopByID(ctx context.Context, req *proto.GetRequest) (*proto.Shop, error) {
-	id := req.Id
-	shop := &Shop{}
-
-	shop, err := getItemOrError(s.svc.shopRepo.FindOneById(id))
+func (s *GRPCMarketPlaceServer) SetProductCategories(ctx context.Context, req *proto.SetProductCategoriesRequest) (*proto.Product, error) {
+	categoryIds, err := s.checkCategories(req.CategoryIds)
 	if err != nil {
-		s.svc.logger.Println("Error: ", err)
-		return nil, errors.New("failed to get shop")
+		return nil, err
 	}
 
-	products, err := s.GetServiceableProducts(ctx, &proto.GetServiceableProductsRequest{ShopId: id})
+	res, err := getCollection(&s.svc.productRepo.AbstractRepository).UpdateOne(ctx,
+		bson.M{"_id": req.ProductId},
+		bson.M{"$set": bson.M{"category_ids": categoryIds}},
+	)
 	if err != nil {
 		s.svc.logger.Println("Error: ", err)
-		return nil, errors.New("failed to get serviceable products for shop")
+		return nil, errors.New("failed to update product categories")
 	}
 
-	return &proto.Shop{
-		Id:                  shop.ID,
-		Name:                shop.Name,
-		Location:            shop.Location,
-		OperationHours:      shop.OperationHours,
-		ServiceableProducts: products.Products,
-		Coordinates: &proto.Coordinates{
-			Latitude:  shop.Coordinates[0],
-			Longitude: shop.Coordinates[1],
-		},
-	}, nil
+	if res.MatchedCount == 0 {
+		return nil, status.Error(codes.NotFound, "product does not exists")
+	}
+
+	return s.GetProductByID(ctx, &proto.GetRequest{
+		Id: req.ProductId,
+	})
 }
 
-func (s *GRPCMarketPlaceServer) GetProductByID(ctx context.Context, req *proto.GetRequest) (*proto.Product, error) {
-	id := req.Id
-	product := &Product{}
+// checkCategories removes duplicates from a list of category ids, failing if
+// any of them does not exist.
+func (s *GRPCMarketPlaceServer) checkCategories(categoryIds []string) ([]string, error) {
+	categoryIds = uniqueStrings(categoryIds)
+	if len(categoryIds) == 0 {
+		return []string{}, nil
+	}
 
-	product, err := getItemOrError(s.svc.productRepo.FindOneById(id))
+	count, err := getItemOrError(s.svc.categoryRepo.CountDocuments(bson.M{"_id": bson.M{"$in": categoryIds}}))
 	if err != nil {
 		s.svc.logger.Println("Error: ", err)
-		return nil, errors.New("failed to get product")
+		return nil, errors.New("failed to get categories")
 	}
 
-	return &proto.Product{
-		Id:          product.ID,
-		Name:        product.Name,
-		Description: product.Description,
-		Price:       float32(product.Price),
-	}, nil
-}
+	if count != int64(len(categoryIds)) {
+		return nil, status.Error(codes.NotFound, "category does not exists")
+	}
 
-func (s *GRPCMarketPlaceServer) GetUserByID(ctx context.Context, req *proto.GetRequest) (*proto.User, error) {
-	id := req.Id
-	user := &User{}
+	return categoryIds, nil
+}
 
-	user, err := getItemOrError(s.svc.userRepo.FindOneById(id))
+// AddProductVariant adds a variant to an existing product. The stock a product
+// without variants already has becomes the stock of its first variant.
+func (s *GRPCMarketPlaceServer) AddProductVariant(ctx context.Context, req *proto.AddProductVariantRequest) (*proto.Product, error) {
+	variant, err := newVariant(req.Variant)
 	if err != nil {
-		s.svc.logger.Println("Error: ", err)
-		return nil, errors.New("failed to get user: %s")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
-	return &proto.User{
-		Id:       user.ID,
-		Name:     user.Name,
-		Location: user.Location,
-		Coordinates: &proto.Coordinates{
-			Latitude:  user.Coordinates[0],
-			Longitude: user.Coordinates[1],
-		},
-	}, nil
-}
+	err = s.svc.withTransaction(ctx, func(sessCtx mongo.SessionContext) error {
+		product := &Product{}
+		err := getCollection(&s.svc.productRepo.AbstractRepository).FindOneAndUpdate(sessCtx,
+			bson.M{"_id": req.ProductId, "variants.sku": bson.M{"$ne": variant.SKU}},
+			bson.M{"$push": bson.M{"variants": variant}},
+			options.FindOneAndUpdate().SetReturnDocument(options.Before),
+		).Decode(product)
+		if err == mongo.ErrNoDocuments {
+			if !s.svc.productRepo.IsExistsById(req.ProductId) {
+				return status.Error(codes.NotFound, "product does not exists")
+			}
+			return status.Errorf(codes.AlreadyExists, "product already has a variant with sku %s", variant.SKU)
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return status.Error(codes.AlreadyExists, "sku is already used by another product")
+		}
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return errors.New("failed to add variant")
+		}
 
-func (s *GRPCMarketPlaceServer) AddServiceableProduct(ctx context.Context, req *proto.AddServiceableProductRequest) (*proto.Shop, error) {
-	shopId := req.ShopId
-	productId := req.ProductId
-	shop := &Shop{}
+		err = s.recordPriceChange(sessCtx, PriceChange{
+			ProductID: product.ID,
+			VariantID: variant.ID,
+			NewPrice:  &variant.Price,
+			Reason:    PriceChangeReasonCreated,
+		})
+		if err != nil {
+			return err
+		}
 
-	if !s.svc.productRepo.IsExistsById(productId) {
-		s.svc.logger.Printf("Error: product[%s] does not exists", productId)
-		return nil, errors.New("product does not exists")
-	}
+		if len(product.Variants) > 0 {
+			return nil
+		}
 
-	shop, err := getItemOrError(s.svc.shopRepo.FindOneById(shopId))
-	if err != nil {
-		s.svc.logger.Println("Error: ", err)
-		return nil, errors.New("failed to get shop")
-	}
+		filter := bson.M{"product_id": product.ID, "variant_id": ""}
+		update := bson.M{"$set": bson.M{"variant_id": variant.ID}}
 
-	var alreadyExists bool
-	for _, id := range shop.ServiceableProductsId {
-		if id == productId {
-			alreadyExists = true
+		_, err = getCollection(&s.svc.inventoryRepo.AbstractRepository).UpdateMany(sessCtx, filter, update)
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return errors.New("failed to update inventories")
 		}
-	}
 
-	if !alreadyExists {
-		products := append(shop.ServiceableProductsId, productId)
-		shop.ServiceableProductsId = products
-
-		errCh := s.svc.shopRepo.Save(shop)
-		err := getErrorFromChan(errCh)
+		filter["status"] = ReservationStatusActive
+		_, err = getCollection(&s.svc.reservationRepo.AbstractRepository).UpdateMany(sessCtx, filter, update)
 		if err != nil {
 			s.svc.logger.Println("Error: ", err)
-			return nil, errors.New("failed to add serviceable product")
+			return errors.New("failed to update reservations")
 		}
-	}
 
-	_, err = getItemOrError(s.svc.inventoryRepo.FindOne(primitive.M{"shop_id": shopId, "product_id": productId}))
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			inventory := Inventory{
-				ID:        primitive.NewObjectID().Hex(),
-				ShopID:    shopId,
-				ProductID: productId,
-				Quantity:  0,
-			}
+		// order and cart items follow the stock they were or will be taken
+		// from, so that cancelled orders are restocked into it
+		noVariant := bson.M{"$in": bson.A{"", nil}}
+		itemFilter := bson.M{"items": bson.M{"$elemMatch": bson.M{"product_id": product.ID, "variant_id": noVariant}}}
+		itemUpdate := bson.M{"$set": bson.M{"items.$[item].variant_id": variant.ID}}
+		opts := options.Update().SetArrayFilters(options.ArrayFilters{Filters: bson.A{
+			bson.M{"item.product_id": product.ID, "item.variant_id": noVariant},
+		}})
 
-			errCh := s.svc.inventoryRepo.Save(inventory)
-			err := getErrorFromChan(errCh)
-			if err != nil {
-				s.svc.logger.Println("Error: ", err)
-				return nil, errors.New("failed to create inventory")
-			}
-		} else {
+		_, err = getCollection(&s.svc.orderRepo.AbstractRepository).UpdateMany(sessCtx, itemFilter, itemUpdate, opts)
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return errors.New("failed to update orders")
+		}
+
+		_, err = getCollection(&s.svc.cartRepo.AbstractRepository).UpdateMany(sessCtx, itemFilter, itemUpdate, opts)
+		if err != nil {
 			s.svc.logger.Println("Error: ", err)
-			return nil, errors.New("failed while looking for inventory")
+			return errors.New("failed to update carts")
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return s.GetShopByID(ctx, &proto.GetRequest{
-		Id: shopId,
+	return s.GetProductByID(ctx, &proto.GetRequest{
+		Id: req.ProductId,
 	})
 }
 
-func (s *GRPCMarketPlaceServer) GetServiceableProducts(ctx context.Context, req *proto.GetServiceableProductsRequest) (*proto.Products, error) {
-	var serviceableProducts []*proto.Product
-	id := req.ShopId
-	shop := &Shop{}
+func parseProduct(product *Product) *proto.Product {
+	pproduct := &proto.Product{
+		Id:          product.ID,
+		Name:        product.Name,
+		Description: product.Description,
+		Price:       parseMoney(product.Price),
+		CategoryIds: product.CategoryIDs,
+	}
 
-	shop, err := getItemOrError(s.svc.shopRepo.FindOneById(id))
-	if err != nil {
-		s.svc.logger.Println("Error: ", err)
-		return nil, errors.New("failed to get shop")
+	for i := range product.Variants {
+		pproduct.Variants = append(pproduct.Variants, parseVariant(&product.Variants[i]))
 	}
 
-	for _, productId := range shop.ServiceableProductsId {
-		product, err := s.GetProductByID(ctx, &proto.GetRequest{
-			Id: productId,
-		})
+	return pproduct
+}
 
+func (s *GRPCMarketPlaceServer) SearchProducts(ctx context.Context, req *proto.SearchProductsRequest) (*proto.SearchProductsResponse, error) {
+	// searching near a user reveals where the user is
+	if req.UserId != "" {
+		err := authorizeUser(ctx, req.UserId)
 		if err != nil {
-			s.svc.logger.Println("Error: ", err)
-			return nil, errors.New("failed to get product")
+			return nil, err
 		}
-		serviceableProducts = append(serviceableProducts, product)
 	}
 
-	return &proto.Products{
-		Products: serviceableProducts,
-	}, nil
-}
-
-func (s *GRPCMarketPlaceServer) GetInventory(ctx context.Context, req *proto.GetInventoryRequest) (*proto.Inventory, error) {
-	shopId := req.ShopId
-	productId := req.ProductId
-	inventory := &Inventory{}
+	pageSize := int(req.PageSize)
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		return nil, status.Errorf(codes.InvalidArgument, "page size cannot exceed %d", maxPageSize)
+	}
 
-	inventory, err := getItemOrError(s.svc.inventoryRepo.FindOne(primitive.M{"shop_id": shopId, "product_id": productId}))
+	offset, err := decodePageToken(req.PageToken)
 	if err != nil {
-		s.svc.logger.Println("Error: ", err)
-		return nil, errors.New("failed to get inventory")
+		return nil, status.Error(codes.InvalidArgument, "invalid page token")
 	}
 
-	return &proto.Inventory{
-		Id:        inventory.ID,
-		ShopId:    inventory.ShopID,
-		ProductId: inventory.ProductID,
-		Quantity:  int32(inventory.Quantity),
-	}, nil
-}
-
-func (s *GRPCMarketPlaceServer) UpdateInventory(ctx context.Context, req *proto.UpdateInventoryRequest) (*proto.Inventory, error) {
-	shopId := req.ShopId
-	productId := req.ProductId
-	inventory := &Inventory{}
+	match := bson.M{}
+	sort := bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}
+	if query := strings.TrimSpace(req.Query); query != "" {
+		match["$text"] = bson.M{"$search": query}
+		sort = bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}, {Key: "_id", Value: 1}}
+	}
 
-	inventory, err := getItemOrError(s.svc.inventoryRepo.FindOne(primitive.M{"shop_id": shopId, "product_id": productId}))
+	minPrice, err := optionalMoney(req.MinPrice)
 	if err != nil {
-		s.svc.logger.Println("Error: ", err)
-		return nil, errors.New("failed to get inventory")
+		return nil, status.Errorf(codes.InvalidArgument, "min price: %v", err)
+	}
+	maxPrice, err := optionalMoney(req.MaxPrice)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "max price: %v", err)
 	}
 
-	if req.Add {
-		inventory.Quantity = inventory.Quantity + int(req.Change)
-	} else {
-		inventory.Quantity = inventory.Quantity - int(req.Change)
+	cmp, err := minPrice.Cmp(maxPrice)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "min and max price must be in the same currency")
+	}
+	if req.MinPrice != nil && req.MaxPrice != nil && cmp > 0 {
+		return nil, status.Error(codes.InvalidArgument, "min price cannot exceed max price")
 	}
 
-	if inventory.Quantity < 0 {
-		return nil, errors.New("inventory's quantity cannot be negative")
+	price := bson.M{}
+	if req.MinPrice != nil {
+		price["$gte"] = minPrice.MinorUnits
+		match["price.currency_code"] = minPrice.CurrencyCode
+	}
+	if req.MaxPrice != nil {
+		price["$lte"] = maxPrice.MinorUnits
+		match["price.currency_code"] = maxPrice.CurrencyCode
+	}
+	if len(price) > 0 {
+		match["price.minor_units"] = price
 	}
 
-	errCh := s.svc.inventoryRepo.Save(inventory)
-	err = getErrorFromChan(errCh)
-	if err != nil {
-		s.svc.logger.Println("Error: ", err)
-		return nil, errors.New("failed to update inventory")
+	if req.CategoryId != "" {
+		categoryIds, err := s.categorySubtree(req.CategoryId)
+		if err != nil {
+			return nil, err
+		}
+		match["category_ids"] = bson.M{"$in": categoryIds}
 	}
 
-	return &proto.Inventory{
-		Id:        inventory.ID,
-		ShopId:    inventory.ShopID,
-		ProductId: inventory.ProductID,
-		Quantity:  int32(inventory.Quantity),
-	}, nil
-}
+	if req.UserId != "" {
+		productIds, err := s.productsNearUser(ctx, req.UserId, req.MaxDistanceInKM, req.IncludeShopsWithoutZones)
+		if err != nil {
+			return nil, err
+		}
+		match["_id"] = bson.M{"$in": productIds}
+	}
 
-func (s *GRPCMarketPlaceServer) GetShopsByServiceableProducts(ctx context.Context, req *proto.GetShopsByServiceableProductsRequest) (*proto.Shops, error) {
-	var shops []Shop
-	productId := req.ProductId
-	filter := bson.M{"serviceableProductsId": productId}
+	// one more than the page size tells whether there is a next page
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: sort}},
+		{{Key: "$skip", Value: offset}},
+		{{Key: "$limit", Value: pageSize + 1}},
+	}
 
-	shops, err := getItemOrError(s.svc.shopRepo.Find(filter, nil, 0, 0))
+	cursor, err := getCollection(&s.svc.productRepo.AbstractRepository).Aggregate(ctx, pipeline)
 	if err != nil {
 		s.svc.logger.Println("Error: ", err)
-		return nil, errors.New("failed to get shops")
+		return nil, errors.New("failed to search products")
 	}
 
-	resultShops := proto.Shops{}
+	var products []Product
+	if err := cursor.All(ctx, &products); err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to search products")
+	}
 
-	for _, shop := range shops {
-		pshop, err := s.ParseShop(ctx, &shop)
-		if err != nil {
-			s.svc.logger.Println("Error: ", err)
-			return nil, errors.New("failed to parse data")
-		}
+	result := &proto.SearchProductsResponse{}
+	if len(products) > pageSize {
+		products = products[:pageSize]
+		result.NextPageToken = encodePageToken(offset + pageSize)
+	}
 
-		resultShops.Shops = append(resultShops.Shops, pshop)
+	for _, product := range products {
+		result.Products = append(result.Products, parseProduct(&product))
 	}
 
-	return &proto.Shops{
-		Shops: resultShops.Shops,
-	}, nil
+	return result, nil
 }
 
-func (s *GRPCMarketPlaceServer) GetShopForUser(ctx context.Context, req *proto.GetShopForUserRequest) (*proto.Shops, error) {
-	var shops []Shop
-	userId := req.UserId
-	user := &User{}
-	resultShops := proto.Shops{}
+// productsNearUser returns the ids of the products serviceable by the shops
+// within maxDistanceInKM of a user that deliver to them, see deliversTo.
+func (s *GRPCMarketPlaceServer) productsNearUser(ctx context.Context, userId string, maxDistanceInKM float64, includeUnzoned bool) ([]string, error) {
+	if maxDistanceInKM < 0 {
+		return nil, status.Error(codes.InvalidArgument, "max distance cannot be negative")
+	}
 
 	user, err := getItemOrError(s.svc.userRepo.FindOneById(userId))
 	if err != nil {
 		s.svc.logger.Println("Error: ", err)
-		return nil, errors.New("failed to get user")
+		return nil, status.Error(codes.NotFound, "user does not exists")
 	}
 
-	shops, err = getItemOrError(s.svc.shopRepo.Find(nil, nil, 0, 0))
+	shops, err := geoNear[Shop](ctx, getCollection(&s.svc.shopRepo.AbstractRepository), user.Coordinates, nil, maxDistanceInKM,
+		deliversTo(user.Coordinates, includeUnzoned),
+	)
 	if err != nil {
 		s.svc.logger.Println("Error: ", err)
-		return nil, errors.New("failed to get all shops")
+		return nil, errors.New("failed to get nearby shops")
 	}
 
+	var productIds []string
 	for _, shop := range shops {
-		if calculateDistance(user.Coordinates, shop.Coordinates) < req.MaxDistanceInKM {
-			pshop, err := s.ParseShop(ctx, &shop)
-			if err != nil {
-				s.svc.logger.Println("Error: ", err)
-				return nil, errors.New("failed to parse data")
-			}
+		productIds = append(productIds, shop.Item.ServiceableProductsId...)
+	}
 
-			resultShops.Shops = append(resultShops.Shops, pshop)
-		}
+	productIds = uniqueStrings(productIds)
+	if productIds == nil {
+		// an empty list matches nothing, unlike a missing one
+		productIds = []string{}
 	}
 
-	return &proto.Shops{
-		Shops: resultShops.Shops,
-	}, nil
+	return productIds, nil
 }
 
-func (s *GRPCMarketPlaceServer) GetNearestNeighbour(ctx context.Context, req *proto.GetNearestNeighbourRequest) (*proto.User, error) {
-	var users []User
-	userId := req.UserId
-	user := &User{}
+func (s *GRPCMarketPlaceServer) CreateCategory(ctx context.Context, req *proto.CreateCategoryRequest) (*proto.Category, error) {
+	if req.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "category name is required")
+	}
 
-	user, err := getItemOrError(s.svc.userRepo.FindOneById(userId))
+	category := Category{
+		ID:          primitive.NewObjectID().Hex(),
+		Name:        req.Name,
+		ParentID:    req.ParentId,
+		AncestorIDs: []string{},
+		CreatedOn:   time.Now().Unix(),
+	}
+
+	if req.ParentId != "" {
+		parent, err := getItemOrError(s.svc.categoryRepo.FindOneById(req.ParentId))
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return nil, status.Error(codes.NotFound, "parent category does not exists")
+		}
+
+		category.AncestorIDs = append(append(category.AncestorIDs, parent.AncestorIDs...), parent.ID)
+	}
+
+	siblings, err := getItemOrError(s.svc.categoryRepo.CountDocuments(bson.M{"parent_id": req.ParentId, "name": req.Name}))
 	if err != nil {
 		s.svc.logger.Println("Error: ", err)
-		return nil, errors.New("failed to get user")
+		return nil, errors.New("failed to create category")
 	}
 
-	users, err = getItemOrError(s.svc.userRepo.Find(nil, nil, 0, 0))
+	if siblings > 0 {
+		return nil, status.Errorf(codes.AlreadyExists, "category %q already exists", req.Name)
+	}
+
+	err = getErrorFromChan(s.svc.categoryRepo.Save(category))
 	if err != nil {
 		s.svc.logger.Println("Error: ", err)
-		return nil, errors.New("failed to get all users")
+		return nil, errors.New("failed to create category")
 	}
 
-	var nearest User
-	if user.ID == users[0].ID {
-		nearest = users[1]
-	} else {
-		nearest = users[0]
+	return parseCategory(&category), nil
+}
+
+func (s *GRPCMarketPlaceServer) ListCategories(ctx context.Context, req *proto.ListCategoriesRequest) (*proto.Categories, error) {
+	if req.ParentId != "" && !s.svc.categoryRepo.IsExistsById(req.ParentId) {
+		return nil, status.Error(codes.NotFound, "category does not exists")
 	}
 
-	nearestDistance := calculateDistance(user.Coordinates, nearest.Coordinates)
-	for _, kuser := range users {
-		distance := calculateDistance(user.Coordinates, kuser.Coordinates)
-		if distance < nearestDistance && kuser.ID != user.ID {
-			nearest = kuser
-			nearestDistance = distance
+	filter := bson.M{"parent_id": req.ParentId}
+	if req.Recursive {
+		filter = bson.M{}
+		if req.ParentId != "" {
+			filter = bson.M{"ancestor_ids": req.ParentId}
 		}
 	}
 
+	categories, err := getItemOrError(s.svc.categoryRepo.Find(filter, bson.D{{Key: "name", Value: 1}}, 0, 0))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to list categories")
+	}
+
+	// list every category after its parent
+	sort.SliceStable(categories, func(i, j int) bool {
+		return len(categories[i].AncestorIDs) < len(categories[j].AncestorIDs)
+	})
+
+	result := &proto.Categories{}
+	for _, category := range categories {
+		result.Categories = append(result.Categories, parseCategory(&category))
+	}
+
+	return result, nil
+}
+
+func (s *GRPCMarketPlaceServer) ListProductsInCategory(ctx context.Context, req *proto.ListProductsInCategoryRequest) (*proto.ListProductsInCategoryResponse, error) {
+	pageSize := int(req.PageSize)
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		return nil, status.Errorf(codes.InvalidArgument, "page size cannot exceed %d", maxPageSize)
+	}
+
+	offset, err := decodePageToken(req.PageToken)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid page token")
+	}
+
+	categoryIds, err := s.categorySubtree(req.CategoryId)
+	if err != nil {
+		return nil, err
+	}
+
+	// one more than the page size tells whether there is a next page
+	products, err := getItemOrError(s.svc.productRepo.Find(
+		bson.M{"category_ids": bson.M{"$in": categoryIds}},
+		bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}},
+		int64(pageSize+1), int64(offset),
+	))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to get products")
+	}
+
+	result := &proto.ListProductsInCategoryResponse{}
+	if len(products) > pageSize {
+		products = products[:pageSize]
+		result.NextPageToken = encodePageToken(offset + pageSize)
+	}
+
+	for _, product := range products {
+		result.Products = append(result.Products, parseProduct(&product))
+	}
+
+	return result, nil
+}
+
+// categorySubtree returns the id of a category along with the ids of all of its
+// subcategories.
+func (s *GRPCMarketPlaceServer) categorySubtree(categoryId string) ([]string, error) {
+	if !s.svc.categoryRepo.IsExistsById(categoryId) {
+		return nil, status.Error(codes.NotFound, "category does not exists")
+	}
+
+	subcategories, err := getItemOrError(s.svc.categoryRepo.Find(bson.M{"ancestor_ids": categoryId}, nil, 0, 0))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to get subcategories")
+	}
+
+	categoryIds := []string{categoryId}
+	for _, category := range subcategories {
+		categoryIds = append(categoryIds, category.ID)
+	}
+
+	return categoryIds, nil
+}
+
+func parseCategory(category *Category) *proto.Category {
+	return &proto.Category{
+		Id:          category.ID,
+		Name:        category.Name,
+		ParentId:    category.ParentID,
+		AncestorIds: category.AncestorIDs,
+		CreatedOn:   category.CreatedOn,
+	}
+}
+
+func (s *GRPCMarketPlaceServer) CreateUser(ctx context.Context, req *proto.CreateUserRequest) (*proto.User, error) {
+	user, err := newCredentials(req.Email, req.Password)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// anyone may sign up, so only admins hand out other roles, see SetUserRole
+	user.Role = RoleCustomer
+
+	user.ID = primitive.NewObjectID().Hex()
+	user.Name = req.Name
+	user.Location = req.Location
+	user.Coordinates = [2]float64{
+		req.Coordinates.GetLatitude(),
+		req.Coordinates.GetLongitude(),
+	}
+	user.Position = newGeoPoint(user.Coordinates)
+
+	err = getErrorFromChan(s.svc.userRepo.Save(user))
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, status.Error(codes.AlreadyExists, "email is already used by another user")
+	}
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to create user")
+	}
+
+	return parseUser(&user), nil
+}
+
+func parseUser(user *User) *proto.User {
 	return &proto.User{
-		Id:       nearest.ID,
-		Name:     nearest.Name,
-		Location: nearest.Location,
+		Id:       user.ID,
+		Name:     user.Name,
+		Location: user.Location,
 		Coordinates: &proto.Coordinates{
-			Latitude:  nearest.Coordinates[0],
-			Longitude: nearest.Coordinates[1],
+			Latitude:  user.Coordinates[0],
+			Longitude: user.Coordinates[1],
 		},
-	}, nil
-
+		Email: user.Email,
+		Role:  user.Role,
+	}
 }
 
-func (s *GRPCMarketPlaceServer) ParseShop(ctx context.Context, shop *Shop) (*proto.Shop, error) {
-	products, err := s.GetServiceableProducts(ctx, &proto.GetServiceableProductsRequest{
-		ShopId: shop.ID,
-	})
+func (s *GRPCMarketPlaceServer) GetShopByID(ctx context.Context, req *proto.GetRequest) (*proto.Shop, error) {
+	id := req.Id
+	shop := &Shop{}
+
+	shop, err := getItemOrError(s.svc.shopRepo.FindOneById(id))
 	if err != nil {
 		s.svc.logger.Println("Error: ", err)
-		return nil, errors.New("failed to get serviceable products")
+		return nil, errors.New("failed to get shop")
+	}
+
+	products, err := s.GetServiceableProducts(ctx, &proto.GetServiceableProductsRequest{ShopId: id})
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to get serviceable products for shop")
 	}
 
 	return &proto.Shop{
@@ -445,10 +683,2339 @@ func (s *GRPCMarketPlaceServer) ParseShop(ctx context.Context, shop *Shop) (*pro
 		Name:                shop.Name,
 		Location:            shop.Location,
 		OperationHours:      shop.OperationHours,
+		OperatingHours:      parseOperatingHours(shop.OperatingHours),
 		ServiceableProducts: products.Products,
 		Coordinates: &proto.Coordinates{
 			Latitude:  shop.Coordinates[0],
 			Longitude: shop.Coordinates[1],
 		},
+		OwnerId: shop.OwnerID,
+	}, nil
+}
+
+func (s *GRPCMarketPlaceServer) GetProductByID(ctx context.Context, req *proto.GetRequest) (*proto.Product, error) {
+	id := req.Id
+	product := &Product{}
+
+	product, err := getItemOrError(s.svc.productRepo.FindOneById(id))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to get product")
+	}
+
+	return parseProduct(product), nil
+}
+
+func (s *GRPCMarketPlaceServer) GetUserByID(ctx context.Context, req *proto.GetRequest) (*proto.User, error) {
+	id := req.Id
+	user := &User{}
+
+	err := authorizeUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err = getItemOrError(s.svc.userRepo.FindOneById(id))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to get user: %s")
+	}
+
+	return parseUser(user), nil
+}
+
+// SetUserRole promotes or demotes a user, e.g. to let a customer open shops.
+// Admins cannot change their own role, so that there is always one left. The
+// tokens issued to the user before are revoked.
+func (s *GRPCMarketPlaceServer) SetUserRole(ctx context.Context, req *proto.SetUserRoleRequest) (*proto.User, error) {
+	switch req.Role {
+	case RoleCustomer, RoleMerchant, RoleAdmin:
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unknown role %q", req.Role)
+	}
+
+	if isCaller(ctx, req.UserId) {
+		return nil, status.Error(codes.FailedPrecondition, "admins cannot change their own role")
+	}
+
+	user := &User{}
+	err := getCollection(&s.svc.userRepo.AbstractRepository).FindOneAndUpdate(ctx,
+		bson.M{"_id": req.UserId},
+		bson.M{
+			"$set": bson.M{"role": req.Role, "updatedOn": time.Now().Unix()},
+			"$inc": bson.M{"token_version": 1},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(user)
+	if err == mongo.ErrNoDocuments {
+		return nil, status.Errorf(codes.NotFound, "user[%s] does not exists", req.UserId)
+	}
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to set user role")
+	}
+
+	return parseUser(user), nil
+}
+
+func (s *GRPCMarketPlaceServer) AddServiceableProduct(ctx context.Context, req *proto.AddServiceableProductRequest) (*proto.Shop, error) {
+	err := s.authorizeShop(ctx, req.ShopId, PermissionManageCatalog)
+	if err != nil {
+		return nil, err
+	}
+
+	shopId := req.ShopId
+	productId := req.ProductId
+	shop := &Shop{}
+
+	product, err := getItemOrError(s.svc.productRepo.FindOneById(productId))
+	if err != nil {
+		s.svc.logger.Printf("Error: product[%s] does not exists", productId)
+		return nil, errors.New("product does not exists")
+	}
+
+	variantIds := product.variantIDs()
+	if len(req.VariantIds) > 0 {
+		variantIds = nil
+		for _, variantId := range uniqueStrings(req.VariantIds) {
+			if product.variant(variantId) == nil {
+				return nil, status.Errorf(codes.NotFound, "variant[%s] of product[%s] does not exists", variantId, productId)
+			}
+			variantIds = append(variantIds, variantId)
+		}
+	}
+
+	shop, err = getItemOrError(s.svc.shopRepo.FindOneById(shopId))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to get shop")
+	}
+
+	err = s.ensureServiceable(ctx, shop.ID, productId, variantIds...)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetShopByID(ctx, &proto.GetRequest{
+		Id: shopId,
+	})
+}
+
+// ensureServiceable adds a product to the serviceable products of a shop and
+// creates an empty inventory for each of the given variants, leaving any that
+// already exist untouched. ctx may be a mongo.SessionContext to make all of it
+// part of a transaction.
+func (s *GRPCMarketPlaceServer) ensureServiceable(ctx context.Context, shopId, productId string, variantIds ...string) error {
+	now := time.Now().Unix()
+
+	_, err := getCollection(&s.svc.shopRepo.AbstractRepository).UpdateOne(ctx,
+		bson.M{"_id": shopId},
+		bson.M{"$addToSet": bson.M{"products": productId}},
+	)
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return errors.New("failed to add serviceable product")
+	}
+
+	for _, variantId := range variantIds {
+		_, err = getCollection(&s.svc.inventoryRepo.AbstractRepository).UpdateOne(ctx,
+			inventoryFilter(shopId, productId, variantId),
+			bson.M{"$setOnInsert": bson.M{
+				"_id":       primitive.NewObjectID().Hex(),
+				"quantity":  0,
+				"reserved":  0,
+				"version":   0,
+				"createdOn": now,
+			}},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return errors.New("failed to create inventory")
+		}
+	}
+
+	return nil
+}
+
+func (s *GRPCMarketPlaceServer) GetServiceableProducts(ctx context.Context, req *proto.GetServiceableProductsRequest) (*proto.Products, error) {
+	var serviceableProducts []*proto.Product
+	id := req.ShopId
+	shop := &Shop{}
+
+	shop, err := getItemOrError(s.svc.shopRepo.FindOneById(id))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to get shop")
+	}
+
+	inventories, err := getItemOrError(s.svc.inventoryRepo.Find(bson.M{"shop_id": id}, nil, 0, 0))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to get inventories")
+	}
+
+	// the variants a shop sells are the ones it keeps an inventory for
+	stocked := make(map[[2]string]bool)
+	for _, inventory := range inventories {
+		stocked[[2]string{inventory.ProductID, inventory.VariantID}] = true
+	}
+
+	prices, err := s.shopPrices(id)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, productId := range shop.ServiceableProductsId {
+		product, err := getItemOrError(s.svc.productRepo.FindOneById(productId))
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return nil, errors.New("failed to get product")
+		}
+
+		variants := product.Variants[:0]
+		for _, variant := range product.Variants {
+			if stocked[[2]string{productId, variant.ID}] {
+				variants = append(variants, variant)
+			}
+		}
+		product.Variants = variants
+
+		prices[productId].applyTo(product)
+		serviceableProducts = append(serviceableProducts, parseProduct(product))
+	}
+
+	return &proto.Products{
+		Products: serviceableProducts,
+	}, nil
+}
+
+// SetShopPrice sets the price a shop sells one of its serviceable products,
+// or a single variant of it, at.
+func (s *GRPCMarketPlaceServer) SetShopPrice(ctx context.Context, req *proto.SetShopPriceRequest) (*proto.ServiceableProduct, error) {
+	err := s.authorizeShop(ctx, req.ShopId, PermissionManageCatalog)
+	if err != nil {
+		return nil, err
+	}
+
+	price, err := newMoney(req.Price)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "price: %v", err)
+	}
+
+	shop, err := getItemOrError(s.svc.shopRepo.FindOneById(req.ShopId))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, status.Error(codes.NotFound, "shop does not exists")
+	}
+
+	var serviceable bool
+	for _, id := range shop.ServiceableProductsId {
+		if id == req.ProductId {
+			serviceable = true
+		}
+	}
+
+	if !serviceable {
+		return nil, status.Errorf(codes.FailedPrecondition, "product[%s] is not serviceable by shop[%s]", req.ProductId, req.ShopId)
+	}
+
+	if req.VariantId != "" {
+		product, err := getItemOrError(s.svc.productRepo.FindOneById(req.ProductId))
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return nil, errors.New("failed to get product")
+		}
+
+		if product.variant(req.VariantId) == nil {
+			return nil, status.Errorf(codes.NotFound, "variant[%s] of product[%s] does not exists", req.VariantId, req.ProductId)
+		}
+	}
+
+	sp := &ServiceableProduct{}
+	err = s.svc.withTransaction(ctx, func(sessCtx mongo.SessionContext) error {
+		_, err := s.setPrice(sessCtx, req.ProductId, req.VariantId, req.ShopId, &price, PriceChangeReasonManual, "")
+		if err != nil {
+			return err
+		}
+
+		err = getCollection(&s.svc.serviceableRepo.AbstractRepository).FindOne(sessCtx,
+			bson.M{"shop_id": req.ShopId, "product_id": req.ProductId},
+		).Decode(sp)
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return errors.New("failed to get shop price")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return parseServiceableProduct(sp), nil
+}
+
+func (s *GRPCMarketPlaceServer) GetInventory(ctx context.Context, req *proto.GetInventoryRequest) (*proto.Inventory, error) {
+	err := s.authorizeShop(ctx, req.ShopId, PermissionManageInventory)
+	if err != nil {
+		return nil, err
+	}
+
+	shopId := req.ShopId
+	productId := req.ProductId
+	inventory := &Inventory{}
+
+	_, variantId, err := s.productVariant(productId, req.VariantId)
+	if err != nil {
+		return nil, err
+	}
+
+	inventory, err = getItemOrError(s.svc.inventoryRepo.FindOne(inventoryFilter(shopId, productId, variantId)))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to get inventory")
+	}
+
+	return parseInventory(inventory), nil
+}
+
+func (s *GRPCMarketPlaceServer) UpdateInventory(ctx context.Context, req *proto.UpdateInventoryRequest) (*proto.Inventory, error) {
+	err := s.authorizeShop(ctx, req.ShopId, PermissionManageInventory)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Change < 0 {
+		return nil, status.Error(codes.InvalidArgument, "change cannot be negative")
+	}
+
+	_, variantId, err := s.productVariant(req.ProductId, req.VariantId)
+	if err != nil {
+		return nil, err
+	}
+
+	change := inventoryChange{
+		ShopID:          req.ShopId,
+		ProductID:       req.ProductId,
+		VariantID:       variantId,
+		Delta:           int(req.Change),
+		Reason:          req.Reason,
+		Actor:           callerFrom(ctx).ID,
+		ExpectedVersion: req.ExpectedVersion,
+	}
+
+	if !req.Add {
+		change.Delta = -change.Delta
+	}
+
+	if change.Reason == "" {
+		change.Reason = MovementReasonAdjustment
+		if req.Add {
+			change.Reason = MovementReasonRestock
+		}
+	}
+
+	if !isValidMovementReason(change.Reason) {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown inventory movement reason %q", change.Reason)
+	}
+
+	var inventory *Inventory
+	err = s.svc.withTransaction(ctx, func(sessCtx mongo.SessionContext) error {
+		var err error
+		inventory, err = s.adjustInventory(sessCtx, change)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if crossedReorderThreshold(inventory, change.Delta) {
+		s.svc.raiseLowStockAlerts(inventory)
+	}
+
+	return parseInventory(inventory), nil
+}
+
+func (s *GRPCMarketPlaceServer) SetReorderThreshold(ctx context.Context, req *proto.SetReorderThresholdRequest) (*proto.Inventory, error) {
+	err := s.authorizeShop(ctx, req.ShopId, PermissionManageInventory)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.ReorderThreshold < 0 {
+		return nil, status.Error(codes.InvalidArgument, "reorder threshold cannot be negative")
+	}
+
+	_, variantId, err := s.productVariant(req.ProductId, req.VariantId)
+	if err != nil {
+		return nil, err
+	}
+
+	inventory := &Inventory{}
+	err = getCollection(&s.svc.inventoryRepo.AbstractRepository).FindOneAndUpdate(ctx,
+		inventoryFilter(req.ShopId, req.ProductId, variantId),
+		bson.M{"$set": bson.M{"reorder_threshold": req.ReorderThreshold, "updatedOn": time.Now().Unix()}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(inventory)
+	if err == mongo.ErrNoDocuments {
+		return nil, status.Error(codes.NotFound, "inventory does not exists")
+	}
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to update reorder threshold")
+	}
+
+	return parseInventory(inventory), nil
+}
+
+func (s *GRPCMarketPlaceServer) ListShopInventory(ctx context.Context, req *proto.ListShopInventoryRequest) (*proto.Inventories, error) {
+	err := s.authorizeShop(ctx, req.ShopId, PermissionManageInventory)
+	if err != nil {
+		return nil, err
+	}
+
+	sort := bson.D{{Key: "product_id", Value: 1}, {Key: "variant_id", Value: 1}}
+
+	inventories, err := getItemOrError(s.svc.inventoryRepo.Find(bson.M{"shop_id": req.ShopId}, sort, 0, 0))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to get inventories")
+	}
+
+	result := &proto.Inventories{}
+	for i := range inventories {
+		result.Inventories = append(result.Inventories, parseInventory(&inventories[i]))
+	}
+
+	return result, nil
+}
+
+// BulkUpdateInventory applies a stream of inventory rows, each on its own, and
+// reports the outcome of every row once the client closes the stream.
+func (s *GRPCMarketPlaceServer) BulkUpdateInventory(stream proto.MarketplaceService_BulkUpdateInventoryServer) error {
+	report := &proto.BulkInventoryReport{}
+	var line int32
+
+	for {
+		row, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(report)
+		}
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return err
+		}
+
+		line++
+		if row.Line == 0 {
+			row.Line = line
+		}
+
+		result := &proto.BulkInventoryResult{
+			Line:      row.Line,
+			ShopId:    row.ShopId,
+			ProductId: row.ProductId,
+			VariantId: row.VariantId,
+		}
+
+		inventory, err := s.applyInventoryRow(stream.Context(), row)
+		if err != nil {
+			result.Error = status.Convert(err).Message()
+			report.Failed++
+		} else {
+			result.Ok = true
+			result.VariantId = inventory.VariantID
+			result.Quantity = int32(inventory.Quantity)
+			report.Succeeded++
+		}
+		report.Results = append(report.Results, result)
+	}
+}
+
+// applyInventoryRow sets, adds to or removes from the inventory named by row.
+// Setting is done relative to the version that was read so that a concurrent
+// change makes the row fail instead of being overwritten.
+func (s *GRPCMarketPlaceServer) applyInventoryRow(ctx context.Context, row *proto.BulkInventoryRow) (*Inventory, error) {
+	if row.Quantity < 0 {
+		return nil, status.Error(codes.InvalidArgument, "quantity cannot be negative")
+	}
+
+	err := s.authorizeShop(ctx, row.ShopId, PermissionManageInventory)
+	if err != nil {
+		return nil, err
+	}
+
+	_, variantId, err := s.productVariant(row.ProductId, row.VariantId)
+	if err != nil {
+		return nil, err
+	}
+
+	change := inventoryChange{
+		ShopID:    row.ShopId,
+		ProductID: row.ProductId,
+		VariantID: variantId,
+		Actor:     callerFrom(ctx).ID,
+	}
+
+	switch row.Mode {
+	case "", bulkModeSet:
+		current, err := getItemOrError(s.svc.inventoryRepo.FindOne(inventoryFilter(row.ShopId, row.ProductId, variantId)))
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return nil, errors.New("failed to get inventory")
+		}
+
+		change.Delta = int(row.Quantity) - current.Quantity
+		change.Reason = MovementReasonAdjustment
+		change.ExpectedVersion = &current.Version
+	case bulkModeAdd:
+		change.Delta = int(row.Quantity)
+		change.Reason = MovementReasonRestock
+	case bulkModeRemove:
+		change.Delta = -int(row.Quantity)
+		change.Reason = MovementReasonAdjustment
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unknown mode %q", row.Mode)
+	}
+
+	var inventory *Inventory
+	err = s.svc.withTransaction(ctx, func(sessCtx mongo.SessionContext) error {
+		var err error
+		inventory, err = s.adjustInventory(sessCtx, change)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if crossedReorderThreshold(inventory, change.Delta) {
+		s.svc.raiseLowStockAlerts(inventory)
+	}
+
+	return inventory, nil
+}
+
+// ListLowStock returns the inventories of a shop whose quantity on hand is
+// below their reorder threshold.
+func (s *GRPCMarketPlaceServer) ListLowStock(ctx context.Context, req *proto.ListLowStockRequest) (*proto.Inventories, error) {
+	err := s.authorizeShop(ctx, req.ShopId, PermissionManageInventory)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{
+		"shop_id":           req.ShopId,
+		"reorder_threshold": bson.M{"$gt": 0},
+		"$expr":             bson.M{"$lt": bson.A{"$quantity", "$reorder_threshold"}},
+	}
+	sort := bson.D{{Key: "quantity", Value: 1}}
+
+	inventories, err := getItemOrError(s.svc.inventoryRepo.Find(filter, sort, 0, 0))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to get low stock inventories")
+	}
+
+	result := &proto.Inventories{}
+	for i := range inventories {
+		result.Inventories = append(result.Inventories, parseInventory(&inventories[i]))
+	}
+
+	return result, nil
+}
+
+// inventoryChange describes a change to the quantity on hand of a product in a
+// shop together with what is recorded about it in the inventory ledger.
+type inventoryChange struct {
+	ShopID    string
+	ProductID string
+	VariantID string
+	// positive values add stock, negative values remove it
+	Delta     int
+	Reason    string
+	Actor     string
+	Reference string
+	// when set, the change only applies if the inventory is still at this version
+	ExpectedVersion *int64
+}
+
+// adjustInventory applies change to the inventory with a single conditional
+// update, so concurrent callers can never lose an update or take the quantity
+// below what is reserved, and appends the matching movement to the ledger.
+// ctx should be a mongo.SessionContext so that both writes are part of the
+// same transaction.
+func (s *GRPCMarketPlaceServer) adjustInventory(ctx context.Context, change inventoryChange) (*Inventory, error) {
+	collection := getCollection(&s.svc.inventoryRepo.AbstractRepository)
+	inventory := &Inventory{}
+
+	filter := inventoryFilter(change.ShopID, change.ProductID, change.VariantID)
+	if change.Delta < 0 {
+		available := bson.M{"$subtract": bson.A{"$quantity", bson.M{"$ifNull": bson.A{"$reserved", 0}}}}
+		filter["$expr"] = bson.M{"$gte": bson.A{available, -change.Delta}}
+	}
+
+	if change.ExpectedVersion != nil {
+		if *change.ExpectedVersion == 0 {
+			// inventories created before versioning have no version field
+			filter["version"] = bson.M{"$in": bson.A{0, nil}}
+		} else {
+			filter["version"] = *change.ExpectedVersion
+		}
+	}
+
+	err := collection.FindOneAndUpdate(ctx,
+		filter,
+		bson.M{
+			"$inc": bson.M{"quantity": change.Delta, "version": 1},
+			"$set": bson.M{"updatedOn": time.Now().Unix()},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(inventory)
+	if err == mongo.ErrNoDocuments {
+		return nil, s.inventoryUpdateRejection(ctx, change)
+	}
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to update inventory")
+	}
+
+	err = s.recordMovement(ctx, inventory, change)
+	if err != nil {
+		return nil, err
+	}
+
+	return inventory, nil
+}
+
+// inventoryUpdateRejection works out why the conditional update in
+// adjustInventory matched no inventory.
+func (s *GRPCMarketPlaceServer) inventoryUpdateRejection(ctx context.Context, change inventoryChange) error {
+	inventory := &Inventory{}
+
+	err := getCollection(&s.svc.inventoryRepo.AbstractRepository).FindOne(ctx, inventoryFilter(change.ShopID, change.ProductID, change.VariantID)).Decode(inventory)
+	if err == mongo.ErrNoDocuments {
+		return status.Errorf(codes.NotFound, "shop[%s] has no inventory of product[%s]", change.ShopID, change.ProductID)
+	}
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return errors.New("failed to get inventory")
+	}
+
+	if change.ExpectedVersion != nil && inventory.Version != *change.ExpectedVersion {
+		return status.Errorf(codes.Aborted, "inventory is at version %d, expected %d", inventory.Version, *change.ExpectedVersion)
+	}
+
+	if inventory.Quantity < -change.Delta {
+		return errNegativeInventory
+	}
+	return errReservedInventory
+}
+
+// recordMovement appends change to the inventory ledger. inventory must be
+// the state of the inventory after the change was applied.
+func (s *GRPCMarketPlaceServer) recordMovement(ctx context.Context, inventory *Inventory, change inventoryChange) error {
+	movement := InventoryMovement{
+		ID:            primitive.NewObjectID().Hex(),
+		ShopID:        inventory.ShopID,
+		ProductID:     inventory.ProductID,
+		VariantID:     inventory.VariantID,
+		Delta:         change.Delta,
+		QuantityAfter: inventory.Quantity,
+		Reason:        change.Reason,
+		Actor:         change.Actor,
+		Reference:     change.Reference,
+		CreatedOn:     time.Now().Unix(),
+	}
+
+	_, err := getCollection(&s.svc.movementRepo.AbstractRepository).InsertOne(ctx, movement)
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return errors.New("failed to record inventory movement")
+	}
+	return nil
+}
+
+func (s *GRPCMarketPlaceServer) ListInventoryMovements(ctx context.Context, req *proto.ListInventoryMovementsRequest) (*proto.InventoryMovements, error) {
+	err := s.authorizeShop(ctx, req.ShopId, PermissionManageInventory)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"shop_id": req.ShopId}
+	if req.ProductId != "" {
+		filter["product_id"] = req.ProductId
+	}
+	if req.VariantId != "" {
+		filter["variant_id"] = req.VariantId
+	}
+
+	createdOn := bson.M{}
+	if req.From != 0 {
+		createdOn["$gte"] = req.From
+	}
+	if req.To != 0 {
+		createdOn["$lte"] = req.To
+	}
+	if len(createdOn) > 0 {
+		filter["createdOn"] = createdOn
+	}
+
+	sort := bson.D{{Key: "createdOn", Value: 1}, {Key: "_id", Value: 1}}
+
+	movements, err := getItemOrError(s.svc.movementRepo.Find(filter, sort, 0, 0))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to get inventory movements")
+	}
+
+	result := &proto.InventoryMovements{}
+	for _, movement := range movements {
+		result.Movements = append(result.Movements, &proto.InventoryMovement{
+			Id:            movement.ID,
+			ShopId:        movement.ShopID,
+			ProductId:     movement.ProductID,
+			VariantId:     movement.VariantID,
+			Delta:         int32(movement.Delta),
+			QuantityAfter: int32(movement.QuantityAfter),
+			Reason:        movement.Reason,
+			Actor:         movement.Actor,
+			Reference:     movement.Reference,
+			CreatedOn:     movement.CreatedOn,
+		})
+	}
+
+	return result, nil
+}
+
+// GetStockAt reconstructs the quantity on hand at a past instant from the
+// ledger: it is the quantity before the first movement that happened after
+// that instant, or the current quantity when nothing has moved since.
+func (s *GRPCMarketPlaceServer) GetStockAt(ctx context.Context, req *proto.GetStockAtRequest) (*proto.StockLevel, error) {
+	err := s.authorizeShop(ctx, req.ShopId, PermissionManageInventory)
+	if err != nil {
+		return nil, err
+	}
+
+	_, variantId, err := s.productVariant(req.ProductId, req.VariantId)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := inventoryFilter(req.ShopId, req.ProductId, variantId)
+	filter["createdOn"] = bson.M{"$gt": req.At}
+	sort := bson.D{{Key: "createdOn", Value: 1}, {Key: "_id", Value: 1}}
+
+	movements, err := getItemOrError(s.svc.movementRepo.Find(filter, sort, 1, 0))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to get inventory movements")
+	}
+
+	stock := &proto.StockLevel{
+		ShopId:    req.ShopId,
+		ProductId: req.ProductId,
+		VariantId: variantId,
+		At:        req.At,
+	}
+
+	if len(movements) > 0 {
+		stock.Quantity = int32(movements[0].QuantityAfter - movements[0].Delta)
+		return stock, nil
+	}
+
+	inventory, err := getItemOrError(s.svc.inventoryRepo.FindOne(inventoryFilter(req.ShopId, req.ProductId, variantId)))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to get inventory")
+	}
+
+	stock.Quantity = int32(inventory.Quantity)
+	return stock, nil
+}
+
+func (s *GRPCMarketPlaceServer) ReserveStock(ctx context.Context, req *proto.ReserveStockRequest) (*proto.Reservation, error) {
+	err := s.authorizeShop(ctx, req.ShopId, PermissionManageInventory)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Quantity <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "quantity must be positive")
+	}
+
+	ttl := time.Duration(req.TtlSeconds) * time.Second
+	if ttl <= 0 {
+		ttl = defaultReservationTTL
+	}
+
+	_, variantId, err := s.productVariant(req.ProductId, req.VariantId)
+	if err != nil {
+		return nil, err
+	}
+
+	reservation := Reservation{
+		ID:        primitive.NewObjectID().Hex(),
+		ShopID:    req.ShopId,
+		ProductID: req.ProductId,
+		VariantID: variantId,
+		Quantity:  int(req.Quantity),
+		Reference: req.Reference,
+		Status:    ReservationStatusActive,
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+	}
+
+	err = s.svc.withTransaction(ctx, func(sessCtx mongo.SessionContext) error {
+		// only reserve when quantity - reserved covers the request, so two
+		// concurrent reservations can never hold more than is on hand
+		available := bson.M{"$subtract": bson.A{"$quantity", bson.M{"$ifNull": bson.A{"$reserved", 0}}}}
+		filter := inventoryFilter(reservation.ShopID, reservation.ProductID, reservation.VariantID)
+		filter["$expr"] = bson.M{"$gte": bson.A{available, reservation.Quantity}}
+
+		res, err := getCollection(&s.svc.inventoryRepo.AbstractRepository).UpdateOne(sessCtx,
+			filter,
+			bson.M{
+				"$inc": bson.M{"reserved": reservation.Quantity, "version": 1},
+				"$set": bson.M{"updatedOn": time.Now().Unix()},
+			},
+		)
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return errors.New("failed to reserve stock")
+		}
+		if res.MatchedCount == 0 {
+			return status.Errorf(codes.FailedPrecondition, "not enough available stock of product[%s] in shop[%s]", reservation.ProductID, reservation.ShopID)
+		}
+
+		_, err = getCollection(&s.svc.reservationRepo.AbstractRepository).InsertOne(sessCtx, reservation)
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return errors.New("failed to create reservation")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return parseReservation(&reservation), nil
+}
+
+func (s *GRPCMarketPlaceServer) CommitReservation(ctx context.Context, req *proto.GetRequest) (*proto.Reservation, error) {
+	err := s.authorizeReservation(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	reservation, err := s.settleReservation(ctx, req.Id, ReservationStatusCommitted)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseReservation(reservation), nil
+}
+
+func (s *GRPCMarketPlaceServer) ReleaseReservation(ctx context.Context, req *proto.GetRequest) (*proto.Reservation, error) {
+	err := s.authorizeReservation(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	reservation, err := s.settleReservation(ctx, req.Id, ReservationStatusReleased)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseReservation(reservation), nil
+}
+
+// authorizeReservation checks that the caller manages the shop whose stock
+// the reservation holds.
+func (s *GRPCMarketPlaceServer) authorizeReservation(ctx context.Context, id string) error {
+	reservation, err := getItemOrError(s.svc.reservationRepo.FindOneById(id))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return status.Error(codes.NotFound, "reservation does not exists")
+	}
+
+	return s.authorizeShop(ctx, reservation.ShopID, PermissionManageInventory)
+}
+
+// settleReservation moves an active reservation to the committed or released
+// status. Either way the held quantity stops being reserved; committing also
+// takes it off the quantity on hand.
+func (s *GRPCMarketPlaceServer) settleReservation(ctx context.Context, id, to string) (*Reservation, error) {
+	reservation := &Reservation{}
+	inventory := &Inventory{}
+	now := time.Now().Unix()
+
+	err := s.svc.withTransaction(ctx, func(sessCtx mongo.SessionContext) error {
+		err := getCollection(&s.svc.reservationRepo.AbstractRepository).FindOneAndUpdate(sessCtx,
+			bson.M{"_id": id, "status": ReservationStatusActive},
+			bson.M{"$set": bson.M{"status": to, "updatedOn": now}},
+		).Decode(reservation)
+		if err == mongo.ErrNoDocuments {
+			return status.Error(codes.FailedPrecondition, "reservation does not exists or is no longer active")
+		}
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return errors.New("failed to get reservation")
+		}
+
+		if to == ReservationStatusCommitted && reservation.ExpiresAt < now {
+			return status.Error(codes.FailedPrecondition, "reservation has expired")
+		}
+
+		inc := bson.M{"reserved": -reservation.Quantity, "version": 1}
+		if to == ReservationStatusCommitted {
+			inc["quantity"] = -reservation.Quantity
+		}
+
+		err = getCollection(&s.svc.inventoryRepo.AbstractRepository).FindOneAndUpdate(sessCtx,
+			inventoryFilter(reservation.ShopID, reservation.ProductID, reservation.VariantID),
+			bson.M{"$inc": inc, "$set": bson.M{"updatedOn": now}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(inventory)
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return errors.New("failed to update inventory")
+		}
+
+		if to == ReservationStatusCommitted {
+			err = s.recordMovement(sessCtx, inventory, inventoryChange{
+				Delta:     -reservation.Quantity,
+				Reason:    MovementReasonSale,
+				Reference: reservation.ID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		reservation.Status = to
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if to == ReservationStatusCommitted && crossedReorderThreshold(inventory, -reservation.Quantity) {
+		s.svc.raiseLowStockAlerts(inventory)
+	}
+
+	return reservation, nil
+}
+
+func parseReservation(reservation *Reservation) *proto.Reservation {
+	return &proto.Reservation{
+		Id:        reservation.ID,
+		ShopId:    reservation.ShopID,
+		ProductId: reservation.ProductID,
+		VariantId: reservation.VariantID,
+		Quantity:  int32(reservation.Quantity),
+		Reference: reservation.Reference,
+		Status:    reservation.Status,
+		ExpiresAt: reservation.ExpiresAt,
+	}
+}
+
+func parseInventory(inventory *Inventory) *proto.Inventory {
+	return &proto.Inventory{
+		Id:               inventory.ID,
+		ShopId:           inventory.ShopID,
+		ProductId:        inventory.ProductID,
+		VariantId:        inventory.VariantID,
+		Quantity:         int32(inventory.Quantity),
+		Reserved:         int32(inventory.Reserved),
+		Available:        int32(inventory.Quantity - inventory.Reserved),
+		Version:          inventory.Version,
+		ReorderThreshold: int32(inventory.ReorderThreshold),
+	}
+}
+
+func (s *GRPCMarketPlaceServer) GetShopsByServiceableProducts(ctx context.Context, req *proto.GetShopsByServiceableProductsRequest) (*proto.Shops, error) {
+	var shops []Shop
+	productId := req.ProductId
+	filter := bson.M{"products": productId}
+
+	shops, err := getItemOrError(s.svc.shopRepo.Find(filter, nil, 0, 0))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to get shops")
+	}
+
+	resultShops := proto.Shops{}
+
+	for _, shop := range shops {
+		pshop, err := s.ParseShop(ctx, &shop)
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return nil, errors.New("failed to parse data")
+		}
+
+		resultShops.Shops = append(resultShops.Shops, pshop)
+	}
+
+	return &proto.Shops{
+		Shops: resultShops.Shops,
+	}, nil
+}
+
+// GetShopForUser returns the shops within the given distance of a user that
+// deliver to them, optionally only the ones open at a given time. Shops
+// without structured operating hours are never considered open.
+func (s *GRPCMarketPlaceServer) GetShopForUser(ctx context.Context, req *proto.GetShopForUserRequest) (*proto.Shops, error) {
+	err := authorizeUser(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	userId := req.UserId
+	user := &User{}
+	resultShops := proto.Shops{}
+
+	user, err = getItemOrError(s.svc.userRepo.FindOneById(userId))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to get user")
+	}
+
+	// no shop is closer than a distance that is not positive
+	if req.MaxDistanceInKM <= 0 {
+		return &proto.Shops{}, nil
+	}
+
+	shops, err := geoNear[Shop](ctx, getCollection(&s.svc.shopRepo.AbstractRepository), user.Coordinates, nil, req.MaxDistanceInKM,
+		deliversTo(user.Coordinates, req.IncludeShopsWithoutZones),
+	)
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to get nearby shops")
+	}
+
+	for _, shop := range shops {
+		if req.OpenAt != 0 && (shop.Item.OperatingHours == nil || !shop.Item.OperatingHours.IsOpen(time.Unix(req.OpenAt, 0))) {
+			continue
+		}
+
+		pshop, err := s.ParseShop(ctx, &shop.Item)
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return nil, errors.New("failed to parse data")
+		}
+
+		resultShops.Shops = append(resultShops.Shops, pshop)
+	}
+
+	return &proto.Shops{
+		Shops: resultShops.Shops,
+	}, nil
+}
+
+func (s *GRPCMarketPlaceServer) GetNearestNeighbour(ctx context.Context, req *proto.GetNearestNeighbourRequest) (*proto.User, error) {
+	neighbours, err := s.GetKNearestUsers(ctx, &proto.KNearestRequest{
+		UserId: req.UserId,
+		K:      1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(neighbours.Users) == 0 {
+		return nil, status.Error(codes.NotFound, "there are no other users")
+	}
+
+	return neighbours.Users[0].User, nil
+}
+
+func (s *GRPCMarketPlaceServer) GetKNearestUsers(ctx context.Context, req *proto.KNearestRequest) (*proto.NearbyUsers, error) {
+	origin, err := s.nearestOrigin(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var query bson.M
+	if req.UserId != "" {
+		query = bson.M{"_id": bson.M{"$ne": req.UserId}}
+	}
+
+	users, err := geoNear[User](ctx, getCollection(&s.svc.userRepo.AbstractRepository), origin, query, req.MaxDistanceInKM,
+		bson.D{{Key: "$limit", Value: req.K}},
+	)
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to get nearest users")
+	}
+
+	result := &proto.NearbyUsers{}
+	for _, user := range users {
+		result.Users = append(result.Users, &proto.NearbyUser{
+			User: &proto.User{
+				Id:       user.Item.ID,
+				Name:     user.Item.Name,
+				Location: user.Item.Location,
+				Coordinates: &proto.Coordinates{
+					Latitude:  user.Item.Coordinates[0],
+					Longitude: user.Item.Coordinates[1],
+				},
+			},
+			DistanceInKM: user.DistanceInKM,
+		})
+	}
+
+	return result, nil
+}
+
+func (s *GRPCMarketPlaceServer) GetKNearestShops(ctx context.Context, req *proto.KNearestRequest) (*proto.NearbyShops, error) {
+	if req.UserId != "" && req.Coordinates == nil {
+		err := authorizeUser(ctx, req.UserId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	origin, err := s.nearestOrigin(req)
+	if err != nil {
+		return nil, err
+	}
+
+	shops, err := geoNear[Shop](ctx, getCollection(&s.svc.shopRepo.AbstractRepository), origin, nil, req.MaxDistanceInKM,
+		bson.D{{Key: "$limit", Value: req.K}},
+	)
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to get nearest shops")
+	}
+
+	result := &proto.NearbyShops{}
+	for _, shop := range shops {
+		pshop, err := s.ParseShop(ctx, &shop.Item)
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return nil, errors.New("failed to parse data")
+		}
+
+		result.Shops = append(result.Shops, &proto.NearbyShop{
+			Shop:         pshop,
+			DistanceInKM: shop.DistanceInKM,
+		})
+	}
+
+	return result, nil
+}
+
+// nearestOrigin validates a k nearest request and returns the coordinates the
+// search starts from.
+func (s *GRPCMarketPlaceServer) nearestOrigin(req *proto.KNearestRequest) ([2]float64, error) {
+	if req.K <= 0 || req.K > maxNearestResults {
+		return [2]float64{}, status.Errorf(codes.InvalidArgument, "k must be between 1 and %d", maxNearestResults)
+	}
+
+	if req.MaxDistanceInKM < 0 {
+		return [2]float64{}, status.Error(codes.InvalidArgument, "max distance cannot be negative")
+	}
+
+	return s.searchOrigin(req.UserId, req.Coordinates)
+}
+
+// searchOrigin returns the coordinates a proximity search starts from: the
+// given coordinates, or else those of the user.
+func (s *GRPCMarketPlaceServer) searchOrigin(userId string, coordinates *proto.Coordinates) ([2]float64, error) {
+	if coordinates != nil {
+		return [2]float64{coordinates.Latitude, coordinates.Longitude}, nil
+	}
+
+	if userId == "" {
+		return [2]float64{}, status.Error(codes.InvalidArgument, "either a user id or coordinates are required")
+	}
+
+	user, err := getItemOrError(s.svc.userRepo.FindOneById(userId))
+	if err == mongo.ErrNoDocuments {
+		return [2]float64{}, status.Error(codes.NotFound, "user does not exists")
+	}
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return [2]float64{}, errors.New("failed to get user")
+	}
+
+	return user.Coordinates, nil
+}
+
+// SearchNearbyShops pages through the shops around a user or point, optionally
+// only those that have every one of the given products available in at least
+// the given quantity.
+func (s *GRPCMarketPlaceServer) SearchNearbyShops(ctx context.Context, req *proto.SearchNearbyShopsRequest) (*proto.SearchNearbyShopsResponse, error) {
+	if req.UserId != "" && req.Coordinates == nil {
+		err := authorizeUser(ctx, req.UserId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if req.MaxDistanceInKM < 0 {
+		return nil, status.Error(codes.InvalidArgument, "max distance cannot be negative")
+	}
+
+	if req.MinQuantity < 0 {
+		return nil, status.Error(codes.InvalidArgument, "min quantity cannot be negative")
+	}
+
+	pageSize := int(req.PageSize)
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		return nil, status.Errorf(codes.InvalidArgument, "page size cannot exceed %d", maxPageSize)
+	}
+
+	offset, err := decodePageToken(req.PageToken)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid page token")
+	}
+
+	var sort bson.D
+	switch req.Sort {
+	case "", shopSortDistance:
+		sort = bson.D{{Key: "distance_km", Value: 1}, {Key: "_id", Value: 1}}
+	case shopSortDistanceDesc:
+		sort = bson.D{{Key: "distance_km", Value: -1}, {Key: "_id", Value: 1}}
+	case shopSortName:
+		sort = bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unknown sort %q", req.Sort)
+	}
+
+	origin, err := s.searchOrigin(req.UserId, req.Coordinates)
+	if err != nil {
+		return nil, err
+	}
+
+	var query bson.M
+	stages := []bson.D{deliversTo(origin, req.IncludeShopsWithoutZones)}
+
+	productIds := uniqueStrings(req.ProductIds)
+	if len(productIds) > 0 {
+		query = bson.M{"products": bson.M{"$all": productIds}}
+
+		// a shop out of stock of a product does not have it
+		minQuantity := req.MinQuantity
+		if minQuantity == 0 {
+			minQuantity = 1
+		}
+
+		available := bson.M{"$subtract": bson.A{"$quantity", bson.M{"$ifNull": bson.A{"$reserved", 0}}}}
+		stages = append(stages,
+			bson.D{{Key: "$lookup", Value: bson.M{
+				"from": s.svc.inventoryRepo.CollectionName,
+				"let":  bson.M{"shopId": "$_id"},
+				"pipeline": bson.A{
+					bson.M{"$match": bson.M{"$expr": bson.M{"$and": bson.A{
+						bson.M{"$eq": bson.A{"$shop_id", "$$shopId"}},
+						bson.M{"$in": bson.A{"$product_id", productIds}},
+						bson.M{"$gte": bson.A{available, minQuantity}},
+					}}}},
+				},
+				"as": "stocked",
+			}}},
+			// any variant of a product having enough stock counts for the product
+			bson.D{{Key: "$match", Value: bson.M{"$expr": bson.M{"$eq": bson.A{
+				bson.M{"$size": bson.M{"$setUnion": bson.A{"$stocked.product_id", bson.A{}}}},
+				len(productIds),
+			}}}}},
+			bson.D{{Key: "$project", Value: bson.M{"stocked": 0}}},
+		)
+	}
+
+	// one more than the page size tells whether there is a next page
+	stages = append(stages,
+		bson.D{{Key: "$sort", Value: sort}},
+		bson.D{{Key: "$skip", Value: offset}},
+		bson.D{{Key: "$limit", Value: pageSize + 1}},
+	)
+
+	shops, err := geoNear[Shop](ctx, getCollection(&s.svc.shopRepo.AbstractRepository), origin, query, req.MaxDistanceInKM, stages...)
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to search shops")
+	}
+
+	result := &proto.SearchNearbyShopsResponse{}
+	if len(shops) > pageSize {
+		shops = shops[:pageSize]
+		result.NextPageToken = encodePageToken(offset + pageSize)
+	}
+
+	for _, shop := range shops {
+		pshop, err := s.ParseShop(ctx, &shop.Item)
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return nil, errors.New("failed to parse data")
+		}
+
+		result.Shops = append(result.Shops, &proto.NearbyShop{
+			Shop:         pshop,
+			DistanceInKM: shop.DistanceInKM,
+		})
+	}
+
+	return result, nil
+}
+
+// SearchShopsInArea returns the shops inside a bounding box or polygon, grouped
+// into the cells of a grid laid over the area when there are more than the
+// limit.
+func (s *GRPCMarketPlaceServer) SearchShopsInArea(ctx context.Context, req *proto.SearchShopsInAreaRequest) (*proto.SearchShopsInAreaResponse, error) {
+	limit := int64(req.Limit)
+	if limit <= 0 {
+		limit = defaultAreaShopLimit
+	}
+	if limit > maxAreaShopLimit {
+		return nil, status.Errorf(codes.InvalidArgument, "limit cannot exceed %d", maxAreaShopLimit)
+	}
+
+	gridSize := int(req.GridSize)
+	if gridSize <= 0 {
+		gridSize = defaultClusterGridSize
+	}
+	if gridSize > maxClusterGridSize {
+		return nil, status.Errorf(codes.InvalidArgument, "grid size cannot exceed %d", maxClusterGridSize)
+	}
+
+	var area GeoPolygon
+	var err error
+	switch {
+	case req.BoundingBox != nil && len(req.Polygon) > 0:
+		return nil, status.Error(codes.InvalidArgument, "only one of bounding box and polygon can be set")
+	case req.BoundingBox != nil:
+		area, err = boundingBoxPolygon(req.BoundingBox)
+	case len(req.Polygon) > 0:
+		area, err = newGeoPolygon(req.Polygon)
+	default:
+		return nil, status.Error(codes.InvalidArgument, "either a bounding box or a polygon is required")
+	}
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	filter := bson.M{"position": bson.M{"$geoWithin": bson.M{"$geometry": area}}}
+
+	total, err := getItemOrError(s.svc.shopRepo.CountDocuments(filter))
+	if isInvalidQueryGeometry(err) {
+		return nil, status.Error(codes.InvalidArgument, "area boundaries must not intersect themselves")
+	}
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to count shops")
+	}
+
+	result := &proto.SearchShopsInAreaResponse{
+		Total: int32(total),
+	}
+
+	if total <= limit {
+		shops, err := getItemOrError(s.svc.shopRepo.Find(filter, bson.D{{Key: "_id", Value: 1}}, 0, 0))
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return nil, errors.New("failed to get shops")
+		}
+
+		for _, shop := range shops {
+			result.Shops = append(result.Shops, &proto.AreaShop{
+				Id:       shop.ID,
+				Name:     shop.Name,
+				Location: shop.Location,
+				Coordinates: &proto.Coordinates{
+					Latitude:  shop.Coordinates[0],
+					Longitude: shop.Coordinates[1],
+				},
+				ServiceableProductCount: int32(len(shop.ServiceableProductsId)),
+			})
+		}
+
+		return result, nil
+	}
+
+	sw, ne := polygonBounds(area)
+	cellSize := [2]float64{(ne[0] - sw[0]) / float64(gridSize), (ne[1] - sw[1]) / float64(gridSize)}
+
+	// index of the grid cell along one axis, clamped to the grid since the
+	// great circle edges of the area may bulge slightly past its bounds
+	cell := func(axis int) bson.M {
+		offset := bson.M{"$subtract": bson.A{bson.M{"$arrayElemAt": bson.A{"$position.coordinates", axis}}, sw[axis]}}
+		index := bson.M{"$floor": bson.M{"$divide": bson.A{offset, cellSize[axis]}}}
+		return bson.M{"$min": bson.A{gridSize - 1, bson.M{"$max": bson.A{0, index}}}}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.M{
+			"_id":           bson.M{"x": cell(0), "y": cell(1)},
+			"shop_count":    bson.M{"$sum": 1},
+			"product_count": bson.M{"$sum": bson.M{"$size": bson.M{"$ifNull": bson.A{"$products", bson.A{}}}}},
+			"longitude":     bson.M{"$avg": bson.M{"$arrayElemAt": bson.A{"$position.coordinates", 0}}},
+			"latitude":      bson.M{"$avg": bson.M{"$arrayElemAt": bson.A{"$position.coordinates", 1}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id.y", Value: 1}, {Key: "_id.x", Value: 1}}}},
+	}
+
+	cursor, err := getCollection(&s.svc.shopRepo.AbstractRepository).Aggregate(ctx, pipeline)
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to cluster shops")
+	}
+
+	var clusters []struct {
+		Cell struct {
+			X int `bson:"x"`
+			Y int `bson:"y"`
+		} `bson:"_id"`
+		ShopCount    int     `bson:"shop_count"`
+		ProductCount int     `bson:"product_count"`
+		Longitude    float64 `bson:"longitude"`
+		Latitude     float64 `bson:"latitude"`
+	}
+	if err := cursor.All(ctx, &clusters); err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to cluster shops")
+	}
+
+	for _, cluster := range clusters {
+		west := sw[0] + float64(cluster.Cell.X)*cellSize[0]
+		south := sw[1] + float64(cluster.Cell.Y)*cellSize[1]
+
+		result.Clusters = append(result.Clusters, &proto.ShopCluster{
+			Center: &proto.Coordinates{
+				Latitude:  cluster.Latitude,
+				Longitude: cluster.Longitude,
+			},
+			Cell: &proto.BoundingBox{
+				SouthWest: &proto.Coordinates{Latitude: south, Longitude: west},
+				NorthEast: &proto.Coordinates{Latitude: south + cellSize[1], Longitude: west + cellSize[0]},
+			},
+			ShopCount:               int32(cluster.ShopCount),
+			ServiceableProductCount: int32(cluster.ProductCount),
+		})
+	}
+
+	return result, nil
+}
+
+func (s *GRPCMarketPlaceServer) SetDeliveryZones(ctx context.Context, req *proto.SetDeliveryZonesRequest) (*proto.DeliveryZones, error) {
+	err := s.authorizeShopOwner(ctx, req.ShopId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.svc.shopRepo.IsExistsById(req.ShopId) {
+		s.svc.logger.Printf("Error: shop[%s] does not exists", req.ShopId)
+		return nil, status.Error(codes.NotFound, "shop does not exists")
+	}
+
+	var zones []DeliveryZone
+	var amounts []Money
+	for _, pzone := range req.Zones {
+		zone, err := newDeliveryZone(pzone)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		zones = append(zones, zone)
+		amounts = append(amounts, zone.DeliveryFee, zone.MinOrderValue)
+	}
+
+	if _, err := sumMoney(amounts...); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "all delivery zones of a shop must use the same currency")
+	}
+
+	update := bson.M{"$set": bson.M{"delivery_zones": zones}}
+	if len(zones) == 0 {
+		update = bson.M{"$unset": bson.M{"delivery_zones": ""}}
+	}
+
+	_, err = getCollection(&s.svc.shopRepo.AbstractRepository).UpdateOne(ctx, bson.M{"_id": req.ShopId}, update)
+	if err != nil {
+		if isInvalidGeometry(err) {
+			return nil, status.Error(codes.InvalidArgument, "delivery zone boundaries must not intersect themselves")
+		}
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to update delivery zones")
+	}
+
+	return s.GetDeliveryZones(ctx, &proto.GetDeliveryZonesRequest{
+		ShopId: req.ShopId,
+	})
+}
+
+func (s *GRPCMarketPlaceServer) GetDeliveryZones(ctx context.Context, req *proto.GetDeliveryZonesRequest) (*proto.DeliveryZones, error) {
+	shop, err := getItemOrError(s.svc.shopRepo.FindOneById(req.ShopId))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, status.Error(codes.NotFound, "failed to get shop")
+	}
+
+	result := &proto.DeliveryZones{
+		ShopId: shop.ID,
+	}
+	for i := range shop.DeliveryZones {
+		result.Zones = append(result.Zones, parseDeliveryZone(&shop.DeliveryZones[i]))
+	}
+
+	return result, nil
+}
+
+func (s *GRPCMarketPlaceServer) SetOperatingHours(ctx context.Context, req *proto.SetOperatingHoursRequest) (*proto.Shop, error) {
+	err := s.authorizeShopOwner(ctx, req.ShopId)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.OperatingHours == nil {
+		return nil, status.Error(codes.InvalidArgument, "operating hours are required")
+	}
+
+	hours, err := newOperatingHours(req.OperatingHours)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid operating hours: %v", err)
+	}
+
+	res, err := getCollection(&s.svc.shopRepo.AbstractRepository).UpdateOne(ctx,
+		bson.M{"_id": req.ShopId},
+		bson.M{
+			"$set":   bson.M{"operating_hours": hours},
+			"$unset": bson.M{"operation_hours": ""},
+		},
+	)
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to update operating hours")
+	}
+
+	if res.MatchedCount == 0 {
+		return nil, status.Error(codes.NotFound, "shop does not exists")
+	}
+
+	return s.GetShopByID(ctx, &proto.GetRequest{
+		Id: req.ShopId,
+	})
+}
+
+func (s *GRPCMarketPlaceServer) IsShopOpen(ctx context.Context, req *proto.IsShopOpenRequest) (*proto.ShopOpenStatus, error) {
+	shop, err := getItemOrError(s.svc.shopRepo.FindOneById(req.ShopId))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, status.Error(codes.NotFound, "failed to get shop")
+	}
+
+	if shop.OperatingHours == nil {
+		return nil, status.Error(codes.FailedPrecondition, "shop has no operating hours")
+	}
+
+	at := time.Now()
+	if req.At != 0 {
+		at = time.Unix(req.At, 0)
+	}
+
+	result := &proto.ShopOpenStatus{
+		Open: shop.OperatingHours.IsOpen(at),
+	}
+	if next, ok := shop.OperatingHours.NextOpening(at); ok {
+		result.NextOpening = next.Unix()
+	}
+
+	return result, nil
+}
+
+func (s *GRPCMarketPlaceServer) ParseShop(ctx context.Context, shop *Shop) (*proto.Shop, error) {
+	products, err := s.GetServiceableProducts(ctx, &proto.GetServiceableProductsRequest{
+		ShopId: shop.ID,
+	})
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to get serviceable products")
+	}
+
+	return &proto.Shop{
+		Id:                  shop.ID,
+		Name:                shop.Name,
+		Location:            shop.Location,
+		OperationHours:      shop.OperationHours,
+		OperatingHours:      parseOperatingHours(shop.OperatingHours),
+		ServiceableProducts: products.Products,
+		Coordinates: &proto.Coordinates{
+			Latitude:  shop.Coordinates[0],
+			Longitude: shop.Coordinates[1],
+		},
+		OwnerId: shop.OwnerID,
+	}, nil
+}
+
+func (s *GRPCMarketPlaceServer) PlaceOrder(ctx context.Context, req *proto.PlaceOrderRequest) (*proto.Order, error) {
+	err := authorizeUser(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := getItemOrError(s.svc.userRepo.FindOneById(req.UserId))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, status.Error(codes.NotFound, "user does not exists")
+	}
+
+	order, err := s.buildOrder(ctx, user, req.ShopId, req.Items, req.CouponCode)
+	if err != nil {
+		return nil, err
+	}
+
+	var lowStock []*Inventory
+	err = s.svc.withTransaction(ctx, func(sessCtx mongo.SessionContext) error {
+		var err error
+		lowStock, err = s.saveOrder(sessCtx, order)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.svc.raiseLowStockAlerts(lowStock...)
+
+	return parseOrder(order), nil
+}
+
+// buildOrder validates the requested items against the shop and prices them at
+// the prices of the shop, taking off the running promotions and the one of
+// couponCode, if given, and adding the fee for delivering to the user and the
+// taxes. The returned order has not been persisted yet.
+func (s *GRPCMarketPlaceServer) buildOrder(ctx context.Context, user *User, shopId string, items []*proto.OrderItemRequest, couponCode string) (*Order, error) {
+	if len(items) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "order must contain at least one item")
+	}
+
+	shop, err := getItemOrError(s.svc.shopRepo.FindOneById(shopId))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, status.Error(codes.NotFound, "failed to get shop")
+	}
+
+	order := &Order{
+		ID:        primitive.NewObjectID().Hex(),
+		UserID:    user.ID,
+		ShopID:    shop.ID,
+		Status:    OrderStatusPlaced,
+		CreatedOn: time.Now().Unix(),
+	}
+	order.StatusHistory = []OrderStatusChange{{
+		Status:    OrderStatusPlaced,
+		ChangedOn: order.CreatedOn,
+	}}
+
+	var products map[string]*Product
+	order.Items, products, err = s.priceItems(shop, items)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.priceOrder(ctx, order, products, couponCode)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(shop.DeliveryZones) > 0 {
+		zone := shop.deliveryZoneFor(user.Coordinates)
+		if zone == nil {
+			return nil, status.Errorf(codes.FailedPrecondition, "shop[%s] does not deliver to user[%s]", shop.ID, user.ID)
+		}
+
+		cmp, err := order.Total.Cmp(zone.MinOrderValue)
+		if err != nil {
+			return nil, status.Errorf(codes.FailedPrecondition, "delivery zone of shop[%s] is priced in another currency than the order", shop.ID)
+		}
+		if cmp < 0 {
+			return nil, status.Errorf(codes.FailedPrecondition, "order from shop[%s] is below the minimum order value of %s", shop.ID, zone.MinOrderValue)
+		}
+
+		order.DeliveryFee = zone.DeliveryFee
+		order.Total, err = order.Total.Add(zone.DeliveryFee)
+		if err != nil {
+			return nil, status.Errorf(codes.FailedPrecondition, "delivery zone of shop[%s] is priced in another currency than the order", shop.ID)
+		}
+	}
+
+	err = s.applyTaxes(order, shop, products)
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// priceItems validates the requested items against the shop and prices them
+// at the prices of the shop, merging repeated lines for the same variant so
+// that stock is checked against the total quantity requested. The products of
+// the items are returned keyed by id.
+func (s *GRPCMarketPlaceServer) priceItems(shop *Shop, items []*proto.OrderItemRequest) ([]OrderItem, map[string]*Product, error) {
+	serviceable := make(map[string]bool)
+	for _, id := range shop.ServiceableProductsId {
+		serviceable[id] = true
+	}
+
+	prices, err := s.shopPrices(shop.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var priced []OrderItem
+	products := make(map[string]*Product)
+	lines := make(map[[2]string]int)
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			return nil, nil, status.Errorf(codes.InvalidArgument, "quantity of product[%s] must be positive", item.ProductId)
+		}
+
+		if !serviceable[item.ProductId] {
+			return nil, nil, status.Errorf(codes.InvalidArgument, "product[%s] is not serviceable by shop[%s]", item.ProductId, shop.ID)
+		}
+
+		product, ok := products[item.ProductId]
+		if !ok {
+			product, err = getItemOrError(s.svc.productRepo.FindOneById(item.ProductId))
+			if err != nil {
+				s.svc.logger.Println("Error: ", err)
+				return nil, nil, errors.New("failed to get product")
+			}
+			products[item.ProductId] = product
+		}
+
+		variantId, err := product.resolveVariant(item.VariantId)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		key := [2]string{product.ID, variantId}
+		if i, ok := lines[key]; ok {
+			priced[i].Quantity += int(item.Quantity)
+			continue
+		}
+
+		lines[key] = len(priced)
+		priced = append(priced, OrderItem{
+			ProductID: product.ID,
+			VariantID: variantId,
+			Quantity:  int(item.Quantity),
+			Price:     prices[product.ID].priceOf(product, variantId),
+		})
+	}
+
+	return priced, products, nil
+}
+
+// saveOrder takes the ordered items out of the shop's inventory and stores the
+// order. It must run inside a transaction so that an out of stock item leaves
+// neither the inventory nor the orders modified. The inventories that dropped
+// below their reorder threshold are returned so that alerts can be raised once
+// the transaction commits.
+func (s *GRPCMarketPlaceServer) saveOrder(sessCtx mongo.SessionContext, order *Order) ([]*Inventory, error) {
+	var lowStock []*Inventory
+
+	for _, item := range order.Items {
+		inventory, err := s.adjustInventory(sessCtx, inventoryChange{
+			ShopID:    order.ShopID,
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Delta:     -item.Quantity,
+			Reason:    MovementReasonSale,
+			Actor:     order.UserID,
+			Reference: order.ID,
+		})
+		if err == errNegativeInventory || err == errReservedInventory {
+			return nil, status.Errorf(codes.FailedPrecondition, "product[%s] is out of stock", item.ProductID)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if crossedReorderThreshold(inventory, -item.Quantity) {
+			lowStock = append(lowStock, inventory)
+		}
+	}
+
+	err := s.redeemPromotions(sessCtx, order)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = getCollection(&s.svc.orderRepo.AbstractRepository).InsertOne(sessCtx, order)
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to create order")
+	}
+	return lowStock, nil
+}
+
+func (s *GRPCMarketPlaceServer) GetOrder(ctx context.Context, req *proto.GetRequest) (*proto.Order, error) {
+	order, err := getItemOrError(s.svc.orderRepo.FindOneById(req.Id))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		if err == mongo.ErrNoDocuments {
+			return nil, status.Error(codes.NotFound, "order does not exists")
+		}
+		return nil, errors.New("failed to get order")
+	}
+
+	if !isCaller(ctx, order.UserID) {
+		err = s.authorizeShop(ctx, order.ShopID, PermissionManageOrders)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return parseOrder(order), nil
+}
+
+func (s *GRPCMarketPlaceServer) ListOrdersForUser(ctx context.Context, req *proto.ListOrdersForUserRequest) (*proto.Orders, error) {
+	err := authorizeUser(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.listOrders(bson.M{"user_id": req.UserId})
+}
+
+func (s *GRPCMarketPlaceServer) ListOrdersForShop(ctx context.Context, req *proto.ListOrdersForShopRequest) (*proto.Orders, error) {
+	err := s.authorizeShop(ctx, req.ShopId, PermissionManageOrders)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.listOrders(bson.M{"shop_id": req.ShopId})
+}
+
+func (s *GRPCMarketPlaceServer) UpdateOrderStatus(ctx context.Context, req *proto.UpdateOrderStatusRequest) (*proto.Order, error) {
+	if !isValidOrderStatus(req.Status) {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown order status %q", req.Status)
+	}
+
+	collection := getCollection(&s.svc.orderRepo.AbstractRepository)
+	order := &Order{}
+
+	err := s.svc.withTransaction(ctx, func(sessCtx mongo.SessionContext) error {
+		err := collection.FindOne(sessCtx, bson.M{"_id": req.OrderId}).Decode(order)
+		if err == mongo.ErrNoDocuments {
+			return status.Error(codes.NotFound, "order does not exists")
+		}
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return errors.New("failed to get order")
+		}
+
+		// customers may cancel their own orders, anything else is up to the shop
+		if req.Status != OrderStatusCancelled || !isCaller(ctx, order.UserID) {
+			err = s.authorizeShop(ctx, order.ShopID, PermissionManageOrders)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !canTransitionOrder(order.Status, req.Status) {
+			return status.Errorf(codes.FailedPrecondition, "order cannot move from %s to %s", order.Status, req.Status)
+		}
+
+		change := OrderStatusChange{
+			Status:    req.Status,
+			Note:      req.Note,
+			ChangedOn: time.Now().Unix(),
+		}
+
+		// matching on the previous status guards against a concurrent
+		// transition being applied twice
+		res, err := collection.UpdateOne(sessCtx,
+			bson.M{"_id": order.ID, "status": order.Status},
+			bson.M{
+				"$set":  bson.M{"status": change.Status, "updatedOn": change.ChangedOn},
+				"$push": bson.M{"status_history": change},
+			},
+		)
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return errors.New("failed to update order status")
+		}
+		if res.MatchedCount == 0 {
+			return status.Error(codes.Aborted, "order was modified concurrently")
+		}
+
+		if restoresStock(req.Status) {
+			for _, item := range order.Items {
+				variantId, err := s.stockedVariant(item.ProductID, item.VariantID)
+				if err != nil {
+					return err
+				}
+
+				_, err = s.adjustInventory(sessCtx, inventoryChange{
+					ShopID:    order.ShopID,
+					ProductID: item.ProductID,
+					VariantID: variantId,
+					Delta:     item.Quantity,
+					Reason:    MovementReasonReturn,
+					Reference: order.ID,
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			err = s.releasePromotions(sessCtx, order)
+			if err != nil {
+				return err
+			}
+		}
+
+		order.Status = change.Status
+		order.StatusHistory = append(order.StatusHistory, change)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return parseOrder(order), nil
+}
+
+func (s *GRPCMarketPlaceServer) listOrders(filter bson.M) (*proto.Orders, error) {
+	sort := bson.D{{Key: "createdOn", Value: -1}}
+
+	orders, err := getItemOrError(s.svc.orderRepo.Find(filter, sort, 0, 0))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to get orders")
+	}
+
+	result := &proto.Orders{}
+	for i := range orders {
+		result.Orders = append(result.Orders, parseOrder(&orders[i]))
+	}
+
+	return result, nil
+}
+
+func parseOrder(order *Order) *proto.Order {
+	porder := &proto.Order{
+		Id:          order.ID,
+		UserId:      order.UserID,
+		ShopId:      order.ShopID,
+		DeliveryFee: parseMoney(order.DeliveryFee),
+		Total:       parseMoney(order.Total),
+		Tax:         parseMoney(order.Tax),
+		Status:      order.Status,
+		CreatedOn:   order.CreatedOn,
+	}
+
+	for _, item := range order.Items {
+		porder.Items = append(porder.Items, &proto.OrderItem{
+			ProductId: item.ProductID,
+			VariantId: item.VariantID,
+			Quantity:  int32(item.Quantity),
+			Price:     parseMoney(item.Price),
+			Taxes:     parseLineTaxes(item.Taxes),
+		})
+	}
+
+	for _, discount := range order.Discounts {
+		porder.Discounts = append(porder.Discounts, &proto.OrderDiscount{
+			PromotionId: discount.PromotionID,
+			Name:        discount.Name,
+			Code:        discount.Code,
+			Amount:      parseMoney(discount.Amount),
+		})
+	}
+
+	for _, change := range order.StatusHistory {
+		porder.StatusHistory = append(porder.StatusHistory, &proto.OrderStatusChange{
+			Status:    change.Status,
+			Note:      change.Note,
+			ChangedOn: change.ChangedOn,
+		})
+	}
+
+	return porder
+}
+
+func (s *GRPCMarketPlaceServer) AddToCart(ctx context.Context, req *proto.AddToCartRequest) (*proto.Cart, error) {
+	err := authorizeUser(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Quantity <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "quantity must be positive")
+	}
+
+	if !s.svc.userRepo.IsExistsById(req.UserId) {
+		s.svc.logger.Printf("Error: user[%s] does not exists", req.UserId)
+		return nil, status.Error(codes.NotFound, "user does not exists")
+	}
+
+	shop, err := getItemOrError(s.svc.shopRepo.FindOneById(req.ShopId))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, status.Error(codes.NotFound, "failed to get shop")
+	}
+
+	var serviceable bool
+	for _, id := range shop.ServiceableProductsId {
+		if id == req.ProductId {
+			serviceable = true
+		}
+	}
+
+	if !serviceable {
+		return nil, status.Errorf(codes.InvalidArgument, "product[%s] is not serviceable by shop[%s]", req.ProductId, req.ShopId)
+	}
+
+	_, variantId, err := s.productVariant(req.ProductId, req.VariantId)
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := s.getCart(req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	var found bool
+	for i := range cart.Items {
+		item := &cart.Items[i]
+		if item.ShopID == req.ShopId && item.ProductID == req.ProductId && item.VariantID == variantId {
+			item.Quantity += int(req.Quantity)
+			found = true
+		}
+	}
+
+	if !found {
+		cart.Items = append(cart.Items, CartItem{
+			ShopID:    req.ShopId,
+			ProductID: req.ProductId,
+			VariantID: variantId,
+			Quantity:  int(req.Quantity),
+		})
+	}
+
+	err = getErrorFromChan(s.svc.cartRepo.Save(cart))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to update cart")
+	}
+
+	return parseCart(cart), nil
+}
+
+func (s *GRPCMarketPlaceServer) RemoveFromCart(ctx context.Context, req *proto.RemoveFromCartRequest) (*proto.Cart, error) {
+	err := authorizeUser(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Quantity < 0 {
+		return nil, status.Error(codes.InvalidArgument, "quantity cannot be negative")
+	}
+
+	_, variantId, err := s.productVariant(req.ProductId, req.VariantId)
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := s.getCart(req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	items := cart.Items[:0]
+	for _, item := range cart.Items {
+		if item.ShopID == req.ShopId && item.ProductID == req.ProductId && item.VariantID == variantId {
+			item.Quantity -= int(req.Quantity)
+			if req.Quantity == 0 || item.Quantity <= 0 {
+				continue
+			}
+		}
+		items = append(items, item)
+	}
+	cart.Items = items
+
+	err = getErrorFromChan(s.svc.cartRepo.Save(cart))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to update cart")
+	}
+
+	return parseCart(cart), nil
+}
+
+func (s *GRPCMarketPlaceServer) GetCart(ctx context.Context, req *proto.GetCartRequest) (*proto.Cart, error) {
+	err := authorizeUser(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := s.getCart(req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseCart(cart), nil
+}
+
+// Checkout turns the user's cart into one order per shop. Either every order
+// is placed and the cart emptied, or nothing changes.
+func (s *GRPCMarketPlaceServer) Checkout(ctx context.Context, req *proto.CheckoutRequest) (*proto.Orders, error) {
+	err := authorizeUser(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := getItemOrError(s.svc.userRepo.FindOneById(req.UserId))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, status.Error(codes.NotFound, "user does not exists")
+	}
+
+	cart, err := s.getCart(req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cart.Items) == 0 {
+		return nil, status.Error(codes.FailedPrecondition, "cart is empty")
+	}
+
+	itemsByShop := make(map[string][]*proto.OrderItemRequest)
+	var shopIds []string
+	for _, item := range cart.Items {
+		if _, ok := itemsByShop[item.ShopID]; !ok {
+			shopIds = append(shopIds, item.ShopID)
+		}
+		itemsByShop[item.ShopID] = append(itemsByShop[item.ShopID], &proto.OrderItemRequest{
+			ProductId: item.ProductID,
+			VariantId: item.VariantID,
+			Quantity:  int32(item.Quantity),
+		})
+	}
+
+	var coupon *Promotion
+	if req.CouponCode != "" {
+		coupon, err = s.findCoupon(req.CouponCode)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var orders []*Order
+	var couponOrders []int
+	for i, shopId := range shopIds {
+		order, err := s.buildOrder(ctx, user, shopId, itemsByShop[shopId], "")
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+
+		if coupon != nil && coupon.appliesTo(shopId) {
+			couponOrders = append(couponOrders, i)
+		}
+	}
+
+	if coupon != nil && len(couponOrders) == 0 {
+		return nil, status.Error(codes.FailedPrecondition, "coupon is not valid for any shop in the cart")
+	}
+
+	// a coupon is redeemed once per checkout, by the order it takes the most
+	// off among the ones from shops it is valid for
+	best := -1
+	var bestOrder *Order
+	var bestDiscount Money
+	for _, i := range couponOrders {
+		order, err := s.buildOrder(ctx, user, shopIds[i], itemsByShop[shopIds[i]], coupon.Code)
+		if err != nil {
+			return nil, err
+		}
+
+		discount := order.discountBy(coupon.ID)
+		if cmp, err := discount.Cmp(bestDiscount); best < 0 || err == nil && cmp > 0 {
+			best, bestOrder, bestDiscount = i, order, discount
+		}
+	}
+	if best >= 0 {
+		orders[best] = bestOrder
+	}
+
+	var lowStock []*Inventory
+	err = s.svc.withTransaction(ctx, func(sessCtx mongo.SessionContext) error {
+		// the transaction may be retried, so start from scratch every time
+		lowStock = nil
+
+		for _, order := range orders {
+			low, err := s.saveOrder(sessCtx, order)
+			if err != nil {
+				return err
+			}
+			lowStock = append(lowStock, low...)
+		}
+
+		_, err := getCollection(&s.svc.cartRepo.AbstractRepository).UpdateOne(sessCtx,
+			bson.M{"_id": cart.UserID},
+			bson.M{"$set": bson.M{"items": []CartItem{}, "updatedOn": time.Now().Unix()}},
+		)
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return errors.New("failed to empty cart")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.svc.raiseLowStockAlerts(lowStock...)
+
+	result := &proto.Orders{}
+	for _, order := range orders {
+		result.Orders = append(result.Orders, parseOrder(order))
+	}
+
+	return result, nil
+}
+
+// getCart returns the cart of a user, or an empty one if the user has not
+// added anything yet.
+func (s *GRPCMarketPlaceServer) getCart(userId string) (*Cart, error) {
+	cart, err := getItemOrError(s.svc.cartRepo.FindOneById(userId))
+	if err == mongo.ErrNoDocuments {
+		return &Cart{UserID: userId}, nil
+	}
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to get cart")
+	}
+
+	return cart, nil
+}
+
+func parseCart(cart *Cart) *proto.Cart {
+	pcart := &proto.Cart{
+		UserId: cart.UserID,
+	}
+
+	for _, item := range cart.Items {
+		pcart.Items = append(pcart.Items, &proto.CartItem{
+			ShopId:    item.ShopID,
+			ProductId: item.ProductID,
+			VariantId: item.VariantID,
+			Quantity:  int32(item.Quantity),
+		})
+	}
+
+	return pcart
+}
+
+// TransferStock moves stock of a product from one shop to another in a single
+// transaction, making the product serviceable by the destination shop first
+// if it is not already.
+func (s *GRPCMarketPlaceServer) TransferStock(ctx context.Context, req *proto.TransferStockRequest) (*proto.StockTransfer, error) {
+	if req.Quantity <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "quantity must be positive")
+	}
+
+	if req.FromShopId == req.ToShopId {
+		return nil, status.Error(codes.InvalidArgument, "cannot transfer stock to the same shop")
+	}
+
+	_, variantId, err := s.productVariant(req.ProductId, req.VariantId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, shopId := range []string{req.FromShopId, req.ToShopId} {
+		if !s.svc.shopRepo.IsExistsById(shopId) {
+			s.svc.logger.Printf("Error: shop[%s] does not exists", shopId)
+			return nil, status.Errorf(codes.NotFound, "shop[%s] does not exists", shopId)
+		}
+
+		err = s.authorizeShop(ctx, shopId, PermissionManageInventory)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	transfer := StockTransfer{
+		ID:         primitive.NewObjectID().Hex(),
+		FromShopID: req.FromShopId,
+		ToShopID:   req.ToShopId,
+		ProductID:  req.ProductId,
+		VariantID:  variantId,
+		Quantity:   int(req.Quantity),
+		Actor:      callerFrom(ctx).ID,
+		CreatedOn:  time.Now().Unix(),
+	}
+
+	var from *Inventory
+	err = s.svc.withTransaction(ctx, func(sessCtx mongo.SessionContext) error {
+		var err error
+		from, err = s.adjustInventory(sessCtx, inventoryChange{
+			ShopID:    transfer.FromShopID,
+			ProductID: transfer.ProductID,
+			VariantID: transfer.VariantID,
+			Delta:     -transfer.Quantity,
+			Reason:    MovementReasonTransfer,
+			Actor:     transfer.Actor,
+			Reference: transfer.ID,
+		})
+		if err == errNegativeInventory || err == errReservedInventory {
+			return status.Errorf(codes.FailedPrecondition, "shop[%s] does not have enough available stock of product[%s]", transfer.FromShopID, transfer.ProductID)
+		}
+		if err != nil {
+			return err
+		}
+
+		err = s.ensureServiceable(sessCtx, transfer.ToShopID, transfer.ProductID, transfer.VariantID)
+		if err != nil {
+			return err
+		}
+
+		to, err := s.adjustInventory(sessCtx, inventoryChange{
+			ShopID:    transfer.ToShopID,
+			ProductID: transfer.ProductID,
+			VariantID: transfer.VariantID,
+			Delta:     transfer.Quantity,
+			Reason:    MovementReasonTransfer,
+			Actor:     transfer.Actor,
+			Reference: transfer.ID,
+		})
+		if err != nil {
+			return err
+		}
+
+		transfer.FromQuantityAfter = from.Quantity
+		transfer.ToQuantityAfter = to.Quantity
+
+		_, err = getCollection(&s.svc.transferRepo.AbstractRepository).InsertOne(sessCtx, transfer)
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return errors.New("failed to record stock transfer")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if crossedReorderThreshold(from, -transfer.Quantity) {
+		s.svc.raiseLowStockAlerts(from)
+	}
+
+	return &proto.StockTransfer{
+		Id:                transfer.ID,
+		FromShopId:        transfer.FromShopID,
+		ToShopId:          transfer.ToShopID,
+		ProductId:         transfer.ProductID,
+		VariantId:         transfer.VariantID,
+		Quantity:          int32(transfer.Quantity),
+		FromQuantityAfter: int32(transfer.FromQuantityAfter),
+		ToQuantityAfter:   int32(transfer.ToQuantityAfter),
+		Actor:             transfer.Actor,
+		CreatedOn:         transfer.CreatedOn,
 	}, nil
 }
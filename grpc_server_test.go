@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/NikhilSharmaWe/marketplace/proto"
+	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newTestServer returns a server backed by the mongo of MONGO-URI, read from
+// vars.env like the server does. The test is skipped when that mongo cannot
+// be used: it must be reachable, a replica set for the transactions and
+// reached over TLS, the only way go-api-boot connects. With REQUIRE_MONGO set,
+// e.g. in CI, the test fails instead, so that it cannot pass without running.
+func newTestServer(tb testing.TB) *GRPCMarketPlaceServer {
+	tb.Helper()
+
+	skipf := tb.Skipf
+	if os.Getenv("REQUIRE_MONGO") != "" {
+		skipf = tb.Fatalf
+	}
+
+	_ = godotenv.Load("vars.env")
+	uri := os.Getenv("MONGO-URI")
+	if uri == "" {
+		skipf("MONGO-URI is not set")
+	}
+
+	cs, err := connstring.ParseAndValidate(uri)
+	if err != nil {
+		skipf("invalid MONGO-URI: %v", err)
+	}
+	if !cs.SSL {
+		skipf("MONGO-URI does not enable TLS")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		skipf("mongo is not available: %v", err)
+	}
+	defer client.Disconnect(ctx)
+
+	hello := bson.M{}
+	err = client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	if err != nil {
+		skipf("mongo is not available: %v", err)
+	}
+	if _, ok := hello["setName"]; !ok {
+		skipf("mongo is not a replica set")
+	}
+
+	if grpcAddr == nil {
+		// the client to the grpc server is only dialed when used
+		addr := "localhost:0"
+		grpcAddr = &addr
+	}
+
+	return NewGRPCPriceFetcherServer(*newApplication())
+}
+
+// TestUpdateInventoryConcurrentDecrements hammers UpdateInventory with more
+// concurrent decrements than there is stock, which must neither lose an
+// update nor take the quantity below zero.
+func TestUpdateInventoryConcurrentDecrements(t *testing.T) {
+	s := newTestServer(t)
+
+	const (
+		start   = 50
+		workers = 200
+	)
+
+	ctx := context.WithValue(context.Background(), callerKey{}, &User{
+		ID:   primitive.NewObjectID().Hex(),
+		Role: RoleAdmin,
+	})
+
+	product := Product{ID: primitive.NewObjectID().Hex(), Name: "race test"}
+	inventory := Inventory{
+		ID:        primitive.NewObjectID().Hex(),
+		ShopID:    primitive.NewObjectID().Hex(),
+		ProductID: product.ID,
+		Quantity:  start,
+	}
+
+	_, err := getCollection(&s.svc.productRepo.AbstractRepository).InsertOne(ctx, product)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = getCollection(&s.svc.inventoryRepo.AbstractRepository).InsertOne(ctx, inventory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		ctx := context.Background()
+		getCollection(&s.svc.productRepo.AbstractRepository).DeleteOne(ctx, bson.M{"_id": product.ID})
+		getCollection(&s.svc.inventoryRepo.AbstractRepository).DeleteOne(ctx, bson.M{"_id": inventory.ID})
+		getCollection(&s.svc.movementRepo.AbstractRepository).DeleteMany(ctx, bson.M{"shop_id": inventory.ShopID})
+	})
+
+	var (
+		wg        sync.WaitGroup
+		succeeded int64
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			res, err := s.UpdateInventory(ctx, &proto.UpdateInventoryRequest{
+				ShopId:    inventory.ShopID,
+				ProductId: product.ID,
+				Change:    1,
+			})
+			if status.Code(err) == codes.FailedPrecondition {
+				return
+			}
+			if err != nil {
+				t.Errorf("UpdateInventory: %v", err)
+				return
+			}
+
+			atomic.AddInt64(&succeeded, 1)
+			if res.Quantity < 0 {
+				t.Errorf("quantity went negative: %d", res.Quantity)
+			}
+		}()
+	}
+	wg.Wait()
+
+	final, err := getItemOrError(s.svc.inventoryRepo.FindOneById(inventory.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if final.Quantity < 0 {
+		t.Errorf("final quantity is negative: %d", final.Quantity)
+	}
+	if want := start - int(succeeded); final.Quantity != want {
+		t.Errorf("final quantity is %d, want %d after %d successful decrements", final.Quantity, want, succeeded)
+	}
+	if succeeded != start {
+		t.Errorf("%d decrements succeeded, want %d", succeeded, start)
+	}
+
+	movements, err := getCollection(&s.svc.movementRepo.AbstractRepository).CountDocuments(ctx, bson.M{"shop_id": inventory.ShopID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if movements != succeeded {
+		t.Errorf("%d movements were recorded for %d successful decrements", movements, succeeded)
+	}
+}
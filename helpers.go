@@ -1,9 +1,18 @@
 package main
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 func calculateDistance(coord1, coord2 [2]float64) float64 {
@@ -59,3 +68,91 @@ func getItemOrError[T any](itemCh <-chan T, errCh <-chan error) (T, error) {
 	}
 	return item, err
 }
+
+// getCollection exposes the underlying mongo collection of a repository so
+// that queries which the odm does not support (sessions, atomic updates,
+// aggregations) can be issued against it.
+func getCollection[T any](repo *odm.AbstractRepository[T]) *mongo.Collection {
+	return odm.GetClient().Database(repo.Database).Collection(repo.CollectionName)
+}
+
+// withTransaction runs fn inside a mongo transaction, committing if fn
+// returns nil and aborting otherwise.
+func (app *application) withTransaction(ctx context.Context, fn func(sessCtx mongo.SessionContext) error) error {
+	session, err := app.mongoClient.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	return err
+}
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// encodePageToken turns the offset of the next page into an opaque token.
+func encodePageToken(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+// decodePageToken returns the offset encoded in token, zero for the first page.
+func decodePageToken(token string) (int, error) {
+	if token == "" {
+		return 0, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, err
+	}
+
+	offset, err := strconv.Atoi(string(raw))
+	if err != nil || offset < 0 {
+		return 0, errors.New("invalid page token")
+	}
+	return offset, nil
+}
+
+// uniqueStrings returns values without empty strings and duplicates, keeping
+// the order of first appearance.
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, value := range values {
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		result = append(result, value)
+	}
+	return result
+}
+
+// parseFloats parses a list of numbers separated by sep.
+func parseFloats(list, sep string) ([]float64, error) {
+	var result []float64
+	for _, value := range strings.Split(list, sep) {
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, parsed)
+	}
+
+	return result, nil
+}
+
+// readUnixTime parses a time given as unix seconds or as now.
+func readUnixTime(value string) (int64, error) {
+	if value == "now" {
+		return time.Now().Unix(), nil
+	}
+
+	return strconv.ParseInt(value, 10, 64)
+}
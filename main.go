@@ -26,8 +26,20 @@ type application struct {
 	productRepo     ProductRepository
 	inventoryRepo   InventoryRepository
 	serviceableRepo ServiceableProductRepository
+	orderRepo       OrderRepository
+	cartRepo        CartRepository
+	reservationRepo ReservationRepository
+	movementRepo    InventoryMovementRepository
+	transferRepo    StockTransferRepository
+	categoryRepo    CategoryRepository
+	promotionRepo   PromotionRepository
+	taxRuleRepo     TaxRuleRepository
+	priceChangeRepo PriceChangeRepository
+	scheduleRepo    ScheduledPriceChangeRepository
+	invitationRepo  StaffInvitationRepository
 	goApiBoot       *server.GoApiBoot
 	grpcClient      proto.MarketplaceServiceClient
+	stockNotifier   StockNotifier
 	logger          *log.Logger
 	mongoClient     *mongo.Client
 }
@@ -52,6 +64,50 @@ type ServiceableProductRepository struct {
 	odm.AbstractRepository[ServiceableProduct]
 }
 
+type OrderRepository struct {
+	odm.AbstractRepository[Order]
+}
+
+type CartRepository struct {
+	odm.AbstractRepository[Cart]
+}
+
+type ReservationRepository struct {
+	odm.AbstractRepository[Reservation]
+}
+
+type InventoryMovementRepository struct {
+	odm.AbstractRepository[InventoryMovement]
+}
+
+type StockTransferRepository struct {
+	odm.AbstractRepository[StockTransfer]
+}
+
+type CategoryRepository struct {
+	odm.AbstractRepository[Category]
+}
+
+type PromotionRepository struct {
+	odm.AbstractRepository[Promotion]
+}
+
+type TaxRuleRepository struct {
+	odm.AbstractRepository[TaxRule]
+}
+
+type PriceChangeRepository struct {
+	odm.AbstractRepository[PriceChange]
+}
+
+type ScheduledPriceChangeRepository struct {
+	odm.AbstractRepository[ScheduledPriceChange]
+}
+
+type StaffInvitationRepository struct {
+	odm.AbstractRepository[StaffInvitation]
+}
+
 func main() {
 	grpcAddr = flag.String("grpc", ":4000", "listen address of the grpc transport")
 	webAddr = flag.String("web", ":3000", "listen address of the web transport")
@@ -105,6 +161,83 @@ func newApplication() *application {
 		},
 	}
 
+	orderRepo := &OrderRepository{
+		AbstractRepository: odm.AbstractRepository[Order]{
+			Database:       "market",
+			CollectionName: "order",
+		},
+	}
+
+	cartRepo := &CartRepository{
+		AbstractRepository: odm.AbstractRepository[Cart]{
+			Database:       "market",
+			CollectionName: "cart",
+		},
+	}
+
+	reservationRepo := &ReservationRepository{
+		AbstractRepository: odm.AbstractRepository[Reservation]{
+			Database:       "market",
+			CollectionName: "reservation",
+		},
+	}
+
+	inventoryMovementRepo := &InventoryMovementRepository{
+		AbstractRepository: odm.AbstractRepository[InventoryMovement]{
+			Database:       "market",
+			CollectionName: "inventoryMovement",
+		},
+	}
+
+	transferRepo := &StockTransferRepository{
+		AbstractRepository: odm.AbstractRepository[StockTransfer]{
+			Database:       "market",
+			CollectionName: "stockTransfer",
+		},
+	}
+
+	categoryRepo := &CategoryRepository{
+		AbstractRepository: odm.AbstractRepository[Category]{
+			Database:       "market",
+			CollectionName: "category",
+		},
+	}
+
+	promotionRepo := &PromotionRepository{
+		AbstractRepository: odm.AbstractRepository[Promotion]{
+			Database:       "market",
+			CollectionName: "promotion",
+		},
+	}
+
+	taxRuleRepo := &TaxRuleRepository{
+		AbstractRepository: odm.AbstractRepository[TaxRule]{
+			Database:       "market",
+			CollectionName: "taxRule",
+		},
+	}
+
+	priceChangeRepo := &PriceChangeRepository{
+		AbstractRepository: odm.AbstractRepository[PriceChange]{
+			Database:       "market",
+			CollectionName: "priceChange",
+		},
+	}
+
+	scheduleRepo := &ScheduledPriceChangeRepository{
+		AbstractRepository: odm.AbstractRepository[ScheduledPriceChange]{
+			Database:       "market",
+			CollectionName: "scheduledPriceChange",
+		},
+	}
+
+	invitationRepo := &StaffInvitationRepository{
+		AbstractRepository: odm.AbstractRepository[StaffInvitation]{
+			Database:       "market",
+			CollectionName: "staffInvitation",
+		},
+	}
+
 	grpcClient, err := newGRPCClient(*grpcAddr)
 	if err != nil {
 		log.Fatal(err)
@@ -121,14 +254,81 @@ func newApplication() *application {
 		productRepo:     *productRepo,
 		inventoryRepo:   *inventoryRepo,
 		serviceableRepo: *serviceableProductRepo,
+		orderRepo:       *orderRepo,
+		cartRepo:        *cartRepo,
+		reservationRepo: *reservationRepo,
+		movementRepo:    *inventoryMovementRepo,
+		transferRepo:    *transferRepo,
+		categoryRepo:    *categoryRepo,
+		promotionRepo:   *promotionRepo,
+		taxRuleRepo:     *taxRuleRepo,
+		priceChangeRepo: *priceChangeRepo,
+		scheduleRepo:    *scheduleRepo,
+		invitationRepo:  *invitationRepo,
 		goApiBoot:       goApiBoot,
 		logger:          logger,
 		grpcClient:      grpcClient,
+		stockNotifier:   newStockNotifier(logger),
 		mongoClient:     mongoClient,
 	}
 }
 
 func (app *application) start() {
+	err := app.setupAuth()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = app.setupStaffIndexes()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = app.setupGeoIndexes()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = app.migrateOperationHours()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = app.setupCatalogIndexes()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = app.setupPromotionIndexes()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = app.setupTaxIndexes()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = app.loadTaxRules()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = app.setupPriceIndexes()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = app.migrateInventoryVariants()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = app.migrateMoney()
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	app.setupGoApiBoot()
 	app.goApiBoot.Start(*grpcAddr, *webAddr)
 }
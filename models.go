@@ -1,44 +1,194 @@
 package main
 
+// Money is an amount in the minor units of a currency, e.g. cents, so that
+// prices are exact.
+type Money struct {
+	CurrencyCode string `bson:"currency_code" json:"currencyCode"`
+	MinorUnits   int64  `bson:"minor_units" json:"minorUnits"`
+}
+
+// GeoPoint is a GeoJSON point. Unlike the [latitude, longitude] coordinates
+// used everywhere else, GeoJSON orders them as [longitude, latitude].
+type GeoPoint struct {
+	Type        string     `bson:"type"`
+	Coordinates [2]float64 `bson:"coordinates"`
+}
+
+// GeoPolygon is a GeoJSON polygon made of closed [longitude, latitude] rings,
+// the first one being the outer boundary and any others holes in it.
+type GeoPolygon struct {
+	Type        string         `bson:"type"`
+	Coordinates [][][2]float64 `bson:"coordinates"`
+}
+
+// DeliveryZone is an area a shop delivers to, along with what delivering there
+// costs.
+type DeliveryZone struct {
+	Name          string     `bson:"name"`
+	Area          GeoPolygon `bson:"area"`
+	DeliveryFee   Money      `bson:"delivery_fee"`
+	MinOrderValue Money      `bson:"min_order_value"`
+}
+
+// Shift is a period a shop is open for, between two "15:04" wall clock times in
+// the time zone of the shop. A shift closing at or before the time it opens
+// runs past midnight.
+type Shift struct {
+	Open  string `bson:"open"`
+	Close string `bson:"close"`
+}
+
+type DayHours struct {
+	Day    string  `bson:"day"`
+	Shifts []Shift `bson:"shifts"`
+}
+
+// HoursOverride replaces the weekly schedule on a single "2006-01-02" date,
+// e.g. for a holiday. Without shifts the shop is closed all day.
+type HoursOverride struct {
+	Date   string  `bson:"date"`
+	Shifts []Shift `bson:"shifts"`
+	Note   string  `bson:"note"`
+}
+
+type OperatingHours struct {
+	TimeZone string `bson:"time_zone"`
+	// days missing from the schedule are closed
+	Weekly    []DayHours      `bson:"weekly"`
+	Overrides []HoursOverride `bson:"overrides"`
+}
+
 type Shop struct {
-	ID                    string     `bson:"_id,omitempty"`
-	Name                  string     `bson:"name"`
-	Location              string     `bson:"location"`
-	OperationHours        string     `bson:"operation_hours"`
-	ServiceableProductsId []string   `bson:"products"`
-	Coordinates           [2]float64 `bson:"coordinates"`
+	ID       string `bson:"_id,omitempty"`
+	Name     string `bson:"name"`
+	Location string `bson:"location"`
+	// free-form hours, kept only when they could not be parsed into
+	// OperatingHours
+	OperationHours        string          `bson:"operation_hours,omitempty"`
+	OperatingHours        *OperatingHours `bson:"operating_hours,omitempty"`
+	ServiceableProductsId []string        `bson:"products"`
+	Coordinates           [2]float64      `bson:"coordinates"`
+	Position              GeoPoint        `bson:"position"`
+	// shops without delivery zones are only found by nearby searches asking
+	// for them, but take orders from anywhere
+	DeliveryZones []DeliveryZone `bson:"delivery_zones,omitempty"`
+	// the merchant managing the shop, shops created before logins existed are
+	// managed by admins only
+	OwnerID string      `bson:"owner_id,omitempty"`
+	Staff   []ShopStaff `bson:"staff,omitempty"`
 }
 
 func (s Shop) Id() string {
 	return s.ID
 }
 
+const (
+	PermissionManageInventory = "MANAGE_INVENTORY"
+	PermissionManageCatalog   = "MANAGE_CATALOG"
+	PermissionManageOrders    = "MANAGE_ORDERS"
+)
+
+// ShopStaff is a merchant managing a shop for its owner, limited to what its
+// permissions allow.
+type ShopStaff struct {
+	UserID      string   `bson:"user_id"`
+	Permissions []string `bson:"permissions"`
+	AddedOn     int64    `bson:"addedOn"`
+}
+
+const (
+	InvitationStatusPending  = "PENDING"
+	InvitationStatusAccepted = "ACCEPTED"
+	InvitationStatusDeclined = "DECLINED"
+	InvitationStatusRevoked  = "REVOKED"
+)
+
+// StaffInvitation asks a user to join the staff of a shop. Only the user it
+// was sent to may respond to it; Email is the one they had when invited.
+type StaffInvitation struct {
+	ID          string   `bson:"_id,omitempty"`
+	ShopID      string   `bson:"shop_id"`
+	UserID      string   `bson:"user_id"`
+	Email       string   `bson:"email"`
+	Permissions []string `bson:"permissions"`
+	Status      string   `bson:"status"`
+	InvitedBy   string   `bson:"invited_by"`
+	CreatedOn   int64    `bson:"createdOn"`
+	ExpiresAt   int64    `bson:"expires_at"`
+	RespondedOn int64    `bson:"respondedOn,omitempty"`
+}
+
+func (s StaffInvitation) Id() string {
+	return s.ID
+}
+
+// Variant is a version of a product, such as a size or colour, with its own
+// stock and price.
+type Variant struct {
+	ID         string            `bson:"id"`
+	SKU        string            `bson:"sku"`
+	Attributes map[string]string `bson:"attributes"`
+	Price      Money             `bson:"price"`
+}
+
 type Product struct {
-	ID          string  `bson:"_id,omitempty"`
-	Name        string  `bson:"name"`
-	Description string  `bson:"description"`
-	Price       float64 `bson:"price"`
+	ID          string   `bson:"_id,omitempty"`
+	Name        string   `bson:"name"`
+	Description string   `bson:"description"`
+	Price       Money    `bson:"price"`
+	CategoryIDs []string `bson:"category_ids"`
+	// products without variants are stocked and sold as a whole
+	Variants  []Variant `bson:"variants,omitempty"`
+	CreatedOn int64     `bson:"createdOn"`
+	// bumped by SchedulePriceChange so that concurrent schedules of the
+	// product conflict
+	ScheduleVersion int64 `bson:"schedule_version"`
 }
 
 func (s Product) Id() string {
 	return s.ID
 }
 
+// Category is a node of the product taxonomy. AncestorIDs lists the categories
+// above it from the root down so that a whole subtree can be found with a
+// single query.
+type Category struct {
+	ID          string   `bson:"_id,omitempty"`
+	Name        string   `bson:"name"`
+	ParentID    string   `bson:"parent_id"`
+	AncestorIDs []string `bson:"ancestor_ids"`
+	CreatedOn   int64    `bson:"createdOn"`
+}
+
+func (s Category) Id() string {
+	return s.ID
+}
+
 type Inventory struct {
 	ID        string `bson:"_id,omitempty"`
 	ShopID    string `bson:"shop_id"`
 	ProductID string `bson:"product_id"`
+	VariantID string `bson:"variant_id"`
 	Quantity  int    `bson:"quantity"`
+	Reserved  int    `bson:"reserved"`
+	Version   int64  `bson:"version"`
+	// alerts are raised when the quantity drops below this, zero disables them
+	ReorderThreshold int `bson:"reorder_threshold"`
 }
 
 func (s Inventory) Id() string {
 	return s.ID
 }
 
+// ServiceableProduct holds what is specific to a product in one shop. Prices
+// left unset fall back to the catalog prices of the product.
 type ServiceableProduct struct {
-	ID        string `bson:"_id,omitempty"`
-	ProductID string `bson:"product_id"`
-	ShopID    string `bson:"shop_id"`
+	ID            string           `bson:"_id,omitempty"`
+	ProductID     string           `bson:"product_id"`
+	ShopID        string           `bson:"shop_id"`
+	Price         *Money           `bson:"price,omitempty"`
+	VariantPrices map[string]Money `bson:"variant_prices,omitempty"`
+	UpdatedOn     int64            `bson:"updatedOn,omitempty"`
 }
 
 func (s ServiceableProduct) Id() string {
@@ -55,13 +205,278 @@ func (s ShopsServiceableProducts) Id() string {
 	return s.ID
 }
 
+const (
+	RoleCustomer = "CUSTOMER"
+	RoleMerchant = "MERCHANT"
+	RoleAdmin    = "ADMIN"
+)
+
 type User struct {
 	ID          string     `bson:"_id,omitempty"`
 	Name        string     `bson:"name"`
 	Location    string     `bson:"location"`
 	Coordinates [2]float64 `bson:"coordinates"`
+	Position    GeoPoint   `bson:"position"`
+	// users created before logins existed have neither
+	Email        string `bson:"email,omitempty"`
+	PasswordHash string `bson:"password_hash,omitempty"`
+	Role         string `bson:"role"`
+	// bumped to revoke the tokens issued to the user, see tokenClaims
+	TokenVersion int64 `bson:"token_version"`
 }
 
 func (s User) Id() string {
 	return s.ID
 }
+
+const (
+	OrderStatusPlaced         = "PLACED"
+	OrderStatusAccepted       = "ACCEPTED"
+	OrderStatusPacked         = "PACKED"
+	OrderStatusOutForDelivery = "OUT_FOR_DELIVERY"
+	OrderStatusDelivered      = "DELIVERED"
+	OrderStatusCancelled      = "CANCELLED"
+	OrderStatusRejected       = "REJECTED"
+)
+
+type OrderStatusChange struct {
+	Status    string `bson:"status"`
+	Note      string `bson:"note"`
+	ChangedOn int64  `bson:"changed_on"`
+}
+
+type OrderItem struct {
+	ProductID string    `bson:"product_id"`
+	VariantID string    `bson:"variant_id"`
+	Quantity  int       `bson:"quantity"`
+	Price     Money     `bson:"price"`
+	Taxes     []LineTax `bson:"taxes,omitempty"`
+}
+
+type Order struct {
+	ID            string              `bson:"_id,omitempty"`
+	UserID        string              `bson:"user_id"`
+	ShopID        string              `bson:"shop_id"`
+	Items         []OrderItem         `bson:"items"`
+	DeliveryFee   Money               `bson:"delivery_fee"`
+	Total         Money               `bson:"total"`
+	Discounts     []OrderDiscount     `bson:"discounts,omitempty"`
+	Tax           Money               `bson:"tax"`
+	Status        string              `bson:"status"`
+	StatusHistory []OrderStatusChange `bson:"status_history"`
+	CreatedOn     int64               `bson:"createdOn"`
+}
+
+func (s Order) Id() string {
+	return s.ID
+}
+
+type CartItem struct {
+	ShopID    string `bson:"shop_id"`
+	ProductID string `bson:"product_id"`
+	VariantID string `bson:"variant_id"`
+	Quantity  int    `bson:"quantity"`
+}
+
+// Cart is keyed by the id of the user it belongs to.
+type Cart struct {
+	UserID string     `bson:"_id,omitempty"`
+	Items  []CartItem `bson:"items"`
+}
+
+func (s Cart) Id() string {
+	return s.UserID
+}
+
+const (
+	ReservationStatusActive    = "ACTIVE"
+	ReservationStatusCommitted = "COMMITTED"
+	ReservationStatusReleased  = "RELEASED"
+)
+
+type Reservation struct {
+	ID        string `bson:"_id,omitempty"`
+	ShopID    string `bson:"shop_id"`
+	ProductID string `bson:"product_id"`
+	VariantID string `bson:"variant_id"`
+	Quantity  int    `bson:"quantity"`
+	Reference string `bson:"reference"`
+	Status    string `bson:"status"`
+	ExpiresAt int64  `bson:"expires_at"`
+}
+
+func (s Reservation) Id() string {
+	return s.ID
+}
+
+const (
+	MovementReasonRestock    = "restock"
+	MovementReasonSale       = "sale"
+	MovementReasonAdjustment = "adjustment"
+	MovementReasonReturn     = "return"
+	MovementReasonTransfer   = "transfer"
+)
+
+func isValidMovementReason(reason string) bool {
+	switch reason {
+	case MovementReasonRestock, MovementReasonSale, MovementReasonAdjustment, MovementReasonReturn, MovementReasonTransfer:
+		return true
+	}
+	return false
+}
+
+// InventoryMovement is an append-only ledger entry for a change to the
+// quantity on hand of a product in a shop.
+type InventoryMovement struct {
+	ID            string `bson:"_id,omitempty"`
+	ShopID        string `bson:"shop_id"`
+	ProductID     string `bson:"product_id"`
+	VariantID     string `bson:"variant_id"`
+	Delta         int    `bson:"delta"`
+	QuantityAfter int    `bson:"quantity_after"`
+	Reason        string `bson:"reason"`
+	Actor         string `bson:"actor"`
+	Reference     string `bson:"reference"`
+	CreatedOn     int64  `bson:"createdOn"`
+}
+
+func (s InventoryMovement) Id() string {
+	return s.ID
+}
+
+// StockTransfer records both sides of stock moved between two shops.
+type StockTransfer struct {
+	ID                string `bson:"_id,omitempty"`
+	FromShopID        string `bson:"from_shop_id"`
+	ToShopID          string `bson:"to_shop_id"`
+	ProductID         string `bson:"product_id"`
+	VariantID         string `bson:"variant_id"`
+	Quantity          int    `bson:"quantity"`
+	FromQuantityAfter int    `bson:"from_quantity_after"`
+	ToQuantityAfter   int    `bson:"to_quantity_after"`
+	Actor             string `bson:"actor"`
+	CreatedOn         int64  `bson:"createdOn"`
+}
+
+func (s StockTransfer) Id() string {
+	return s.ID
+}
+
+const (
+	PromotionTypePercentage = "PERCENTAGE"
+	PromotionTypeFixed      = "FIXED"
+	PromotionTypeBuyXGetY   = "BUY_X_GET_Y"
+)
+
+// Promotion is a discount applied to the orders it is eligible for, either
+// automatically or, when it has a code, to those redeeming the code as a
+// coupon. Zero limits and times mean unlimited.
+type Promotion struct {
+	ID           string   `bson:"_id,omitempty"`
+	Name         string   `bson:"name"`
+	Code         string   `bson:"code,omitempty"`
+	Type         string   `bson:"type"`
+	PercentOff   int      `bson:"percent_off"`
+	AmountOff    Money    `bson:"amount_off"`
+	BuyQuantity  int      `bson:"buy_quantity"`
+	GetQuantity  int      `bson:"get_quantity"`
+	ShopIDs      []string `bson:"shop_ids"`
+	CategoryIDs  []string `bson:"category_ids"`
+	StartsAt     int64    `bson:"starts_at"`
+	EndsAt       int64    `bson:"ends_at"`
+	UsageLimit   int      `bson:"usage_limit"`
+	PerUserLimit int      `bson:"per_user_limit"`
+	UsedCount    int      `bson:"used_count"`
+	CreatedOn    int64    `bson:"createdOn"`
+}
+
+func (s Promotion) Id() string {
+	return s.ID
+}
+
+type OrderDiscount struct {
+	PromotionID string `bson:"promotion_id"`
+	Name        string `bson:"name"`
+	Code        string `bson:"code,omitempty"`
+	Amount      Money  `bson:"amount"`
+}
+
+const (
+	TaxModeExclusive = "EXCLUSIVE"
+	TaxModeInclusive = "INCLUSIVE"
+)
+
+// TaxRule taxes the products of the given categories, or all of them, sold by
+// shops within an area.
+type TaxRule struct {
+	ID              string     `bson:"_id,omitempty"`
+	Name            string     `bson:"name"`
+	Region          string     `bson:"region"`
+	Area            GeoPolygon `bson:"area"`
+	CategoryIDs     []string   `bson:"category_ids"`
+	RateBasisPoints int        `bson:"rate_basis_points"`
+	Mode            string     `bson:"mode"`
+	UpdatedOn       int64      `bson:"updatedOn"`
+}
+
+func (s TaxRule) Id() string {
+	return s.ID
+}
+
+type LineTax struct {
+	TaxRuleID       string `bson:"tax_rule_id"`
+	Name            string `bson:"name"`
+	RateBasisPoints int    `bson:"rate_basis_points"`
+	Mode            string `bson:"mode"`
+	Amount          Money  `bson:"amount"`
+}
+
+const (
+	PriceChangeReasonCreated       = "CREATED"
+	PriceChangeReasonManual        = "MANUAL"
+	PriceChangeReasonScheduled     = "SCHEDULED"
+	PriceChangeReasonScheduleEnded = "SCHEDULE_ENDED"
+)
+
+// PriceChange records a change of the catalog price of a product or variant,
+// or of the price a shop sells it at when ShopID is set.
+type PriceChange struct {
+	ID         string `bson:"_id,omitempty"`
+	ProductID  string `bson:"product_id"`
+	VariantID  string `bson:"variant_id"`
+	ShopID     string `bson:"shop_id"`
+	OldPrice   *Money `bson:"old_price,omitempty"`
+	NewPrice   *Money `bson:"new_price,omitempty"`
+	Reason     string `bson:"reason"`
+	ScheduleID string `bson:"schedule_id,omitempty"`
+	ChangedOn  int64  `bson:"changedOn"`
+}
+
+func (s PriceChange) Id() string {
+	return s.ID
+}
+
+const (
+	ScheduleStatusPending   = "PENDING"
+	ScheduleStatusActive    = "ACTIVE"
+	ScheduleStatusCompleted = "COMPLETED"
+)
+
+// ScheduledPriceChange sets a price from StartsAt on, restoring PreviousPrice
+// at EndsAt unless the change is permanent.
+type ScheduledPriceChange struct {
+	ID            string `bson:"_id,omitempty"`
+	ProductID     string `bson:"product_id"`
+	VariantID     string `bson:"variant_id"`
+	ShopID        string `bson:"shop_id"`
+	Price         Money  `bson:"price"`
+	PreviousPrice *Money `bson:"previous_price,omitempty"`
+	StartsAt      int64  `bson:"starts_at"`
+	EndsAt        int64  `bson:"ends_at"`
+	Status        string `bson:"status"`
+	CreatedOn     int64  `bson:"createdOn"`
+}
+
+func (s ScheduledPriceChange) Id() string {
+	return s.ID
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/NikhilSharmaWe/marketplace/proto"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	errCurrencyMismatch = errors.New("amounts are in different currencies")
+
+	currencyCodePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// currencyExponents lists the currencies whose minor unit is not a hundredth.
+var currencyExponents = map[string]int{
+	"BHD": 3, "BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "IQD": 3, "ISK": 0, "JOD": 3,
+	"JPY": 0, "KMF": 0, "KRW": 0, "KWD": 3, "LYD": 3, "OMR": 3, "PYG": 0, "RWF": 0,
+	"TND": 3, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+}
+
+// defaultCurrency is used for amounts that do not state their currency, such
+// as the ones migrated from plain numbers.
+func defaultCurrency() string {
+	if currency := os.Getenv("DEFAULT-CURRENCY"); currency != "" {
+		return currency
+	}
+	return "USD"
+}
+
+// currencyExponent returns the number of decimal places of a currency.
+func currencyExponent(currencyCode string) int {
+	if exponent, ok := currencyExponents[currencyCode]; ok {
+		return exponent
+	}
+	return 2
+}
+
+// Add returns the sum of two amounts. The zero Money adds to an amount in any
+// currency, so that totals can start from it.
+func (m Money) Add(other Money) (Money, error) {
+	if m.CurrencyCode == "" {
+		return other, nil
+	}
+	if other.CurrencyCode == "" {
+		return m, nil
+	}
+	if m.CurrencyCode != other.CurrencyCode {
+		return Money{}, errCurrencyMismatch
+	}
+
+	return Money{CurrencyCode: m.CurrencyCode, MinorUnits: m.MinorUnits + other.MinorUnits}, nil
+}
+
+// Sub returns m less other, which may be negative.
+func (m Money) Sub(other Money) (Money, error) {
+	return m.Add(Money{CurrencyCode: other.CurrencyCode, MinorUnits: -other.MinorUnits})
+}
+
+// Mul returns the amount times n, e.g. the price of n items.
+func (m Money) Mul(n int) Money {
+	return Money{CurrencyCode: m.CurrencyCode, MinorUnits: m.MinorUnits * int64(n)}
+}
+
+// Percent returns percent hundredths of the amount, rounded down.
+func (m Money) Percent(percent int) Money {
+	return Money{CurrencyCode: m.CurrencyCode, MinorUnits: m.MinorUnits * int64(percent) / 100}
+}
+
+// Scale returns the amount times num / den, rounded to the nearest minor unit.
+// den must be positive.
+func (m Money) Scale(num, den int64) Money {
+	scaled := m.MinorUnits * num
+	if scaled < 0 {
+		return Money{CurrencyCode: m.CurrencyCode, MinorUnits: -((-scaled + den/2) / den)}
+	}
+	return Money{CurrencyCode: m.CurrencyCode, MinorUnits: (scaled + den/2) / den}
+}
+
+// Cmp compares two amounts, returning -1, 0 or 1 like strings.Compare.
+func (m Money) Cmp(other Money) (int, error) {
+	diff, err := m.Sub(other)
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case diff.MinorUnits < 0:
+		return -1, nil
+	case diff.MinorUnits > 0:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// String formats the amount in major units, e.g. "19.99 USD".
+func (m Money) String() string {
+	exponent := currencyExponent(m.CurrencyCode)
+	units := m.MinorUnits
+	sign := ""
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+
+	if exponent == 0 {
+		return fmt.Sprintf("%s%d %s", sign, units, m.CurrencyCode)
+	}
+
+	scale := int64(1)
+	for i := 0; i < exponent; i++ {
+		scale *= 10
+	}
+	return fmt.Sprintf("%s%d.%0*d %s", sign, units/scale, exponent, units%scale, m.CurrencyCode)
+}
+
+// sumMoney adds up amounts that must all be in the same currency.
+func sumMoney(amounts ...Money) (Money, error) {
+	var total Money
+	for _, amount := range amounts {
+		var err error
+		total, err = total.Add(amount)
+		if err != nil {
+			return Money{}, err
+		}
+	}
+	return total, nil
+}
+
+// newMoney validates an amount, which is in the default currency when it does
+// not state one.
+func newMoney(pmoney *proto.Money) (Money, error) {
+	if pmoney == nil {
+		return Money{}, errors.New("amount is required")
+	}
+
+	currencyCode := strings.ToUpper(strings.TrimSpace(pmoney.CurrencyCode))
+	if currencyCode == "" {
+		currencyCode = defaultCurrency()
+	}
+
+	if !currencyCodePattern.MatchString(currencyCode) {
+		return Money{}, fmt.Errorf("invalid currency code %q", pmoney.CurrencyCode)
+	}
+
+	if pmoney.MinorUnits < 0 {
+		return Money{}, errors.New("amount cannot be negative")
+	}
+
+	return Money{CurrencyCode: currencyCode, MinorUnits: pmoney.MinorUnits}, nil
+}
+
+// optionalMoney is newMoney for amounts that may be left out, which are zero
+// in no particular currency then.
+func optionalMoney(pmoney *proto.Money) (Money, error) {
+	if pmoney == nil {
+		return Money{}, nil
+	}
+	return newMoney(pmoney)
+}
+
+func parseMoney(money Money) *proto.Money {
+	return &proto.Money{
+		CurrencyCode: money.CurrencyCode,
+		MinorUnits:   money.MinorUnits,
+	}
+}
+
+// parseAmount reads an amount written in major units, e.g. "19.99", without
+// going through floating point.
+func parseAmount(amount, currencyCode string) (*proto.Money, error) {
+	if currencyCode == "" {
+		currencyCode = defaultCurrency()
+	}
+	currencyCode = strings.ToUpper(currencyCode)
+	exponent := currencyExponent(currencyCode)
+
+	whole, fraction, _ := strings.Cut(strings.TrimSpace(amount), ".")
+	if whole == "" && fraction == "" {
+		return nil, fmt.Errorf("invalid amount %q", amount)
+	}
+	if len(fraction) > exponent {
+		return nil, fmt.Errorf("%s has at most %d decimal places", currencyCode, exponent)
+	}
+	fraction += strings.Repeat("0", exponent-len(fraction))
+
+	units, err := strconv.ParseUint(whole+fraction, 10, 63)
+	if err != nil {
+		return nil, fmt.Errorf("invalid amount %q", amount)
+	}
+
+	return &proto.Money{CurrencyCode: currencyCode, MinorUnits: int64(units)}, nil
+}
+
+// legacyAmountTypes are the bson types amounts were stored as before prices
+// had a currency. Strings are converted when they hold a number.
+var legacyAmountTypes = bson.A{"double", "int", "long", "decimal", "string"}
+
+// migrateMoney converts the amounts stored as plain numbers before prices had
+// a currency into Money in the default currency. Amounts that are not numbers
+// are left as they are and logged.
+func (app *application) migrateMoney() error {
+	currencyCode := defaultCurrency()
+	scale := 1
+	for i := 0; i < currencyExponent(currencyCode); i++ {
+		scale *= 10
+	}
+
+	// decimals keep amounts such as "19.99" exact until they are rounded
+	toDecimal := func(expr string) bson.M {
+		return bson.M{"$convert": bson.M{"input": expr, "to": "decimal", "onError": nil, "onNull": nil}}
+	}
+	isLegacy := func(expr string) bson.M {
+		return bson.M{"$in": bson.A{bson.M{"$type": expr}, legacyAmountTypes}}
+	}
+	toMoney := func(expr string) bson.M {
+		return bson.M{"$cond": bson.A{
+			bson.M{"$and": bson.A{isLegacy(expr), bson.M{"$ne": bson.A{toDecimal(expr), nil}}}},
+			bson.M{
+				"currency_code": currencyCode,
+				"minor_units":   bson.M{"$toLong": bson.M{"$round": bson.A{bson.M{"$multiply": bson.A{toDecimal(expr), scale}}, 0}}},
+			},
+			expr,
+		}}
+	}
+	legacy := bson.M{"$type": legacyAmountTypes}
+	// eachToMoney converts the given fields of every element of an array
+	eachToMoney := func(array string, fields ...string) bson.M {
+		converted := bson.M{}
+		for _, field := range fields {
+			converted[field] = toMoney("$$item." + field)
+		}
+		return bson.M{"$map": bson.M{
+			"input": array,
+			"as":    "item",
+			"in":    bson.M{"$mergeObjects": bson.A{"$$item", converted}},
+		}}
+	}
+
+	migrations := []struct {
+		collection *mongo.Collection
+		filter     bson.M
+		set        bson.M
+	}{
+		{
+			getCollection(&app.productRepo.AbstractRepository),
+			bson.M{"price": legacy},
+			bson.M{"price": toMoney("$price")},
+		},
+		{
+			getCollection(&app.productRepo.AbstractRepository),
+			bson.M{"variants.price": legacy},
+			bson.M{"variants": eachToMoney("$variants", "price")},
+		},
+		{
+			getCollection(&app.serviceableRepo.AbstractRepository),
+			bson.M{"price": legacy},
+			bson.M{"price": toMoney("$price")},
+		},
+		{
+			getCollection(&app.serviceableRepo.AbstractRepository),
+			bson.M{"$expr": bson.M{"$anyElementTrue": bson.A{bson.M{"$map": bson.M{
+				"input": bson.M{"$objectToArray": bson.M{"$ifNull": bson.A{"$variant_prices", bson.M{}}}},
+				"as":    "item",
+				"in":    isLegacy("$$item.v"),
+			}}}}},
+			bson.M{"variant_prices": bson.M{"$arrayToObject": bson.M{"$map": bson.M{
+				"input": bson.M{"$objectToArray": "$variant_prices"},
+				"as":    "item",
+				"in":    bson.M{"k": "$$item.k", "v": toMoney("$$item.v")},
+			}}}},
+		},
+		{
+			getCollection(&app.orderRepo.AbstractRepository),
+			bson.M{"total": legacy},
+			bson.M{
+				"total":        toMoney("$total"),
+				"delivery_fee": toMoney("$delivery_fee"),
+				"items":        eachToMoney("$items", "price"),
+			},
+		},
+		{
+			getCollection(&app.shopRepo.AbstractRepository),
+			bson.M{"delivery_zones.delivery_fee": legacy},
+			bson.M{"delivery_zones": eachToMoney("$delivery_zones", "delivery_fee", "min_order_value")},
+		},
+	}
+
+	for _, migration := range migrations {
+		_, err := migration.collection.UpdateMany(app.ctx, migration.filter,
+			mongo.Pipeline{{{Key: "$set", Value: migration.set}}},
+		)
+		if err != nil {
+			return err
+		}
+
+		err = app.logUnmigratedAmounts(migration.collection, migration.filter)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// logUnmigratedAmounts logs the documents still matching the filter of a
+// migration once it ran, whose amounts are not numbers.
+func (app *application) logUnmigratedAmounts(collection *mongo.Collection, filter bson.M) error {
+	cursor, err := collection.Find(app.ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return err
+	}
+
+	var docs []struct {
+		ID string `bson:"_id"`
+	}
+	err = cursor.All(app.ctx, &docs)
+	if err != nil {
+		return err
+	}
+
+	for _, doc := range docs {
+		app.logger.Printf("could not migrate the amounts of %s[%s], they are not numbers", collection.Name(), doc.ID)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"testing"
+)
+
+func usd(minorUnits int64) Money {
+	return Money{CurrencyCode: "USD", MinorUnits: minorUnits}
+}
+
+func TestMoneyAdd(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    Money
+		want    Money
+		wantErr error
+	}{
+		{"same currency", usd(1999), usd(1), usd(2000), nil},
+		{"zero money on the left", Money{}, usd(250), usd(250), nil},
+		{"zero money on the right", usd(250), Money{}, usd(250), nil},
+		{"negative result", usd(100), usd(-250), usd(-150), nil},
+		{"different currencies", usd(100), Money{CurrencyCode: "EUR", MinorUnits: 100}, Money{}, errCurrencyMismatch},
+	}
+
+	for _, test := range tests {
+		got, err := test.a.Add(test.b)
+		if err != test.wantErr || got != test.want {
+			t.Errorf("%s: %v.Add(%v) = %v, %v, want %v, %v", test.name, test.a, test.b, got, err, test.want, test.wantErr)
+		}
+	}
+}
+
+func TestMoneySubAndCmp(t *testing.T) {
+	tests := []struct {
+		a, b    Money
+		wantSub Money
+		wantCmp int
+	}{
+		{usd(500), usd(200), usd(300), 1},
+		{usd(200), usd(500), usd(-300), -1},
+		{usd(200), usd(200), usd(0), 0},
+		{Money{}, usd(200), usd(-200), -1},
+	}
+
+	for _, test := range tests {
+		sub, err := test.a.Sub(test.b)
+		if err != nil || sub != test.wantSub {
+			t.Errorf("%v.Sub(%v) = %v, %v, want %v", test.a, test.b, sub, err, test.wantSub)
+		}
+
+		cmp, err := test.a.Cmp(test.b)
+		if err != nil || cmp != test.wantCmp {
+			t.Errorf("%v.Cmp(%v) = %d, %v, want %d", test.a, test.b, cmp, err, test.wantCmp)
+		}
+	}
+
+	if _, err := usd(1).Cmp(Money{CurrencyCode: "EUR", MinorUnits: 1}); err != errCurrencyMismatch {
+		t.Errorf("comparing different currencies returned %v, want %v", err, errCurrencyMismatch)
+	}
+}
+
+func TestMoneyMulPercentScale(t *testing.T) {
+	if got := usd(1999).Mul(3); got != usd(5997) {
+		t.Errorf("Mul(3) = %v, want 59.97 USD", got)
+	}
+
+	percents := []struct {
+		amount  Money
+		percent int
+		want    Money
+	}{
+		{usd(1000), 10, usd(100)},
+		// rounded down
+		{usd(1999), 10, usd(199)},
+		{usd(1999), 15, usd(299)},
+		{usd(1999), 100, usd(1999)},
+		{usd(1999), 0, usd(0)},
+	}
+	for _, test := range percents {
+		if got := test.amount.Percent(test.percent); got != test.want {
+			t.Errorf("%v.Percent(%d) = %v, want %v", test.amount, test.percent, got, test.want)
+		}
+	}
+
+	scales := []struct {
+		amount   Money
+		num, den int64
+		want     Money
+	}{
+		{usd(1000), 1, 3, usd(333)},
+		{usd(1000), 2, 3, usd(667)},
+		// halves round away from zero
+		{usd(5), 1, 2, usd(3)},
+		{usd(-5), 1, 2, usd(-3)},
+		{usd(-1000), 2, 3, usd(-667)},
+		// basis points, as taxes use them
+		{usd(1999), 1800, 10000, usd(360)},
+	}
+	for _, test := range scales {
+		if got := test.amount.Scale(test.num, test.den); got != test.want {
+			t.Errorf("%v.Scale(%d, %d) = %v, want %v", test.amount, test.num, test.den, got, test.want)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{usd(1999), "19.99 USD"},
+		{usd(5), "0.05 USD"},
+		{usd(-1999), "-19.99 USD"},
+		{usd(-5), "-0.05 USD"},
+		{Money{CurrencyCode: "JPY", MinorUnits: 500}, "500 JPY"},
+		{Money{CurrencyCode: "KWD", MinorUnits: 1005}, "1.005 KWD"},
+	}
+
+	for _, test := range tests {
+		if got := test.money.String(); got != test.want {
+			t.Errorf("String() = %q, want %q", got, test.want)
+		}
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	t.Setenv("DEFAULT-CURRENCY", "")
+
+	tests := []struct {
+		amount, currency string
+		want             Money
+		wantErr          bool
+	}{
+		{"19.99", "USD", usd(1999), false},
+		{"19.9", "USD", usd(1990), false},
+		{"19", "USD", usd(1900), false},
+		{" 19.99 ", "usd", usd(1999), false},
+		{".5", "USD", usd(50), false},
+		{"0.1", "", usd(10), false},
+		{"500", "JPY", Money{CurrencyCode: "JPY", MinorUnits: 500}, false},
+		{"1.005", "KWD", Money{CurrencyCode: "KWD", MinorUnits: 1005}, false},
+		{"19.999", "USD", Money{}, true},
+		{"500.5", "JPY", Money{}, true},
+		{"-1", "USD", Money{}, true},
+		{"1e3", "USD", Money{}, true},
+		{"abc", "USD", Money{}, true},
+		{".", "USD", Money{}, true},
+		{"", "USD", Money{}, true},
+	}
+
+	for _, test := range tests {
+		pmoney, err := parseAmount(test.amount, test.currency)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("parseAmount(%q, %q) = %v, want an error", test.amount, test.currency, pmoney)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("parseAmount(%q, %q): %v", test.amount, test.currency, err)
+			continue
+		}
+		if got := (Money{CurrencyCode: pmoney.CurrencyCode, MinorUnits: pmoney.MinorUnits}); got != test.want {
+			t.Errorf("parseAmount(%q, %q) = %v, want %v", test.amount, test.currency, got, test.want)
+		}
+	}
+}
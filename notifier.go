@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+// LowStockAlert is raised when a change takes the quantity of a product in a
+// shop below its reorder threshold.
+type LowStockAlert struct {
+	ShopID           string `json:"shopId"`
+	ProductID        string `json:"productId"`
+	VariantID        string `json:"variantId,omitempty"`
+	Quantity         int    `json:"quantity"`
+	ReorderThreshold int    `json:"reorderThreshold"`
+	RaisedOn         int64  `json:"raisedOn"`
+}
+
+// StockNotifier delivers low stock alerts to merchants.
+type StockNotifier interface {
+	NotifyLowStock(alert LowStockAlert) error
+}
+
+// newStockNotifier posts alerts to the LOW-STOCK-WEBHOOK url when it is set
+// and only logs them otherwise.
+func newStockNotifier(logger *log.Logger) StockNotifier {
+	if url := os.Getenv("LOW-STOCK-WEBHOOK"); url != "" {
+		return &webhookNotifier{
+			url:    url,
+			client: &http.Client{Timeout: 10 * time.Second},
+		}
+	}
+
+	return &logNotifier{logger: logger}
+}
+
+type logNotifier struct {
+	logger *log.Logger
+}
+
+func (n *logNotifier) NotifyLowStock(alert LowStockAlert) error {
+	n.logger.Printf("low stock of product[%s] in shop[%s]: %d left, reorder threshold is %d",
+		alert.ProductID, alert.ShopID, alert.Quantity, alert.ReorderThreshold)
+	return nil
+}
+
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func (n *webhookNotifier) NotifyLowStock(alert LowStockAlert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("low stock webhook responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// crossedReorderThreshold reports whether applying delta took inventory from
+// at or above its reorder threshold to below it. inventory must be the state
+// after the change.
+func crossedReorderThreshold(inventory *Inventory, delta int) bool {
+	if inventory == nil || inventory.ReorderThreshold <= 0 || delta >= 0 {
+		return false
+	}
+
+	before := inventory.Quantity - delta
+	return inventory.Quantity < inventory.ReorderThreshold && before >= inventory.ReorderThreshold
+}
+
+// raiseLowStockAlerts notifies about the given inventories in the background
+// so that slow notifiers do not hold up the request that changed the stock.
+func (app *application) raiseLowStockAlerts(inventories ...*Inventory) {
+	for _, inventory := range inventories {
+		alert := LowStockAlert{
+			ShopID:           inventory.ShopID,
+			ProductID:        inventory.ProductID,
+			VariantID:        inventory.VariantID,
+			Quantity:         inventory.Quantity,
+			ReorderThreshold: inventory.ReorderThreshold,
+			RaisedOn:         time.Now().Unix(),
+		}
+
+		go func() {
+			if err := app.stockNotifier.NotifyLowStock(alert); err != nil {
+				app.logger.Printf("Error: failed to deliver low stock alert for product[%s] in shop[%s]: %v", alert.ProductID, alert.ShopID, err)
+			}
+		}()
+	}
+}
@@ -0,0 +1,524 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NikhilSharmaWe/marketplace/proto"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	clockLayout = "15:04"
+	dateLayout  = "2006-01-02"
+
+	// how far ahead the next opening of a shop is looked for
+	maxOpeningSearchDays = 366
+)
+
+// weekdays are indexed by time.Weekday.
+var weekdays = [...]string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+
+func weekdayIndex(day string) int {
+	for i, weekday := range weekdays {
+		if weekday == day {
+			return i
+		}
+	}
+	return -1
+}
+
+// defaultTimeZone is used for operating hours that do not state their time
+// zone, such as the ones migrated from free-form text.
+func defaultTimeZone() string {
+	if zone := os.Getenv("DEFAULT-TIME-ZONE"); zone != "" {
+		return zone
+	}
+	return "UTC"
+}
+
+// parseClock returns the minutes since midnight of a "15:04" time, allowing
+// "24:00" for shifts that close at midnight.
+func parseClock(clock string) (int, error) {
+	if clock == "24:00" {
+		return 24 * 60, nil
+	}
+
+	t, err := time.Parse(clockLayout, clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", clock)
+	}
+
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func formatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+func validateShifts(shifts []Shift) error {
+	for _, shift := range shifts {
+		open, err := parseClock(shift.Open)
+		if err != nil {
+			return err
+		}
+
+		close, err := parseClock(shift.Close)
+		if err != nil {
+			return err
+		}
+
+		if open == 24*60 {
+			return errors.New("shifts cannot open at 24:00")
+		}
+
+		if open == close {
+			return fmt.Errorf("shift %s-%s has no length, use 00:00-24:00 to stay open all day", shift.Open, shift.Close)
+		}
+	}
+
+	return nil
+}
+
+// newOperatingHours validates operating hours received over the api.
+func newOperatingHours(phours *proto.OperatingHours) (*OperatingHours, error) {
+	hours := &OperatingHours{
+		TimeZone: phours.TimeZone,
+	}
+	if hours.TimeZone == "" {
+		hours.TimeZone = defaultTimeZone()
+	}
+
+	if _, err := time.LoadLocation(hours.TimeZone); err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", hours.TimeZone)
+	}
+
+	seen := make(map[string]bool)
+	for _, pday := range phours.Weekly {
+		day := strings.ToLower(pday.Day)
+		if weekdayIndex(day) < 0 {
+			return nil, fmt.Errorf("unknown day %q", pday.Day)
+		}
+
+		if seen[day] {
+			return nil, fmt.Errorf("%s is scheduled more than once", day)
+		}
+		seen[day] = true
+
+		shifts := parseProtoShifts(pday.Shifts)
+		if err := validateShifts(shifts); err != nil {
+			return nil, fmt.Errorf("%s: %w", day, err)
+		}
+
+		if len(shifts) > 0 {
+			hours.Weekly = append(hours.Weekly, DayHours{Day: day, Shifts: shifts})
+		}
+	}
+
+	sort.Slice(hours.Weekly, func(i, j int) bool {
+		return weekdayIndex(hours.Weekly[i].Day) < weekdayIndex(hours.Weekly[j].Day)
+	})
+
+	seen = make(map[string]bool)
+	for _, poverride := range phours.Overrides {
+		if _, err := time.Parse(dateLayout, poverride.Date); err != nil {
+			return nil, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", poverride.Date)
+		}
+
+		if seen[poverride.Date] {
+			return nil, fmt.Errorf("%s is overridden more than once", poverride.Date)
+		}
+		seen[poverride.Date] = true
+
+		shifts := parseProtoShifts(poverride.Shifts)
+		if err := validateShifts(shifts); err != nil {
+			return nil, fmt.Errorf("%s: %w", poverride.Date, err)
+		}
+
+		hours.Overrides = append(hours.Overrides, HoursOverride{
+			Date:   poverride.Date,
+			Shifts: shifts,
+			Note:   poverride.Note,
+		})
+	}
+
+	return hours, nil
+}
+
+func parseProtoShifts(pshifts []*proto.Shift) []Shift {
+	var shifts []Shift
+	for _, pshift := range pshifts {
+		shifts = append(shifts, Shift{Open: pshift.Open, Close: pshift.Close})
+	}
+	return shifts
+}
+
+func parseShifts(shifts []Shift) []*proto.Shift {
+	var pshifts []*proto.Shift
+	for _, shift := range shifts {
+		pshifts = append(pshifts, &proto.Shift{Open: shift.Open, Close: shift.Close})
+	}
+	return pshifts
+}
+
+func parseOperatingHours(hours *OperatingHours) *proto.OperatingHours {
+	if hours == nil {
+		return nil
+	}
+
+	phours := &proto.OperatingHours{
+		TimeZone: hours.TimeZone,
+	}
+
+	for _, day := range hours.Weekly {
+		phours.Weekly = append(phours.Weekly, &proto.DayHours{
+			Day:    day.Day,
+			Shifts: parseShifts(day.Shifts),
+		})
+	}
+
+	for _, override := range hours.Overrides {
+		phours.Overrides = append(phours.Overrides, &proto.HoursOverride{
+			Date:   override.Date,
+			Shifts: parseShifts(override.Shifts),
+			Note:   override.Note,
+		})
+	}
+
+	return phours
+}
+
+func (h *OperatingHours) location() *time.Location {
+	loc, err := time.LoadLocation(h.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// shiftsOn returns the shifts starting on the date of day, in the time zone of
+// the shop.
+func (h *OperatingHours) shiftsOn(day time.Time) []Shift {
+	date := day.Format(dateLayout)
+	for _, override := range h.Overrides {
+		if override.Date == date {
+			return override.Shifts
+		}
+	}
+
+	weekday := weekdays[day.Weekday()]
+	for _, hours := range h.Weekly {
+		if hours.Day == weekday {
+			return hours.Shifts
+		}
+	}
+
+	return nil
+}
+
+// intervalsOn returns when the shop opens and closes for each of the shifts
+// starting on the date of day.
+func (h *OperatingHours) intervalsOn(day time.Time) [][2]time.Time {
+	year, month, date := day.Date()
+
+	var intervals [][2]time.Time
+	for _, shift := range h.shiftsOn(day) {
+		open, _ := parseClock(shift.Open)
+		close, _ := parseClock(shift.Close)
+
+		closeDate := date
+		if close <= open {
+			closeDate++
+		}
+
+		intervals = append(intervals, [2]time.Time{
+			time.Date(year, month, date, open/60, open%60, 0, 0, day.Location()),
+			time.Date(year, month, closeDate, close/60, close%60, 0, 0, day.Location()),
+		})
+	}
+
+	return intervals
+}
+
+// IsOpen reports whether the shop is open at t.
+func (h *OperatingHours) IsOpen(t time.Time) bool {
+	local := t.In(h.location())
+
+	// shifts of the previous day may run past midnight
+	for _, day := range []time.Time{local.AddDate(0, 0, -1), local} {
+		for _, interval := range h.intervalsOn(day) {
+			if !t.Before(interval[0]) && t.Before(interval[1]) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// NextOpening returns when the shop next opens at or after t, which is t itself
+// if it is open then. It reports false if the shop does not open within a year.
+func (h *OperatingHours) NextOpening(t time.Time) (time.Time, bool) {
+	if h.IsOpen(t) {
+		return t, true
+	}
+
+	local := t.In(h.location())
+	for i := 0; i < maxOpeningSearchDays; i++ {
+		var next time.Time
+		for _, interval := range h.intervalsOn(local.AddDate(0, 0, i)) {
+			if interval[0].After(t) && (next.IsZero() || interval[0].Before(next)) {
+				next = interval[0]
+			}
+		}
+
+		if !next.IsZero() {
+			return next, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+var (
+	alwaysOpenPattern = regexp.MustCompile(`^(?i)(open\s+)?(24\s*/\s*7|24\s*x\s*7|24\s*hours?)$`)
+	allDaysPattern    = regexp.MustCompile(`^(?i)(daily|every\s*day|all\s+days)\b`)
+	dayRangePattern   = regexp.MustCompile(`^(?i)(mon|tue|wed|thu|fri|sat|sun)[a-z]*\.?(?:\s*(?:-|–|to)\s*(mon|tue|wed|thu|fri|sat|sun)[a-z]*\.?)?`)
+	timeRangePattern  = regexp.MustCompile(`^(?i)(\d{1,2})(?:[:.](\d{2}))?\s*(am|pm)?\s*(?:-|–|to)\s*(\d{1,2})(?:[:.](\d{2}))?\s*(am|pm)?`)
+	closedPattern     = regexp.MustCompile(`^(?i)closed\b`)
+	separatorPattern  = regexp.MustCompile(`^[\s,;|&:]+`)
+)
+
+// parseHoursText parses the free-form operation hours shops used to have, such
+// as "9am-9pm", "24/7" or "Mon-Fri 09:00-13:00, 14:00-18:00; Sat 10:00-14:00;
+// Sun closed". Times without am/pm are read as 24 hour clock times and must
+// not run past midnight, since "9-5" is more likely to mean 9am to 5pm.
+func parseHoursText(text, timeZone string) (*OperatingHours, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, errors.New("no operation hours")
+	}
+
+	schedule := make(map[int][]Shift)
+	if alwaysOpenPattern.MatchString(text) {
+		for day := range weekdays {
+			schedule[day] = []Shift{{Open: "00:00", Close: "24:00"}}
+		}
+		return newScheduledHours(schedule, timeZone), nil
+	}
+
+	var days []int
+	// whether days are being listed, as opposed to the times of the days
+	// listed before
+	listingDays := false
+	parsedAny := false
+
+	allDays := func() []int {
+		return []int{0, 1, 2, 3, 4, 5, 6}
+	}
+	addDays := func(group []int) {
+		if !listingDays {
+			days = nil
+			listingDays = true
+		}
+		days = append(days, group...)
+	}
+
+	for rest := text; rest != ""; {
+		if m := separatorPattern.FindString(rest); m != "" {
+			rest = rest[len(m):]
+			continue
+		}
+
+		if m := allDaysPattern.FindString(rest); m != "" {
+			addDays(allDays())
+			rest = rest[len(m):]
+			continue
+		}
+
+		if m := dayRangePattern.FindStringSubmatch(rest); m != nil {
+			from := weekdayPrefixIndex(m[1])
+			to := from
+			if m[2] != "" {
+				to = weekdayPrefixIndex(m[2])
+			}
+
+			var group []int
+			for day := from; ; day = (day + 1) % 7 {
+				group = append(group, day)
+				if day == to {
+					break
+				}
+			}
+
+			addDays(group)
+			rest = rest[len(m[0]):]
+			continue
+		}
+
+		if m := closedPattern.FindString(rest); m != "" {
+			if days == nil {
+				return nil, errors.New("closed without any days")
+			}
+
+			for _, day := range days {
+				schedule[day] = nil
+			}
+			listingDays = false
+			parsedAny = true
+			rest = rest[len(m):]
+			continue
+		}
+
+		if m := timeRangePattern.FindStringSubmatch(rest); m != nil {
+			shift, err := parseTextShift(m)
+			if err != nil {
+				return nil, err
+			}
+
+			if days == nil {
+				days = allDays()
+			}
+
+			// the first time listed for the days replaces whatever they were
+			// given by an earlier, more general, part of the text
+			for _, day := range days {
+				if listingDays {
+					schedule[day] = nil
+				}
+				schedule[day] = append(schedule[day], shift)
+			}
+			listingDays = false
+			parsedAny = true
+			rest = rest[len(m[0]):]
+			continue
+		}
+
+		return nil, fmt.Errorf("cannot parse %q", rest)
+	}
+
+	if !parsedAny {
+		return nil, errors.New("no times found")
+	}
+
+	return newScheduledHours(schedule, timeZone), nil
+}
+
+func weekdayPrefixIndex(prefix string) int {
+	prefix = strings.ToLower(prefix)
+	for i, weekday := range weekdays {
+		if strings.HasPrefix(weekday, prefix) {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseTextShift converts the submatches of timeRangePattern into a shift.
+func parseTextShift(m []string) (Shift, error) {
+	open, err := textClock(m[1], m[2], m[3])
+	if err != nil {
+		return Shift{}, err
+	}
+
+	close, err := textClock(m[4], m[5], m[6])
+	if err != nil {
+		return Shift{}, err
+	}
+
+	if close <= open && m[3] == "" && m[6] == "" {
+		return Shift{}, fmt.Errorf("ambiguous time range %q", m[0])
+	}
+
+	if open == close || open == 24*60 {
+		return Shift{}, fmt.Errorf("invalid time range %q", m[0])
+	}
+
+	return Shift{Open: formatClock(open), Close: formatClock(close)}, nil
+}
+
+func textClock(hour, minute, meridiem string) (int, error) {
+	h, _ := strconv.Atoi(hour)
+	m := 0
+	if minute != "" {
+		m, _ = strconv.Atoi(minute)
+	}
+
+	if m > 59 {
+		return 0, fmt.Errorf("invalid minutes in %s:%s", hour, minute)
+	}
+
+	switch strings.ToLower(meridiem) {
+	case "":
+		if h > 24 || (h == 24 && m > 0) {
+			return 0, fmt.Errorf("invalid hour %s", hour)
+		}
+	case "am", "pm":
+		if h < 1 || h > 12 {
+			return 0, fmt.Errorf("invalid hour %s%s", hour, meridiem)
+		}
+		h %= 12
+		if strings.ToLower(meridiem) == "pm" {
+			h += 12
+		}
+	}
+
+	return h*60 + m, nil
+}
+
+func newScheduledHours(schedule map[int][]Shift, timeZone string) *OperatingHours {
+	hours := &OperatingHours{
+		TimeZone: timeZone,
+	}
+
+	for day, name := range weekdays {
+		if len(schedule[day]) > 0 {
+			hours.Weekly = append(hours.Weekly, DayHours{Day: name, Shifts: schedule[day]})
+		}
+	}
+
+	return hours
+}
+
+// migrateOperationHours converts the free-form operation hours of shops into
+// structured operating hours wherever they parse, leaving the others as they
+// are.
+func (app *application) migrateOperationHours() error {
+	filter := bson.M{
+		"operation_hours": bson.M{"$nin": bson.A{nil, ""}},
+		"operating_hours": bson.M{"$exists": false},
+	}
+
+	shops, err := getItemOrError(app.shopRepo.Find(filter, nil, 0, 0))
+	if err != nil {
+		return err
+	}
+
+	collection := getCollection(&app.shopRepo.AbstractRepository)
+	for _, shop := range shops {
+		hours, err := parseHoursText(shop.OperationHours, defaultTimeZone())
+		if err != nil {
+			app.logger.Printf("could not migrate operation hours %q of shop[%s]: %v", shop.OperationHours, shop.ID, err)
+			continue
+		}
+
+		_, err = collection.UpdateOne(app.ctx,
+			bson.M{"_id": shop.ID},
+			bson.M{
+				"$set":   bson.M{"operating_hours": hours},
+				"$unset": bson.M{"operation_hours": ""},
+			},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
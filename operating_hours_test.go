@@ -0,0 +1,207 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// weeklyText describes the weekly hours of a shop as the shifts of each day it
+// opens, e.g. "09:00-13:00,14:00-18:00".
+func weeklyText(hours *OperatingHours) map[string]string {
+	weekly := make(map[string]string)
+	for _, day := range hours.Weekly {
+		var shifts []string
+		for _, shift := range day.Shifts {
+			shifts = append(shifts, shift.Open+"-"+shift.Close)
+		}
+		weekly[day.Day] = strings.Join(shifts, ",")
+	}
+	return weekly
+}
+
+func everyDay(shifts string) map[string]string {
+	weekly := make(map[string]string)
+	for _, day := range weekdays {
+		weekly[day] = shifts
+	}
+	return weekly
+}
+
+func TestParseHoursText(t *testing.T) {
+	tests := []struct {
+		text string
+		want map[string]string
+	}{
+		{"24/7", everyDay("00:00-24:00")},
+		{"Open 24 hours", everyDay("00:00-24:00")},
+		{"9am-9pm", everyDay("09:00-21:00")},
+		{"9:30 am to 5:30 pm", everyDay("09:30-17:30")},
+		{"mon 9-17", map[string]string{"monday": "09:00-17:00"}},
+		{
+			"Mon-Fri 09:00-13:00, 14:00-18:00; Sat 10:00-14:00; Sun closed",
+			map[string]string{
+				"monday":    "09:00-13:00,14:00-18:00",
+				"tuesday":   "09:00-13:00,14:00-18:00",
+				"wednesday": "09:00-13:00,14:00-18:00",
+				"thursday":  "09:00-13:00,14:00-18:00",
+				"friday":    "09:00-13:00,14:00-18:00",
+				"saturday":  "10:00-14:00",
+			},
+		},
+		{
+			"Daily 10:00-22:00, Sun closed",
+			map[string]string{
+				"monday":    "10:00-22:00",
+				"tuesday":   "10:00-22:00",
+				"wednesday": "10:00-22:00",
+				"thursday":  "10:00-22:00",
+				"friday":    "10:00-22:00",
+				"saturday":  "10:00-22:00",
+			},
+		},
+		{
+			// overnight shifts close on the next day
+			"Fri-Sat 6pm-2am",
+			map[string]string{"friday": "18:00-02:00", "saturday": "18:00-02:00"},
+		},
+		{
+			// day ranges wrap around the end of the week
+			"Sat-Mon 10:00-14:00",
+			map[string]string{"saturday": "10:00-14:00", "sunday": "10:00-14:00", "monday": "10:00-14:00"},
+		},
+		{
+			"Mon & Wed 08:00-12:00",
+			map[string]string{"monday": "08:00-12:00", "wednesday": "08:00-12:00"},
+		},
+	}
+
+	for _, test := range tests {
+		hours, err := parseHoursText(test.text, "Asia/Kolkata")
+		if err != nil {
+			t.Errorf("parseHoursText(%q): %v", test.text, err)
+			continue
+		}
+
+		if hours.TimeZone != "Asia/Kolkata" {
+			t.Errorf("parseHoursText(%q) is in time zone %q, want Asia/Kolkata", test.text, hours.TimeZone)
+		}
+		if got := weeklyText(hours); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("parseHoursText(%q) = %v, want %v", test.text, got, test.want)
+		}
+	}
+}
+
+// TestParseHoursTextUnparseable lists operation hours that are left free-form,
+// since guessing their structure could be wrong.
+func TestParseHoursTextUnparseable(t *testing.T) {
+	tests := []string{
+		"",
+		"by appointment",
+		"call us",
+		// 9 to 5 without am/pm cannot run past midnight
+		"9-5",
+		"closed",
+		"Mon-Fri",
+		"10am-10am",
+		"25:00-26:00",
+		"Mon-Fri 09:00-18:00 except holidays",
+	}
+
+	for _, text := range tests {
+		if hours, err := parseHoursText(text, "UTC"); err == nil {
+			t.Errorf("parseHoursText(%q) = %v, want an error", text, weeklyText(hours))
+		}
+	}
+}
+
+func testOperatingHours(t *testing.T) (*OperatingHours, func(string) time.Time) {
+	t.Helper()
+
+	hours := &OperatingHours{
+		TimeZone: "Asia/Kolkata",
+		Weekly: []DayHours{
+			{Day: "monday", Shifts: []Shift{{Open: "09:00", Close: "17:00"}}},
+			{Day: "friday", Shifts: []Shift{{Open: "18:00", Close: "02:00"}}},
+		},
+		Overrides: []HoursOverride{
+			// closed on the first monday of 2024
+			{Date: "2024-01-01", Note: "new year"},
+		},
+	}
+
+	loc, err := time.LoadLocation(hours.TimeZone)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := func(value string) time.Time {
+		t.Helper()
+		parsed, err := time.ParseInLocation("2006-01-02 15:04", value, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	return hours, at
+}
+
+func TestOperatingHoursIsOpen(t *testing.T) {
+	hours, at := testOperatingHours(t)
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{"monday shift", at("2024-01-08 10:00"), true},
+		{"monday opening", at("2024-01-08 09:00"), true},
+		{"monday closing", at("2024-01-08 17:00"), false},
+		{"before friday shift", at("2024-01-05 17:59"), false},
+		{"friday evening", at("2024-01-05 19:00"), true},
+		{"past midnight of friday shift", at("2024-01-06 01:30"), true},
+		{"friday shift closing on saturday", at("2024-01-06 02:00"), false},
+		{"saturday evening", at("2024-01-06 19:00"), false},
+		{"overridden monday", at("2024-01-01 10:00"), false},
+		// 04:00 UTC is 09:30 in Kolkata
+		{"in another time zone", time.Date(2024, 1, 8, 4, 0, 0, 0, time.UTC), true},
+		{"before opening in another time zone", time.Date(2024, 1, 8, 3, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, test := range tests {
+		if got := hours.IsOpen(test.at); got != test.want {
+			t.Errorf("%s: IsOpen(%v) = %v, want %v", test.name, test.at, got, test.want)
+		}
+	}
+}
+
+func TestOperatingHoursNextOpening(t *testing.T) {
+	hours, at := testOperatingHours(t)
+
+	tests := []struct {
+		name string
+		from time.Time
+		want time.Time
+	}{
+		{"open now", at("2024-01-05 19:00"), at("2024-01-05 19:00")},
+		{"later the same day", at("2024-01-05 10:00"), at("2024-01-05 18:00")},
+		{"across the end of the week", at("2024-01-06 03:00"), at("2024-01-08 09:00")},
+		{"from sunday", at("2024-01-07 12:00"), at("2024-01-08 09:00")},
+		{"skipping an overridden day", at("2023-12-31 12:00"), at("2024-01-05 18:00")},
+		{"after closing", at("2024-01-08 17:00"), at("2024-01-12 18:00")},
+	}
+
+	for _, test := range tests {
+		got, ok := hours.NextOpening(test.from)
+		if !ok || !got.Equal(test.want) {
+			t.Errorf("%s: NextOpening(%v) = %v, %v, want %v", test.name, test.from, got, ok, test.want)
+		}
+	}
+
+	never := &OperatingHours{TimeZone: "UTC"}
+	if got, ok := never.NextOpening(at("2024-01-01 00:00")); ok {
+		t.Errorf("NextOpening of a shop that never opens = %v, want none", got)
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+// orderTransitions lists, for every order status, the statuses an order may
+// move to next. Statuses without an entry are terminal.
+var orderTransitions = map[string][]string{
+	OrderStatusPlaced:         {OrderStatusAccepted, OrderStatusRejected, OrderStatusCancelled},
+	OrderStatusAccepted:       {OrderStatusPacked, OrderStatusCancelled},
+	OrderStatusPacked:         {OrderStatusOutForDelivery, OrderStatusCancelled},
+	OrderStatusOutForDelivery: {OrderStatusDelivered},
+}
+
+func isValidOrderStatus(status string) bool {
+	switch status {
+	case OrderStatusPlaced, OrderStatusAccepted, OrderStatusPacked, OrderStatusOutForDelivery,
+		OrderStatusDelivered, OrderStatusCancelled, OrderStatusRejected:
+		return true
+	}
+	return false
+}
+
+func canTransitionOrder(from, to string) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// restoresStock reports whether moving an order into status returns its
+// items to the shop's inventory.
+func restoresStock(status string) bool {
+	return status == OrderStatusCancelled || status == OrderStatusRejected
+}
@@ -0,0 +1,534 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/NikhilSharmaWe/marketplace/proto"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const priceScheduleInterval = time.Minute
+
+// scheduleStartTolerance is how far in the past a price change may be asked
+// to start, for clients that schedule it "now" with a clock running a little
+// ahead of the request.
+const scheduleStartTolerance = time.Minute
+
+// setupPriceIndexes lets the history of a price be read in order and the
+// scheduler find the changes that are due.
+func (app *application) setupPriceIndexes() error {
+	_, err := getCollection(&app.priceChangeRepo.AbstractRepository).Indexes().CreateOne(app.ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "product_id", Value: 1},
+			{Key: "shop_id", Value: 1},
+			{Key: "variant_id", Value: 1},
+			{Key: "changedOn", Value: 1},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = getCollection(&app.scheduleRepo.AbstractRepository).Indexes().CreateMany(app.ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "starts_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "ends_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "product_id", Value: 1}, {Key: "shop_id", Value: 1}, {Key: "variant_id", Value: 1}},
+		},
+	})
+	return err
+}
+
+// setPrice changes the catalog price of a product or one of its variants, or
+// the price of the shop when shopId is set, and records the change. A nil
+// price removes the price of the shop so that it sells at the catalog price
+// again. It returns the price before the change, which is nil when the shop
+// had none.
+func (s *GRPCMarketPlaceServer) setPrice(sessCtx mongo.SessionContext, productId, variantId, shopId string, price *Money, reason, scheduleId string) (*Money, error) {
+	var old *Money
+
+	if shopId == "" {
+		if price == nil {
+			return nil, status.Error(codes.InvalidArgument, "catalog price is required")
+		}
+
+		filter := bson.M{"_id": productId}
+		field := "price"
+		if variantId != "" {
+			filter["variants.id"] = variantId
+			field = "variants.$.price"
+		}
+
+		product := &Product{}
+		err := getCollection(&s.svc.productRepo.AbstractRepository).FindOneAndUpdate(sessCtx,
+			filter, bson.M{"$set": bson.M{field: price}},
+			options.FindOneAndUpdate().SetReturnDocument(options.Before),
+		).Decode(product)
+		if err == mongo.ErrNoDocuments {
+			return nil, status.Errorf(codes.NotFound, "product[%s] does not exists", productId)
+		}
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return nil, errors.New("failed to set price")
+		}
+
+		oldPrice := product.priceOf(variantId)
+		old = &oldPrice
+	} else {
+		field := "price"
+		if variantId != "" {
+			field = "variant_prices." + variantId
+		}
+
+		update := bson.M{"$set": bson.M{"updatedOn": time.Now().Unix()}}
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+		if price != nil {
+			update["$set"].(bson.M)[field] = price
+			update["$setOnInsert"] = bson.M{"_id": primitive.NewObjectID().Hex()}
+			opts.SetUpsert(true)
+		} else {
+			update["$unset"] = bson.M{field: ""}
+		}
+
+		sp := &ServiceableProduct{}
+		err := getCollection(&s.svc.serviceableRepo.AbstractRepository).FindOneAndUpdate(sessCtx,
+			bson.M{"shop_id": shopId, "product_id": productId}, update, opts,
+		).Decode(sp)
+		if err != nil && err != mongo.ErrNoDocuments {
+			s.svc.logger.Println("Error: ", err)
+			return nil, errors.New("failed to set shop price")
+		}
+
+		if err == nil {
+			old = sp.ownPrice(variantId)
+		}
+	}
+
+	err := s.recordPriceChange(sessCtx, PriceChange{
+		ProductID:  productId,
+		VariantID:  variantId,
+		ShopID:     shopId,
+		OldPrice:   old,
+		NewPrice:   price,
+		Reason:     reason,
+		ScheduleID: scheduleId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return old, nil
+}
+
+// ownPrice returns the price the shop has set itself for a variant of the
+// product, or for the product when variantId is empty, nil if it has none.
+func (sp *ServiceableProduct) ownPrice(variantId string) *Money {
+	if variantId == "" {
+		return sp.Price
+	}
+	if price, ok := sp.VariantPrices[variantId]; ok {
+		return &price
+	}
+	return nil
+}
+
+// currentPrice returns the catalog price of a product or variant, or the
+// price the shop has set itself when shopId is set.
+func (s *GRPCMarketPlaceServer) currentPrice(ctx context.Context, productId, variantId, shopId string) (*Money, error) {
+	if shopId == "" {
+		product := &Product{}
+		err := getCollection(&s.svc.productRepo.AbstractRepository).FindOne(ctx, bson.M{"_id": productId}).Decode(product)
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return nil, errors.New("failed to get product")
+		}
+
+		price := product.priceOf(variantId)
+		return &price, nil
+	}
+
+	sp := &ServiceableProduct{}
+	err := getCollection(&s.svc.serviceableRepo.AbstractRepository).FindOne(ctx,
+		bson.M{"shop_id": shopId, "product_id": productId},
+	).Decode(sp)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to get shop price")
+	}
+
+	return sp.ownPrice(variantId), nil
+}
+
+func (s *GRPCMarketPlaceServer) recordPriceChange(ctx context.Context, change PriceChange) error {
+	change.ID = primitive.NewObjectID().Hex()
+	change.ChangedOn = time.Now().Unix()
+
+	_, err := getCollection(&s.svc.priceChangeRepo.AbstractRepository).InsertOne(ctx, change)
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return errors.New("failed to record price change")
+	}
+	return nil
+}
+
+// SchedulePriceChange sets a price for a product or variant, or for a shop
+// selling it, from startsAt on. The price before it is restored at endsAt,
+// unless the price has been changed again meanwhile. Changes of the same
+// price must not overlap. A change without a start, or starting a moment ago,
+// starts now.
+func (s *GRPCMarketPlaceServer) SchedulePriceChange(ctx context.Context, req *proto.SchedulePriceChangeRequest) (*proto.ScheduledPriceChange, error) {
+	price, err := newMoney(req.Price)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "price: %v", err)
+	}
+
+	now := time.Now().Unix()
+	startsAt := req.StartsAt
+	if startsAt != 0 && startsAt < now-int64(scheduleStartTolerance.Seconds()) {
+		return nil, status.Error(codes.InvalidArgument, "start cannot be in the past")
+	}
+	if startsAt < now {
+		startsAt = now
+	}
+	if req.EndsAt != 0 && req.EndsAt <= startsAt {
+		return nil, status.Error(codes.InvalidArgument, "end must be after start")
+	}
+
+	_, variantId, err := s.productVariant(req.ProductId, req.VariantId)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.ShopId == "" {
+		err = requireAdmin(ctx, "change catalog prices")
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		err = s.authorizeShop(ctx, req.ShopId, PermissionManageCatalog)
+		if err != nil {
+			return nil, err
+		}
+
+		shop, err := getItemOrError(s.svc.shopRepo.FindOneById(req.ShopId))
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return nil, status.Error(codes.NotFound, "shop does not exists")
+		}
+
+		var serviceable bool
+		for _, id := range shop.ServiceableProductsId {
+			if id == req.ProductId {
+				serviceable = true
+			}
+		}
+
+		if !serviceable {
+			return nil, status.Errorf(codes.FailedPrecondition, "product[%s] is not serviceable by shop[%s]", req.ProductId, req.ShopId)
+		}
+	}
+
+	schedule := ScheduledPriceChange{
+		ID:        primitive.NewObjectID().Hex(),
+		ProductID: req.ProductId,
+		VariantID: variantId,
+		ShopID:    req.ShopId,
+		Price:     price,
+		StartsAt:  startsAt,
+		EndsAt:    req.EndsAt,
+		Status:    ScheduleStatusPending,
+		CreatedOn: now,
+	}
+
+	err = s.svc.withTransaction(ctx, func(sessCtx mongo.SessionContext) error {
+		// transactions only conflict when they write the same document, so
+		// scheduling a change writes its product too. Of two requests
+		// scheduling at once, the one committing last is then retried and
+		// finds the schedule of the other. The version is not read.
+		_, err := getCollection(&s.svc.productRepo.AbstractRepository).UpdateOne(sessCtx,
+			bson.M{"_id": schedule.ProductID},
+			bson.M{"$inc": bson.M{"schedule_version": 1}},
+		)
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return errors.New("failed to schedule price change")
+		}
+
+		collection := getCollection(&s.svc.scheduleRepo.AbstractRepository)
+		count, err := collection.CountDocuments(sessCtx, overlappingSchedules(&schedule))
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return errors.New("failed to get scheduled price changes")
+		}
+
+		if count > 0 {
+			return status.Error(codes.AlreadyExists, "another price change is scheduled at that time")
+		}
+
+		_, err = collection.InsertOne(sessCtx, schedule)
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return errors.New("failed to schedule price change")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return parseScheduledPriceChange(&schedule), nil
+}
+
+// overlappingSchedules matches the pending and active changes of the same
+// price as schedule that overlap it. A permanent change takes no time, so it
+// only overlaps the changes running when it starts.
+func overlappingSchedules(schedule *ScheduledPriceChange) bson.M {
+	filter := bson.M{
+		"product_id": schedule.ProductID,
+		"variant_id": schedule.VariantID,
+		"shop_id":    schedule.ShopID,
+		"status":     bson.M{"$in": bson.A{ScheduleStatusPending, ScheduleStatusActive}},
+	}
+
+	if schedule.EndsAt == 0 {
+		filter["$or"] = bson.A{
+			bson.M{"starts_at": bson.M{"$lte": schedule.StartsAt}, "ends_at": bson.M{"$gt": schedule.StartsAt}},
+			bson.M{"starts_at": schedule.StartsAt, "ends_at": 0},
+		}
+	} else {
+		filter["$or"] = bson.A{
+			bson.M{"starts_at": bson.M{"$lt": schedule.EndsAt}, "ends_at": bson.M{"$gt": schedule.StartsAt}},
+			bson.M{"starts_at": bson.M{"$gte": schedule.StartsAt, "$lt": schedule.EndsAt}, "ends_at": 0},
+		}
+	}
+
+	return filter
+}
+
+// runPriceSchedules periodically ends the scheduled price changes whose end
+// has passed and then starts the ones that are due, so that a change may
+// start right as the one before it ends.
+func (s *GRPCMarketPlaceServer) runPriceSchedules(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		now := time.Now().Unix()
+
+		ending, err := getItemOrError(s.svc.scheduleRepo.Find(
+			bson.M{"status": ScheduleStatusActive, "ends_at": bson.M{"$gt": 0, "$lte": now}},
+			bson.D{{Key: "ends_at", Value: 1}}, 0, 0,
+		))
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			continue
+		}
+
+		for i := range ending {
+			schedule := &ending[i]
+			err := s.endPriceSchedule(context.Background(), schedule)
+			if err != nil {
+				s.svc.logger.Printf("Error: failed to end scheduled price change[%s]: %v", schedule.ID, err)
+				continue
+			}
+			s.svc.logger.Printf("ended scheduled price change[%s]", schedule.ID)
+		}
+
+		due, err := getItemOrError(s.svc.scheduleRepo.Find(
+			bson.M{"status": ScheduleStatusPending, "starts_at": bson.M{"$lte": now}},
+			bson.D{{Key: "starts_at", Value: 1}}, 0, 0,
+		))
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			continue
+		}
+
+		for i := range due {
+			schedule := &due[i]
+			err := s.startPriceSchedule(context.Background(), schedule)
+			if err != nil {
+				s.svc.logger.Printf("Error: failed to start scheduled price change[%s]: %v", schedule.ID, err)
+				continue
+			}
+			s.svc.logger.Printf("started scheduled price change[%s]", schedule.ID)
+		}
+	}
+}
+
+// startPriceSchedule applies a scheduled price, keeping the price it replaces
+// to restore at the end of the schedule.
+func (s *GRPCMarketPlaceServer) startPriceSchedule(ctx context.Context, schedule *ScheduledPriceChange) error {
+	return s.svc.withTransaction(ctx, func(sessCtx mongo.SessionContext) error {
+		old, err := s.setPrice(sessCtx, schedule.ProductID, schedule.VariantID, schedule.ShopID,
+			&schedule.Price, PriceChangeReasonScheduled, schedule.ID,
+		)
+		if err != nil {
+			return err
+		}
+
+		set := bson.M{"status": ScheduleStatusActive, "previous_price": old}
+		if schedule.EndsAt == 0 {
+			set["status"] = ScheduleStatusCompleted
+		}
+
+		return s.settlePriceSchedule(sessCtx, schedule.ID, ScheduleStatusPending, set)
+	})
+}
+
+// endPriceSchedule restores the price a schedule replaced. A price changed
+// while the schedule ran is left as it is.
+func (s *GRPCMarketPlaceServer) endPriceSchedule(ctx context.Context, schedule *ScheduledPriceChange) error {
+	return s.svc.withTransaction(ctx, func(sessCtx mongo.SessionContext) error {
+		current, err := s.currentPrice(sessCtx, schedule.ProductID, schedule.VariantID, schedule.ShopID)
+		if err != nil {
+			return err
+		}
+
+		if current != nil && *current == schedule.Price {
+			_, err = s.setPrice(sessCtx, schedule.ProductID, schedule.VariantID, schedule.ShopID,
+				schedule.PreviousPrice, PriceChangeReasonScheduleEnded, schedule.ID,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		return s.settlePriceSchedule(sessCtx, schedule.ID, ScheduleStatusActive, bson.M{"status": ScheduleStatusCompleted})
+	})
+}
+
+// settlePriceSchedule moves a schedule on from the status it was read in,
+// failing when another run of the scheduler has already done so.
+func (s *GRPCMarketPlaceServer) settlePriceSchedule(sessCtx mongo.SessionContext, scheduleId, from string, set bson.M) error {
+	res, err := getCollection(&s.svc.scheduleRepo.AbstractRepository).UpdateOne(sessCtx,
+		bson.M{"_id": scheduleId, "status": from}, bson.M{"$set": set},
+	)
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return errors.New("failed to update scheduled price change")
+	}
+
+	if res.MatchedCount == 0 {
+		return errors.New("scheduled price change was already updated")
+	}
+	return nil
+}
+
+// GetPriceHistory lists the changes of the catalog price of a product, or of
+// the price a shop sells it at, oldest first. Like catalog prices, their
+// history is for admins only.
+func (s *GRPCMarketPlaceServer) GetPriceHistory(ctx context.Context, req *proto.GetPriceHistoryRequest) (*proto.PriceHistory, error) {
+	if req.ProductId == "" {
+		return nil, status.Error(codes.InvalidArgument, "product id is required")
+	}
+
+	if req.ShopId == "" {
+		err := requireAdmin(ctx, "read the history of catalog prices")
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		err := s.authorizeShop(ctx, req.ShopId, PermissionManageCatalog)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pageSize := int(req.PageSize)
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		return nil, status.Errorf(codes.InvalidArgument, "page size cannot exceed %d", maxPageSize)
+	}
+
+	offset, err := decodePageToken(req.PageToken)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid page token")
+	}
+
+	filter := bson.M{"product_id": req.ProductId, "shop_id": req.ShopId}
+	if req.VariantId != "" {
+		filter["variant_id"] = req.VariantId
+	}
+
+	changedOn := bson.M{}
+	if req.From != 0 {
+		changedOn["$gte"] = req.From
+	}
+	if req.To != 0 {
+		changedOn["$lte"] = req.To
+	}
+	if len(changedOn) > 0 {
+		filter["changedOn"] = changedOn
+	}
+
+	sort := bson.D{{Key: "changedOn", Value: 1}, {Key: "_id", Value: 1}}
+
+	changes, err := getItemOrError(s.svc.priceChangeRepo.Find(filter, sort, int64(pageSize+1), int64(offset)))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to get price history")
+	}
+
+	result := &proto.PriceHistory{}
+	if len(changes) > pageSize {
+		changes = changes[:pageSize]
+		result.NextPageToken = encodePageToken(offset + pageSize)
+	}
+
+	for i := range changes {
+		result.Changes = append(result.Changes, parsePriceChange(&changes[i]))
+	}
+
+	return result, nil
+}
+
+func parsePriceChange(change *PriceChange) *proto.PriceChange {
+	pchange := &proto.PriceChange{
+		Id:         change.ID,
+		ProductId:  change.ProductID,
+		VariantId:  change.VariantID,
+		ShopId:     change.ShopID,
+		Reason:     change.Reason,
+		ScheduleId: change.ScheduleID,
+		ChangedOn:  change.ChangedOn,
+	}
+
+	if change.OldPrice != nil {
+		pchange.OldPrice = parseMoney(*change.OldPrice)
+	}
+	if change.NewPrice != nil {
+		pchange.NewPrice = parseMoney(*change.NewPrice)
+	}
+
+	return pchange
+}
+
+func parseScheduledPriceChange(schedule *ScheduledPriceChange) *proto.ScheduledPriceChange {
+	return &proto.ScheduledPriceChange{
+		Id:        schedule.ID,
+		ProductId: schedule.ProductID,
+		VariantId: schedule.VariantID,
+		ShopId:    schedule.ShopID,
+		Price:     parseMoney(schedule.Price),
+		StartsAt:  schedule.StartsAt,
+		EndsAt:    schedule.EndsAt,
+		Status:    schedule.Status,
+		CreatedOn: schedule.CreatedOn,
+	}
+}
@@ -0,0 +1,589 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/NikhilSharmaWe/marketplace/proto"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// setupPromotionIndexes keeps coupon codes unique. Promotions without a code
+// are left out of the index.
+func (app *application) setupPromotionIndexes() error {
+	_, err := getCollection(&app.promotionRepo.AbstractRepository).Indexes().CreateOne(app.ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "code", Value: 1}},
+		Options: options.Index().
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"code": bson.M{"$type": "string"}}),
+	})
+	return err
+}
+
+func (s *GRPCMarketPlaceServer) CreatePromotion(ctx context.Context, req *proto.CreatePromotionRequest) (*proto.Promotion, error) {
+	promotion, err := newPromotion(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	promotion.CategoryIDs, err = s.checkCategories(req.CategoryIds)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(promotion.ShopIDs) > 0 {
+		count, err := getItemOrError(s.svc.shopRepo.CountDocuments(bson.M{"_id": bson.M{"$in": promotion.ShopIDs}}))
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return nil, errors.New("failed to get shops")
+		}
+
+		if count != int64(len(promotion.ShopIDs)) {
+			return nil, status.Error(codes.NotFound, "shop does not exists")
+		}
+
+		for _, shopId := range promotion.ShopIDs {
+			err = s.authorizeShop(ctx, shopId, PermissionManageCatalog)
+			if err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		err = requireAdmin(ctx, "create promotions for every shop")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = getErrorFromChan(s.svc.promotionRepo.Save(promotion))
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, status.Errorf(codes.AlreadyExists, "coupon code %s is already used", promotion.Code)
+	}
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to create promotion")
+	}
+
+	return parsePromotion(&promotion), nil
+}
+
+// ValidateCoupon tells whether a user can redeem a coupon and, given a shop,
+// what it takes off the items ordered from it.
+func (s *GRPCMarketPlaceServer) ValidateCoupon(ctx context.Context, req *proto.ValidateCouponRequest) (*proto.CouponValidation, error) {
+	err := authorizeUser(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "user id is required")
+	}
+
+	promotion, err := s.findCoupon(req.Code)
+	if status.Code(err) == codes.NotFound {
+		return &proto.CouponValidation{Reason: "unknown coupon"}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	validation := &proto.CouponValidation{
+		Promotion: parsePromotion(promotion),
+	}
+
+	validation.Reason, err = s.promotionUnavailable(ctx, promotion, req.UserId, req.ShopId, time.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
+	if validation.Reason != "" || req.ShopId == "" {
+		validation.Valid = validation.Reason == ""
+		return validation, nil
+	}
+
+	items := req.Items
+	if len(items) == 0 {
+		cart, err := s.getCart(req.UserId)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range cart.Items {
+			if item.ShopID == req.ShopId {
+				items = append(items, &proto.OrderItemRequest{
+					ProductId: item.ProductID,
+					VariantId: item.VariantID,
+					Quantity:  int32(item.Quantity),
+				})
+			}
+		}
+	}
+
+	if len(items) == 0 {
+		validation.Valid = true
+		return validation, nil
+	}
+
+	shop, err := getItemOrError(s.svc.shopRepo.FindOneById(req.ShopId))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, status.Error(codes.NotFound, "failed to get shop")
+	}
+
+	priced, products, err := s.priceItems(shop, items)
+	if err != nil {
+		return nil, err
+	}
+
+	discounts, err := s.discountsFor([]Promotion{*promotion}, priced, products)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(discounts) == 0 {
+		validation.Reason = "coupon does not apply to any of the items"
+		return validation, nil
+	}
+
+	validation.Valid = true
+	validation.Discount = parseMoney(discounts[0].Amount)
+	return validation, nil
+}
+
+// priceOrder sets the discounts and the total before delivery of an order
+// whose items are priced. Every running promotion the order is eligible for
+// applies, along with the one of couponCode if given, each worked out on the
+// item prices and all of them together taking off at most the item total.
+func (s *GRPCMarketPlaceServer) priceOrder(ctx context.Context, order *Order, products map[string]*Product, couponCode string) error {
+	var amounts []Money
+	for _, item := range order.Items {
+		amounts = append(amounts, item.Price.Mul(item.Quantity))
+	}
+
+	subtotal, err := sumMoney(amounts...)
+	if err != nil {
+		return status.Errorf(codes.FailedPrecondition, "products of shop[%s] in one order must be priced in the same currency", order.ShopID)
+	}
+
+	filter := bson.M{
+		"code":      bson.M{"$exists": false},
+		"starts_at": bson.M{"$lte": order.CreatedOn},
+		"$or":       bson.A{bson.M{"shop_ids": order.ShopID}, bson.M{"shop_ids": bson.A{}}},
+	}
+
+	running, err := getItemOrError(s.svc.promotionRepo.Find(filter, bson.D{{Key: "createdOn", Value: 1}}, 0, 0))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return errors.New("failed to get promotions")
+	}
+
+	var promotions []Promotion
+	for i := range running {
+		reason, err := s.promotionUnavailable(ctx, &running[i], order.UserID, order.ShopID, order.CreatedOn)
+		if err != nil {
+			return err
+		}
+		if reason == "" {
+			promotions = append(promotions, running[i])
+		}
+	}
+
+	if couponCode != "" {
+		coupon, err := s.findCoupon(couponCode)
+		if err != nil {
+			return err
+		}
+
+		reason, err := s.promotionUnavailable(ctx, coupon, order.UserID, order.ShopID, order.CreatedOn)
+		if err != nil {
+			return err
+		}
+		if reason != "" {
+			return status.Error(codes.FailedPrecondition, reason)
+		}
+		promotions = append(promotions, *coupon)
+	}
+
+	discounts, err := s.discountsFor(promotions, order.Items, products)
+	if err != nil {
+		return err
+	}
+
+	order.Total = subtotal
+	for _, discount := range discounts {
+		// never take off more than is left
+		if cmp, _ := discount.Amount.Cmp(order.Total); cmp > 0 {
+			discount.Amount = order.Total
+		}
+		if discount.Amount.MinorUnits == 0 {
+			continue
+		}
+
+		order.Total, _ = order.Total.Sub(discount.Amount)
+		order.Discounts = append(order.Discounts, discount)
+	}
+
+	return nil
+}
+
+// discountBy returns what the promotion takes off the order, zero if it does
+// not apply to it.
+func (o *Order) discountBy(promotionId string) Money {
+	for _, discount := range o.Discounts {
+		if discount.PromotionID == promotionId {
+			return discount.Amount
+		}
+	}
+	return Money{}
+}
+
+// discountsFor works out what each of the promotions takes off the items,
+// leaving out the ones taking off nothing.
+func (s *GRPCMarketPlaceServer) discountsFor(promotions []Promotion, items []OrderItem, products map[string]*Product) ([]OrderDiscount, error) {
+	var scoped bool
+	for _, promotion := range promotions {
+		scoped = scoped || len(promotion.CategoryIDs) > 0
+	}
+
+	var categories map[string]map[string]bool
+	if scoped {
+		ancestors, err := s.categoryAncestors(products)
+		if err != nil {
+			return nil, err
+		}
+		categories = productCategories(products, ancestors)
+	}
+
+	var discounts []OrderDiscount
+	for _, promotion := range promotions {
+		var eligible []OrderItem
+		for _, item := range items {
+			if promotion.covers(categories[item.ProductID]) {
+				eligible = append(eligible, item)
+			}
+		}
+
+		amount := promotion.discountOn(eligible)
+		if amount.MinorUnits <= 0 {
+			continue
+		}
+
+		discounts = append(discounts, OrderDiscount{
+			PromotionID: promotion.ID,
+			Name:        promotion.Name,
+			Code:        promotion.Code,
+			Amount:      amount,
+		})
+	}
+
+	return discounts, nil
+}
+
+// categoryAncestors returns the ancestors of the categories the products are
+// in, from the root down.
+func (s *GRPCMarketPlaceServer) categoryAncestors(products map[string]*Product) (map[string][]string, error) {
+	var categoryIds []string
+	for _, product := range products {
+		categoryIds = append(categoryIds, product.CategoryIDs...)
+	}
+
+	ancestors := make(map[string][]string)
+	if categoryIds = uniqueStrings(categoryIds); len(categoryIds) > 0 {
+		found, err := getItemOrError(s.svc.categoryRepo.Find(bson.M{"_id": bson.M{"$in": categoryIds}}, nil, 0, 0))
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return nil, errors.New("failed to get categories")
+		}
+
+		for _, category := range found {
+			ancestors[category.ID] = category.AncestorIDs
+		}
+	}
+
+	return ancestors, nil
+}
+
+// productCategories returns the ids of the categories each product is in,
+// including the ones above its own.
+func productCategories(products map[string]*Product, ancestors map[string][]string) map[string]map[string]bool {
+	categories := make(map[string]map[string]bool)
+	for _, product := range products {
+		in := make(map[string]bool)
+		for _, categoryId := range product.CategoryIDs {
+			in[categoryId] = true
+			for _, ancestorId := range ancestors[categoryId] {
+				in[ancestorId] = true
+			}
+		}
+		categories[product.ID] = in
+	}
+
+	return categories
+}
+
+// findCoupon returns the promotion redeemed with code.
+func (s *GRPCMarketPlaceServer) findCoupon(code string) (*Promotion, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return nil, status.Error(codes.NotFound, "unknown coupon")
+	}
+
+	promotion, err := getItemOrError(s.svc.promotionRepo.FindOne(bson.M{"code": code}))
+	if err == mongo.ErrNoDocuments {
+		return nil, status.Errorf(codes.NotFound, "unknown coupon %s", code)
+	}
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to get coupon")
+	}
+
+	return promotion, nil
+}
+
+// promotionUnavailable returns why a promotion cannot be applied at now to an
+// order of the user from the shop, which is left unchecked when empty. The
+// reason is empty when the promotion can be applied. ctx may be a
+// mongo.SessionContext to count the user's orders within a transaction.
+func (s *GRPCMarketPlaceServer) promotionUnavailable(ctx context.Context, promotion *Promotion, userId, shopId string, now int64) (string, error) {
+	switch {
+	case now < promotion.StartsAt:
+		return "promotion has not started yet", nil
+	case promotion.EndsAt != 0 && now >= promotion.EndsAt:
+		return "promotion has ended", nil
+	case promotion.UsageLimit > 0 && promotion.UsedCount >= promotion.UsageLimit:
+		return "promotion has been used up", nil
+	case shopId != "" && !promotion.appliesTo(shopId):
+		return fmt.Sprintf("promotion is not valid for shop[%s]", shopId), nil
+	}
+
+	if promotion.PerUserLimit > 0 {
+		count, err := getCollection(&s.svc.orderRepo.AbstractRepository).CountDocuments(ctx, redemptionsFilter(promotion.ID, userId))
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return "", errors.New("failed to get orders")
+		}
+
+		if count >= int64(promotion.PerUserLimit) {
+			return "user has already used the promotion as often as allowed", nil
+		}
+	}
+
+	return "", nil
+}
+
+// redeemPromotions counts the order towards the usage limits of its
+// promotions. It must run inside the transaction storing the order.
+func (s *GRPCMarketPlaceServer) redeemPromotions(sessCtx mongo.SessionContext, order *Order) error {
+	for _, discount := range order.Discounts {
+		promotion := &Promotion{}
+		err := getCollection(&s.svc.promotionRepo.AbstractRepository).FindOneAndUpdate(sessCtx,
+			bson.M{
+				"_id": discount.PromotionID,
+				"$or": bson.A{
+					bson.M{"usage_limit": 0},
+					bson.M{"$expr": bson.M{"$lt": bson.A{"$used_count", "$usage_limit"}}},
+				},
+			},
+			bson.M{"$inc": bson.M{"used_count": 1}},
+		).Decode(promotion)
+		if err == mongo.ErrNoDocuments {
+			return status.Errorf(codes.FailedPrecondition, "promotion %q has been used up", discount.Name)
+		}
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return errors.New("failed to redeem promotion")
+		}
+
+		// the usage limit was checked by the update, only the user's is left
+		promotion.UsageLimit = 0
+		reason, err := s.promotionUnavailable(sessCtx, promotion, order.UserID, "", order.CreatedOn)
+		if err != nil {
+			return err
+		}
+		if reason != "" {
+			return status.Error(codes.FailedPrecondition, reason)
+		}
+	}
+
+	return nil
+}
+
+// releasePromotions gives back the uses of an order that did not go through.
+func (s *GRPCMarketPlaceServer) releasePromotions(sessCtx mongo.SessionContext, order *Order) error {
+	for _, discount := range order.Discounts {
+		_, err := getCollection(&s.svc.promotionRepo.AbstractRepository).UpdateOne(sessCtx,
+			bson.M{"_id": discount.PromotionID, "used_count": bson.M{"$gt": 0}},
+			bson.M{"$inc": bson.M{"used_count": -1}},
+		)
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return errors.New("failed to release promotion")
+		}
+	}
+
+	return nil
+}
+
+// redemptionsFilter matches the orders of a user that used a promotion and
+// were not cancelled or rejected.
+func redemptionsFilter(promotionId, userId string) bson.M {
+	return bson.M{
+		"user_id":                userId,
+		"discounts.promotion_id": promotionId,
+		"status":                 bson.M{"$nin": bson.A{OrderStatusCancelled, OrderStatusRejected}},
+	}
+}
+
+func (p *Promotion) appliesTo(shopId string) bool {
+	if len(p.ShopIDs) == 0 {
+		return true
+	}
+
+	for _, id := range p.ShopIDs {
+		if id == shopId {
+			return true
+		}
+	}
+	return false
+}
+
+// covers reports whether a product in the given categories is eligible.
+func (p *Promotion) covers(categories map[string]bool) bool {
+	if len(p.CategoryIDs) == 0 {
+		return true
+	}
+
+	for _, categoryId := range p.CategoryIDs {
+		if categories[categoryId] {
+			return true
+		}
+	}
+	return false
+}
+
+// discountOn returns what the promotion takes off the eligible items, which
+// are all priced in the same currency.
+func (p *Promotion) discountOn(items []OrderItem) Money {
+	var total Money
+	for _, item := range items {
+		line := item.Price.Mul(item.Quantity)
+
+		if p.Type == PromotionTypeBuyXGetY {
+			free := item.Quantity / (p.BuyQuantity + p.GetQuantity) * p.GetQuantity
+			line = item.Price.Mul(free)
+		}
+
+		total, _ = total.Add(line)
+	}
+
+	switch p.Type {
+	case PromotionTypePercentage:
+		return total.Percent(p.PercentOff)
+	case PromotionTypeFixed:
+		cmp, err := p.AmountOff.Cmp(total)
+		if err != nil {
+			// the items are priced in another currency
+			return Money{}
+		}
+		if cmp < 0 {
+			return p.AmountOff
+		}
+	}
+	return total
+}
+
+// newPromotion validates a promotion received over the api. Its categories
+// are left to the caller to check.
+func newPromotion(req *proto.CreatePromotionRequest) (Promotion, error) {
+	promotion := Promotion{
+		ID:           primitive.NewObjectID().Hex(),
+		Name:         strings.TrimSpace(req.Name),
+		Code:         strings.ToUpper(strings.TrimSpace(req.Code)),
+		Type:         req.Type,
+		ShopIDs:      uniqueStrings(req.ShopIds),
+		StartsAt:     req.StartsAt,
+		EndsAt:       req.EndsAt,
+		UsageLimit:   int(req.UsageLimit),
+		PerUserLimit: int(req.PerUserLimit),
+		CreatedOn:    time.Now().Unix(),
+	}
+
+	if promotion.Name == "" {
+		return Promotion{}, errors.New("name is required")
+	}
+
+	if promotion.ShopIDs == nil {
+		promotion.ShopIDs = []string{}
+	}
+
+	switch promotion.Type {
+	case PromotionTypePercentage:
+		if req.PercentOff <= 0 || req.PercentOff > 100 {
+			return Promotion{}, errors.New("percent off must be between 1 and 100")
+		}
+		promotion.PercentOff = int(req.PercentOff)
+	case PromotionTypeFixed:
+		amountOff, err := newMoney(req.AmountOff)
+		if err != nil {
+			return Promotion{}, fmt.Errorf("amount off: %w", err)
+		}
+		if amountOff.MinorUnits == 0 {
+			return Promotion{}, errors.New("amount off must be positive")
+		}
+		promotion.AmountOff = amountOff
+	case PromotionTypeBuyXGetY:
+		if req.BuyQuantity <= 0 || req.GetQuantity <= 0 {
+			return Promotion{}, errors.New("buy and get quantities must be positive")
+		}
+		promotion.BuyQuantity = int(req.BuyQuantity)
+		promotion.GetQuantity = int(req.GetQuantity)
+	default:
+		return Promotion{}, fmt.Errorf("unknown promotion type %q", req.Type)
+	}
+
+	if promotion.StartsAt < 0 || promotion.EndsAt < 0 {
+		return Promotion{}, errors.New("start and end times cannot be negative")
+	}
+	if promotion.EndsAt != 0 && promotion.EndsAt <= promotion.StartsAt {
+		return Promotion{}, errors.New("promotion must end after it starts")
+	}
+
+	if promotion.UsageLimit < 0 || promotion.PerUserLimit < 0 {
+		return Promotion{}, errors.New("usage limits cannot be negative")
+	}
+
+	return promotion, nil
+}
+
+func parsePromotion(promotion *Promotion) *proto.Promotion {
+	ppromotion := &proto.Promotion{
+		Id:           promotion.ID,
+		Name:         promotion.Name,
+		Code:         promotion.Code,
+		Type:         promotion.Type,
+		PercentOff:   int32(promotion.PercentOff),
+		BuyQuantity:  int32(promotion.BuyQuantity),
+		GetQuantity:  int32(promotion.GetQuantity),
+		ShopIds:      promotion.ShopIDs,
+		CategoryIds:  promotion.CategoryIDs,
+		StartsAt:     promotion.StartsAt,
+		EndsAt:       promotion.EndsAt,
+		UsageLimit:   int32(promotion.UsageLimit),
+		PerUserLimit: int32(promotion.PerUserLimit),
+		UsedCount:    int32(promotion.UsedCount),
+		CreatedOn:    promotion.CreatedOn,
+	}
+
+	if promotion.Type == PromotionTypeFixed {
+		ppromotion.AmountOff = parseMoney(promotion.AmountOff)
+	}
+
+	return ppromotion
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestPromotionDiscountOn(t *testing.T) {
+	items := func(lines ...OrderItem) []OrderItem { return lines }
+	item := func(price int64, quantity int) OrderItem {
+		return OrderItem{ProductID: "p", Quantity: quantity, Price: usd(price)}
+	}
+
+	tests := []struct {
+		name      string
+		promotion Promotion
+		items     []OrderItem
+		want      Money
+	}{
+		{
+			"percentage of every line",
+			Promotion{Type: PromotionTypePercentage, PercentOff: 10},
+			items(item(1000, 2), item(500, 1)),
+			usd(250),
+		},
+		{
+			"percentage rounded down",
+			Promotion{Type: PromotionTypePercentage, PercentOff: 15},
+			items(item(999, 1)),
+			usd(149),
+		},
+		{
+			"percentage of nothing",
+			Promotion{Type: PromotionTypePercentage, PercentOff: 50},
+			nil,
+			Money{},
+		},
+		{
+			"fixed amount",
+			Promotion{Type: PromotionTypeFixed, AmountOff: usd(500)},
+			items(item(1000, 2)),
+			usd(500),
+		},
+		{
+			"fixed amount capped at the items",
+			Promotion{Type: PromotionTypeFixed, AmountOff: usd(5000)},
+			items(item(1000, 2)),
+			usd(2000),
+		},
+		{
+			"fixed amount equal to the items",
+			Promotion{Type: PromotionTypeFixed, AmountOff: usd(2000)},
+			items(item(1000, 2)),
+			usd(2000),
+		},
+		{
+			"fixed amount in another currency",
+			Promotion{Type: PromotionTypeFixed, AmountOff: Money{CurrencyCode: "EUR", MinorUnits: 500}},
+			items(item(1000, 2)),
+			Money{},
+		},
+		{
+			"buy 2 get 1 on complete groups",
+			Promotion{Type: PromotionTypeBuyXGetY, BuyQuantity: 2, GetQuantity: 1},
+			items(item(300, 7)),
+			usd(600),
+		},
+		{
+			"buy 2 get 1 short of a group",
+			Promotion{Type: PromotionTypeBuyXGetY, BuyQuantity: 2, GetQuantity: 1},
+			items(item(300, 2)),
+			usd(0),
+		},
+		{
+			"buy 1 get 1 per line",
+			Promotion{Type: PromotionTypeBuyXGetY, BuyQuantity: 1, GetQuantity: 1},
+			items(item(300, 3), item(100, 2)),
+			usd(400),
+		},
+	}
+
+	for _, test := range tests {
+		got := test.promotion.discountOn(test.items)
+		if got != test.want {
+			t.Errorf("%s: discountOn = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestPromotionCovers(t *testing.T) {
+	// shoes are under apparel, and the product is in running shoes
+	products := map[string]*Product{
+		"runner":  {ID: "runner", CategoryIDs: []string{"running"}},
+		"unfiled": {ID: "unfiled"},
+	}
+	ancestors := map[string][]string{"running": {"apparel", "shoes"}}
+	categories := productCategories(products, ancestors)
+
+	tests := []struct {
+		name        string
+		categoryIds []string
+		product     string
+		want        bool
+	}{
+		{"every product", nil, "runner", true},
+		{"every product without categories", nil, "unfiled", true},
+		{"own category", []string{"running"}, "runner", true},
+		{"category above its own", []string{"apparel"}, "runner", true},
+		{"other category", []string{"books"}, "runner", false},
+		{"one of several categories", []string{"books", "shoes"}, "runner", true},
+		{"product without categories", []string{"apparel"}, "unfiled", false},
+	}
+
+	for _, test := range tests {
+		promotion := Promotion{CategoryIDs: test.categoryIds}
+		if got := promotion.covers(categories[test.product]); got != test.want {
+			t.Errorf("%s: covers = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -23,15 +23,69 @@ const (
 	MarketplaceService_GetShopByID_FullMethodName                   = "/MarketplaceService/GetShopByID"
 	MarketplaceService_GetShopsByServiceableProducts_FullMethodName = "/MarketplaceService/GetShopsByServiceableProducts"
 	MarketplaceService_GetShopForUser_FullMethodName                = "/MarketplaceService/GetShopForUser"
+	MarketplaceService_SearchNearbyShops_FullMethodName             = "/MarketplaceService/SearchNearbyShops"
+	MarketplaceService_SearchShopsInArea_FullMethodName             = "/MarketplaceService/SearchShopsInArea"
+	MarketplaceService_SetDeliveryZones_FullMethodName              = "/MarketplaceService/SetDeliveryZones"
+	MarketplaceService_SetOperatingHours_FullMethodName             = "/MarketplaceService/SetOperatingHours"
+	MarketplaceService_IsShopOpen_FullMethodName                    = "/MarketplaceService/IsShopOpen"
+	MarketplaceService_GetDeliveryZones_FullMethodName              = "/MarketplaceService/GetDeliveryZones"
 	MarketplaceService_CreateProduct_FullMethodName                 = "/MarketplaceService/CreateProduct"
 	MarketplaceService_GetProductByID_FullMethodName                = "/MarketplaceService/GetProductByID"
+	MarketplaceService_SetProductCategories_FullMethodName          = "/MarketplaceService/SetProductCategories"
+	MarketplaceService_SearchProducts_FullMethodName                = "/MarketplaceService/SearchProducts"
+	MarketplaceService_AddProductVariant_FullMethodName             = "/MarketplaceService/AddProductVariant"
+	MarketplaceService_CreateCategory_FullMethodName                = "/MarketplaceService/CreateCategory"
+	MarketplaceService_ListCategories_FullMethodName                = "/MarketplaceService/ListCategories"
+	MarketplaceService_ListProductsInCategory_FullMethodName        = "/MarketplaceService/ListProductsInCategory"
 	MarketplaceService_UpdateInventory_FullMethodName               = "/MarketplaceService/UpdateInventory"
 	MarketplaceService_GetInventory_FullMethodName                  = "/MarketplaceService/GetInventory"
+	MarketplaceService_ListInventoryMovements_FullMethodName        = "/MarketplaceService/ListInventoryMovements"
+	MarketplaceService_GetStockAt_FullMethodName                    = "/MarketplaceService/GetStockAt"
+	MarketplaceService_TransferStock_FullMethodName                 = "/MarketplaceService/TransferStock"
+	MarketplaceService_SetReorderThreshold_FullMethodName           = "/MarketplaceService/SetReorderThreshold"
+	MarketplaceService_ListLowStock_FullMethodName                  = "/MarketplaceService/ListLowStock"
+	MarketplaceService_ListShopInventory_FullMethodName             = "/MarketplaceService/ListShopInventory"
+	MarketplaceService_BulkUpdateInventory_FullMethodName           = "/MarketplaceService/BulkUpdateInventory"
+	MarketplaceService_ReserveStock_FullMethodName                  = "/MarketplaceService/ReserveStock"
+	MarketplaceService_CommitReservation_FullMethodName             = "/MarketplaceService/CommitReservation"
+	MarketplaceService_ReleaseReservation_FullMethodName            = "/MarketplaceService/ReleaseReservation"
 	MarketplaceService_AddServiceableProduct_FullMethodName         = "/MarketplaceService/AddServiceableProduct"
 	MarketplaceService_GetServiceableProducts_FullMethodName        = "/MarketplaceService/GetServiceableProducts"
+	MarketplaceService_SetShopPrice_FullMethodName                  = "/MarketplaceService/SetShopPrice"
 	MarketplaceService_CreateUser_FullMethodName                    = "/MarketplaceService/CreateUser"
 	MarketplaceService_GetUserByID_FullMethodName                   = "/MarketplaceService/GetUserByID"
+	MarketplaceService_SetUserRole_FullMethodName                   = "/MarketplaceService/SetUserRole"
 	MarketplaceService_GetNearestNeighbour_FullMethodName           = "/MarketplaceService/GetNearestNeighbour"
+	MarketplaceService_GetKNearestUsers_FullMethodName              = "/MarketplaceService/GetKNearestUsers"
+	MarketplaceService_GetKNearestShops_FullMethodName              = "/MarketplaceService/GetKNearestShops"
+	MarketplaceService_PlaceOrder_FullMethodName                    = "/MarketplaceService/PlaceOrder"
+	MarketplaceService_GetOrder_FullMethodName                      = "/MarketplaceService/GetOrder"
+	MarketplaceService_ListOrdersForUser_FullMethodName             = "/MarketplaceService/ListOrdersForUser"
+	MarketplaceService_ListOrdersForShop_FullMethodName             = "/MarketplaceService/ListOrdersForShop"
+	MarketplaceService_UpdateOrderStatus_FullMethodName             = "/MarketplaceService/UpdateOrderStatus"
+	MarketplaceService_AddToCart_FullMethodName                     = "/MarketplaceService/AddToCart"
+	MarketplaceService_RemoveFromCart_FullMethodName                = "/MarketplaceService/RemoveFromCart"
+	MarketplaceService_GetCart_FullMethodName                       = "/MarketplaceService/GetCart"
+	MarketplaceService_Checkout_FullMethodName                      = "/MarketplaceService/Checkout"
+	MarketplaceService_CreatePromotion_FullMethodName               = "/MarketplaceService/CreatePromotion"
+	MarketplaceService_ValidateCoupon_FullMethodName                = "/MarketplaceService/ValidateCoupon"
+	MarketplaceService_SetTaxRule_FullMethodName                    = "/MarketplaceService/SetTaxRule"
+	MarketplaceService_ListTaxRules_FullMethodName                  = "/MarketplaceService/ListTaxRules"
+	MarketplaceService_DeleteTaxRule_FullMethodName                 = "/MarketplaceService/DeleteTaxRule"
+	MarketplaceService_SchedulePriceChange_FullMethodName           = "/MarketplaceService/SchedulePriceChange"
+	MarketplaceService_GetPriceHistory_FullMethodName               = "/MarketplaceService/GetPriceHistory"
+	MarketplaceService_Login_FullMethodName                         = "/MarketplaceService/Login"
+	MarketplaceService_RefreshToken_FullMethodName                  = "/MarketplaceService/RefreshToken"
+	MarketplaceService_SetPassword_FullMethodName                   = "/MarketplaceService/SetPassword"
+	MarketplaceService_InviteStaff_FullMethodName                   = "/MarketplaceService/InviteStaff"
+	MarketplaceService_RevokeStaffInvitation_FullMethodName         = "/MarketplaceService/RevokeStaffInvitation"
+	MarketplaceService_ListMyInvitations_FullMethodName             = "/MarketplaceService/ListMyInvitations"
+	MarketplaceService_AcceptStaffInvitation_FullMethodName         = "/MarketplaceService/AcceptStaffInvitation"
+	MarketplaceService_DeclineStaffInvitation_FullMethodName        = "/MarketplaceService/DeclineStaffInvitation"
+	MarketplaceService_ListShopStaff_FullMethodName                 = "/MarketplaceService/ListShopStaff"
+	MarketplaceService_SetStaffPermissions_FullMethodName           = "/MarketplaceService/SetStaffPermissions"
+	MarketplaceService_RemoveStaff_FullMethodName                   = "/MarketplaceService/RemoveStaff"
+	MarketplaceService_ListMyShops_FullMethodName                   = "/MarketplaceService/ListMyShops"
 )
 
 // MarketplaceServiceClient is the client API for MarketplaceService service.
@@ -43,20 +97,83 @@ type MarketplaceServiceClient interface {
 	GetShopByID(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Shop, error)
 	GetShopsByServiceableProducts(ctx context.Context, in *GetShopsByServiceableProductsRequest, opts ...grpc.CallOption) (*Shops, error)
 	GetShopForUser(ctx context.Context, in *GetShopForUserRequest, opts ...grpc.CallOption) (*Shops, error)
+	SearchNearbyShops(ctx context.Context, in *SearchNearbyShopsRequest, opts ...grpc.CallOption) (*SearchNearbyShopsResponse, error)
+	SearchShopsInArea(ctx context.Context, in *SearchShopsInAreaRequest, opts ...grpc.CallOption) (*SearchShopsInAreaResponse, error)
+	SetDeliveryZones(ctx context.Context, in *SetDeliveryZonesRequest, opts ...grpc.CallOption) (*DeliveryZones, error)
+	SetOperatingHours(ctx context.Context, in *SetOperatingHoursRequest, opts ...grpc.CallOption) (*Shop, error)
+	IsShopOpen(ctx context.Context, in *IsShopOpenRequest, opts ...grpc.CallOption) (*ShopOpenStatus, error)
+	GetDeliveryZones(ctx context.Context, in *GetDeliveryZonesRequest, opts ...grpc.CallOption) (*DeliveryZones, error)
 	// Product-related methods
 	CreateProduct(ctx context.Context, in *CreateProductRequest, opts ...grpc.CallOption) (*Product, error)
 	GetProductByID(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Product, error)
+	SetProductCategories(ctx context.Context, in *SetProductCategoriesRequest, opts ...grpc.CallOption) (*Product, error)
+	SearchProducts(ctx context.Context, in *SearchProductsRequest, opts ...grpc.CallOption) (*SearchProductsResponse, error)
+	AddProductVariant(ctx context.Context, in *AddProductVariantRequest, opts ...grpc.CallOption) (*Product, error)
+	// Category-related methods
+	CreateCategory(ctx context.Context, in *CreateCategoryRequest, opts ...grpc.CallOption) (*Category, error)
+	ListCategories(ctx context.Context, in *ListCategoriesRequest, opts ...grpc.CallOption) (*Categories, error)
+	ListProductsInCategory(ctx context.Context, in *ListProductsInCategoryRequest, opts ...grpc.CallOption) (*ListProductsInCategoryResponse, error)
 	// Inventory-related methods
 	UpdateInventory(ctx context.Context, in *UpdateInventoryRequest, opts ...grpc.CallOption) (*Inventory, error)
 	GetInventory(ctx context.Context, in *GetInventoryRequest, opts ...grpc.CallOption) (*Inventory, error)
+	ListInventoryMovements(ctx context.Context, in *ListInventoryMovementsRequest, opts ...grpc.CallOption) (*InventoryMovements, error)
+	GetStockAt(ctx context.Context, in *GetStockAtRequest, opts ...grpc.CallOption) (*StockLevel, error)
+	TransferStock(ctx context.Context, in *TransferStockRequest, opts ...grpc.CallOption) (*StockTransfer, error)
+	SetReorderThreshold(ctx context.Context, in *SetReorderThresholdRequest, opts ...grpc.CallOption) (*Inventory, error)
+	ListLowStock(ctx context.Context, in *ListLowStockRequest, opts ...grpc.CallOption) (*Inventories, error)
+	ListShopInventory(ctx context.Context, in *ListShopInventoryRequest, opts ...grpc.CallOption) (*Inventories, error)
+	BulkUpdateInventory(ctx context.Context, opts ...grpc.CallOption) (MarketplaceService_BulkUpdateInventoryClient, error)
+	// Reservation-related methods
+	ReserveStock(ctx context.Context, in *ReserveStockRequest, opts ...grpc.CallOption) (*Reservation, error)
+	CommitReservation(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Reservation, error)
+	ReleaseReservation(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Reservation, error)
 	// Serviceable products methods
 	AddServiceableProduct(ctx context.Context, in *AddServiceableProductRequest, opts ...grpc.CallOption) (*Shop, error)
 	GetServiceableProducts(ctx context.Context, in *GetServiceableProductsRequest, opts ...grpc.CallOption) (*Products, error)
+	SetShopPrice(ctx context.Context, in *SetShopPriceRequest, opts ...grpc.CallOption) (*ServiceableProduct, error)
 	// User-related methods
 	CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*User, error)
 	GetUserByID(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*User, error)
+	SetUserRole(ctx context.Context, in *SetUserRoleRequest, opts ...grpc.CallOption) (*User, error)
 	// Neighbour-related methods
 	GetNearestNeighbour(ctx context.Context, in *GetNearestNeighbourRequest, opts ...grpc.CallOption) (*User, error)
+	GetKNearestUsers(ctx context.Context, in *KNearestRequest, opts ...grpc.CallOption) (*NearbyUsers, error)
+	GetKNearestShops(ctx context.Context, in *KNearestRequest, opts ...grpc.CallOption) (*NearbyShops, error)
+	// Order-related methods
+	PlaceOrder(ctx context.Context, in *PlaceOrderRequest, opts ...grpc.CallOption) (*Order, error)
+	GetOrder(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Order, error)
+	ListOrdersForUser(ctx context.Context, in *ListOrdersForUserRequest, opts ...grpc.CallOption) (*Orders, error)
+	ListOrdersForShop(ctx context.Context, in *ListOrdersForShopRequest, opts ...grpc.CallOption) (*Orders, error)
+	UpdateOrderStatus(ctx context.Context, in *UpdateOrderStatusRequest, opts ...grpc.CallOption) (*Order, error)
+	// Cart-related methods
+	AddToCart(ctx context.Context, in *AddToCartRequest, opts ...grpc.CallOption) (*Cart, error)
+	RemoveFromCart(ctx context.Context, in *RemoveFromCartRequest, opts ...grpc.CallOption) (*Cart, error)
+	GetCart(ctx context.Context, in *GetCartRequest, opts ...grpc.CallOption) (*Cart, error)
+	Checkout(ctx context.Context, in *CheckoutRequest, opts ...grpc.CallOption) (*Orders, error)
+	// Promotion-related methods
+	CreatePromotion(ctx context.Context, in *CreatePromotionRequest, opts ...grpc.CallOption) (*Promotion, error)
+	ValidateCoupon(ctx context.Context, in *ValidateCouponRequest, opts ...grpc.CallOption) (*CouponValidation, error)
+	// Tax-related methods
+	SetTaxRule(ctx context.Context, in *TaxRule, opts ...grpc.CallOption) (*TaxRule, error)
+	ListTaxRules(ctx context.Context, in *ListTaxRulesRequest, opts ...grpc.CallOption) (*TaxRules, error)
+	DeleteTaxRule(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*TaxRule, error)
+	// Price-related methods
+	SchedulePriceChange(ctx context.Context, in *SchedulePriceChangeRequest, opts ...grpc.CallOption) (*ScheduledPriceChange, error)
+	GetPriceHistory(ctx context.Context, in *GetPriceHistoryRequest, opts ...grpc.CallOption) (*PriceHistory, error)
+	// Auth-related methods
+	Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*AuthTokens, error)
+	RefreshToken(ctx context.Context, in *RefreshTokenRequest, opts ...grpc.CallOption) (*AuthTokens, error)
+	SetPassword(ctx context.Context, in *SetPasswordRequest, opts ...grpc.CallOption) (*User, error)
+	// Staff-related methods
+	InviteStaff(ctx context.Context, in *InviteStaffRequest, opts ...grpc.CallOption) (*StaffInvitation, error)
+	RevokeStaffInvitation(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*StaffInvitation, error)
+	ListMyInvitations(ctx context.Context, in *ListMyInvitationsRequest, opts ...grpc.CallOption) (*StaffInvitations, error)
+	AcceptStaffInvitation(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*StaffInvitation, error)
+	DeclineStaffInvitation(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*StaffInvitation, error)
+	ListShopStaff(ctx context.Context, in *ListShopStaffRequest, opts ...grpc.CallOption) (*ShopStaffList, error)
+	SetStaffPermissions(ctx context.Context, in *SetStaffPermissionsRequest, opts ...grpc.CallOption) (*ShopStaff, error)
+	RemoveStaff(ctx context.Context, in *RemoveStaffRequest, opts ...grpc.CallOption) (*ShopStaff, error)
+	ListMyShops(ctx context.Context, in *ListMyShopsRequest, opts ...grpc.CallOption) (*MyShops, error)
 }
 
 type marketplaceServiceClient struct {
@@ -103,6 +220,60 @@ func (c *marketplaceServiceClient) GetShopForUser(ctx context.Context, in *GetSh
 	return out, nil
 }
 
+func (c *marketplaceServiceClient) SearchNearbyShops(ctx context.Context, in *SearchNearbyShopsRequest, opts ...grpc.CallOption) (*SearchNearbyShopsResponse, error) {
+	out := new(SearchNearbyShopsResponse)
+	err := c.cc.Invoke(ctx, MarketplaceService_SearchNearbyShops_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) SearchShopsInArea(ctx context.Context, in *SearchShopsInAreaRequest, opts ...grpc.CallOption) (*SearchShopsInAreaResponse, error) {
+	out := new(SearchShopsInAreaResponse)
+	err := c.cc.Invoke(ctx, MarketplaceService_SearchShopsInArea_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) SetDeliveryZones(ctx context.Context, in *SetDeliveryZonesRequest, opts ...grpc.CallOption) (*DeliveryZones, error) {
+	out := new(DeliveryZones)
+	err := c.cc.Invoke(ctx, MarketplaceService_SetDeliveryZones_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) SetOperatingHours(ctx context.Context, in *SetOperatingHoursRequest, opts ...grpc.CallOption) (*Shop, error) {
+	out := new(Shop)
+	err := c.cc.Invoke(ctx, MarketplaceService_SetOperatingHours_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) IsShopOpen(ctx context.Context, in *IsShopOpenRequest, opts ...grpc.CallOption) (*ShopOpenStatus, error) {
+	out := new(ShopOpenStatus)
+	err := c.cc.Invoke(ctx, MarketplaceService_IsShopOpen_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) GetDeliveryZones(ctx context.Context, in *GetDeliveryZonesRequest, opts ...grpc.CallOption) (*DeliveryZones, error) {
+	out := new(DeliveryZones)
+	err := c.cc.Invoke(ctx, MarketplaceService_GetDeliveryZones_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *marketplaceServiceClient) CreateProduct(ctx context.Context, in *CreateProductRequest, opts ...grpc.CallOption) (*Product, error) {
 	out := new(Product)
 	err := c.cc.Invoke(ctx, MarketplaceService_CreateProduct_FullMethodName, in, out, opts...)
@@ -121,6 +292,60 @@ func (c *marketplaceServiceClient) GetProductByID(ctx context.Context, in *GetRe
 	return out, nil
 }
 
+func (c *marketplaceServiceClient) SetProductCategories(ctx context.Context, in *SetProductCategoriesRequest, opts ...grpc.CallOption) (*Product, error) {
+	out := new(Product)
+	err := c.cc.Invoke(ctx, MarketplaceService_SetProductCategories_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) SearchProducts(ctx context.Context, in *SearchProductsRequest, opts ...grpc.CallOption) (*SearchProductsResponse, error) {
+	out := new(SearchProductsResponse)
+	err := c.cc.Invoke(ctx, MarketplaceService_SearchProducts_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) AddProductVariant(ctx context.Context, in *AddProductVariantRequest, opts ...grpc.CallOption) (*Product, error) {
+	out := new(Product)
+	err := c.cc.Invoke(ctx, MarketplaceService_AddProductVariant_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) CreateCategory(ctx context.Context, in *CreateCategoryRequest, opts ...grpc.CallOption) (*Category, error) {
+	out := new(Category)
+	err := c.cc.Invoke(ctx, MarketplaceService_CreateCategory_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) ListCategories(ctx context.Context, in *ListCategoriesRequest, opts ...grpc.CallOption) (*Categories, error) {
+	out := new(Categories)
+	err := c.cc.Invoke(ctx, MarketplaceService_ListCategories_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) ListProductsInCategory(ctx context.Context, in *ListProductsInCategoryRequest, opts ...grpc.CallOption) (*ListProductsInCategoryResponse, error) {
+	out := new(ListProductsInCategoryResponse)
+	err := c.cc.Invoke(ctx, MarketplaceService_ListProductsInCategory_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *marketplaceServiceClient) UpdateInventory(ctx context.Context, in *UpdateInventoryRequest, opts ...grpc.CallOption) (*Inventory, error) {
 	out := new(Inventory)
 	err := c.cc.Invoke(ctx, MarketplaceService_UpdateInventory_FullMethodName, in, out, opts...)
@@ -139,6 +364,121 @@ func (c *marketplaceServiceClient) GetInventory(ctx context.Context, in *GetInve
 	return out, nil
 }
 
+func (c *marketplaceServiceClient) ListInventoryMovements(ctx context.Context, in *ListInventoryMovementsRequest, opts ...grpc.CallOption) (*InventoryMovements, error) {
+	out := new(InventoryMovements)
+	err := c.cc.Invoke(ctx, MarketplaceService_ListInventoryMovements_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) GetStockAt(ctx context.Context, in *GetStockAtRequest, opts ...grpc.CallOption) (*StockLevel, error) {
+	out := new(StockLevel)
+	err := c.cc.Invoke(ctx, MarketplaceService_GetStockAt_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) TransferStock(ctx context.Context, in *TransferStockRequest, opts ...grpc.CallOption) (*StockTransfer, error) {
+	out := new(StockTransfer)
+	err := c.cc.Invoke(ctx, MarketplaceService_TransferStock_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) SetReorderThreshold(ctx context.Context, in *SetReorderThresholdRequest, opts ...grpc.CallOption) (*Inventory, error) {
+	out := new(Inventory)
+	err := c.cc.Invoke(ctx, MarketplaceService_SetReorderThreshold_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) ListLowStock(ctx context.Context, in *ListLowStockRequest, opts ...grpc.CallOption) (*Inventories, error) {
+	out := new(Inventories)
+	err := c.cc.Invoke(ctx, MarketplaceService_ListLowStock_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) ListShopInventory(ctx context.Context, in *ListShopInventoryRequest, opts ...grpc.CallOption) (*Inventories, error) {
+	out := new(Inventories)
+	err := c.cc.Invoke(ctx, MarketplaceService_ListShopInventory_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) BulkUpdateInventory(ctx context.Context, opts ...grpc.CallOption) (MarketplaceService_BulkUpdateInventoryClient, error) {
+	stream, err := c.cc.NewStream(ctx, &MarketplaceService_ServiceDesc.Streams[0], MarketplaceService_BulkUpdateInventory_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &marketplaceServiceBulkUpdateInventoryClient{stream}
+	return x, nil
+}
+
+type MarketplaceService_BulkUpdateInventoryClient interface {
+	Send(*BulkInventoryRow) error
+	CloseAndRecv() (*BulkInventoryReport, error)
+	grpc.ClientStream
+}
+
+type marketplaceServiceBulkUpdateInventoryClient struct {
+	grpc.ClientStream
+}
+
+func (x *marketplaceServiceBulkUpdateInventoryClient) Send(m *BulkInventoryRow) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *marketplaceServiceBulkUpdateInventoryClient) CloseAndRecv() (*BulkInventoryReport, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(BulkInventoryReport)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *marketplaceServiceClient) ReserveStock(ctx context.Context, in *ReserveStockRequest, opts ...grpc.CallOption) (*Reservation, error) {
+	out := new(Reservation)
+	err := c.cc.Invoke(ctx, MarketplaceService_ReserveStock_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) CommitReservation(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Reservation, error) {
+	out := new(Reservation)
+	err := c.cc.Invoke(ctx, MarketplaceService_CommitReservation_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) ReleaseReservation(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Reservation, error) {
+	out := new(Reservation)
+	err := c.cc.Invoke(ctx, MarketplaceService_ReleaseReservation_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *marketplaceServiceClient) AddServiceableProduct(ctx context.Context, in *AddServiceableProductRequest, opts ...grpc.CallOption) (*Shop, error) {
 	out := new(Shop)
 	err := c.cc.Invoke(ctx, MarketplaceService_AddServiceableProduct_FullMethodName, in, out, opts...)
@@ -157,6 +497,15 @@ func (c *marketplaceServiceClient) GetServiceableProducts(ctx context.Context, i
 	return out, nil
 }
 
+func (c *marketplaceServiceClient) SetShopPrice(ctx context.Context, in *SetShopPriceRequest, opts ...grpc.CallOption) (*ServiceableProduct, error) {
+	out := new(ServiceableProduct)
+	err := c.cc.Invoke(ctx, MarketplaceService_SetShopPrice_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *marketplaceServiceClient) CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*User, error) {
 	out := new(User)
 	err := c.cc.Invoke(ctx, MarketplaceService_CreateUser_FullMethodName, in, out, opts...)
@@ -175,6 +524,15 @@ func (c *marketplaceServiceClient) GetUserByID(ctx context.Context, in *GetReque
 	return out, nil
 }
 
+func (c *marketplaceServiceClient) SetUserRole(ctx context.Context, in *SetUserRoleRequest, opts ...grpc.CallOption) (*User, error) {
+	out := new(User)
+	err := c.cc.Invoke(ctx, MarketplaceService_SetUserRole_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *marketplaceServiceClient) GetNearestNeighbour(ctx context.Context, in *GetNearestNeighbourRequest, opts ...grpc.CallOption) (*User, error) {
 	out := new(User)
 	err := c.cc.Invoke(ctx, MarketplaceService_GetNearestNeighbour_FullMethodName, in, out, opts...)
@@ -184,318 +542,1793 @@ func (c *marketplaceServiceClient) GetNearestNeighbour(ctx context.Context, in *
 	return out, nil
 }
 
-// MarketplaceServiceServer is the server API for MarketplaceService service.
-// All implementations must embed UnimplementedMarketplaceServiceServer
-// for forward compatibility
-type MarketplaceServiceServer interface {
-	// Shop-related methods
-	CreateShop(context.Context, *CreateShopRequest) (*Shop, error)
-	GetShopByID(context.Context, *GetRequest) (*Shop, error)
-	GetShopsByServiceableProducts(context.Context, *GetShopsByServiceableProductsRequest) (*Shops, error)
-	GetShopForUser(context.Context, *GetShopForUserRequest) (*Shops, error)
-	// Product-related methods
-	CreateProduct(context.Context, *CreateProductRequest) (*Product, error)
-	GetProductByID(context.Context, *GetRequest) (*Product, error)
-	// Inventory-related methods
-	UpdateInventory(context.Context, *UpdateInventoryRequest) (*Inventory, error)
-	GetInventory(context.Context, *GetInventoryRequest) (*Inventory, error)
-	// Serviceable products methods
-	AddServiceableProduct(context.Context, *AddServiceableProductRequest) (*Shop, error)
-	GetServiceableProducts(context.Context, *GetServiceableProductsRequest) (*Products, error)
-	// User-related methods
-	CreateUser(context.Context, *CreateUserRequest) (*User, error)
-	GetUserByID(context.Context, *GetRequest) (*User, error)
-	// Neighbour-related methods
-	GetNearestNeighbour(context.Context, *GetNearestNeighbourRequest) (*User, error)
-	mustEmbedUnimplementedMarketplaceServiceServer()
+func (c *marketplaceServiceClient) GetKNearestUsers(ctx context.Context, in *KNearestRequest, opts ...grpc.CallOption) (*NearbyUsers, error) {
+	out := new(NearbyUsers)
+	err := c.cc.Invoke(ctx, MarketplaceService_GetKNearestUsers_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
-// UnimplementedMarketplaceServiceServer must be embedded to have forward compatible implementations.
-type UnimplementedMarketplaceServiceServer struct {
+func (c *marketplaceServiceClient) GetKNearestShops(ctx context.Context, in *KNearestRequest, opts ...grpc.CallOption) (*NearbyShops, error) {
+	out := new(NearbyShops)
+	err := c.cc.Invoke(ctx, MarketplaceService_GetKNearestShops_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
-func (UnimplementedMarketplaceServiceServer) CreateShop(context.Context, *CreateShopRequest) (*Shop, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CreateShop not implemented")
+func (c *marketplaceServiceClient) PlaceOrder(ctx context.Context, in *PlaceOrderRequest, opts ...grpc.CallOption) (*Order, error) {
+	out := new(Order)
+	err := c.cc.Invoke(ctx, MarketplaceService_PlaceOrder_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
-func (UnimplementedMarketplaceServiceServer) GetShopByID(context.Context, *GetRequest) (*Shop, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetShopByID not implemented")
+
+func (c *marketplaceServiceClient) GetOrder(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Order, error) {
+	out := new(Order)
+	err := c.cc.Invoke(ctx, MarketplaceService_GetOrder_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
-func (UnimplementedMarketplaceServiceServer) GetShopsByServiceableProducts(context.Context, *GetShopsByServiceableProductsRequest) (*Shops, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetShopsByServiceableProducts not implemented")
+
+func (c *marketplaceServiceClient) ListOrdersForUser(ctx context.Context, in *ListOrdersForUserRequest, opts ...grpc.CallOption) (*Orders, error) {
+	out := new(Orders)
+	err := c.cc.Invoke(ctx, MarketplaceService_ListOrdersForUser_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
-func (UnimplementedMarketplaceServiceServer) GetShopForUser(context.Context, *GetShopForUserRequest) (*Shops, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetShopForUser not implemented")
+
+func (c *marketplaceServiceClient) ListOrdersForShop(ctx context.Context, in *ListOrdersForShopRequest, opts ...grpc.CallOption) (*Orders, error) {
+	out := new(Orders)
+	err := c.cc.Invoke(ctx, MarketplaceService_ListOrdersForShop_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
-func (UnimplementedMarketplaceServiceServer) CreateProduct(context.Context, *CreateProductRequest) (*Product, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CreateProduct not implemented")
+
+func (c *marketplaceServiceClient) UpdateOrderStatus(ctx context.Context, in *UpdateOrderStatusRequest, opts ...grpc.CallOption) (*Order, error) {
+	out := new(Order)
+	err := c.cc.Invoke(ctx, MarketplaceService_UpdateOrderStatus_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
-func (UnimplementedMarketplaceServiceServer) GetProductByID(context.Context, *GetRequest) (*Product, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetProductByID not implemented")
+
+func (c *marketplaceServiceClient) AddToCart(ctx context.Context, in *AddToCartRequest, opts ...grpc.CallOption) (*Cart, error) {
+	out := new(Cart)
+	err := c.cc.Invoke(ctx, MarketplaceService_AddToCart_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
-func (UnimplementedMarketplaceServiceServer) UpdateInventory(context.Context, *UpdateInventoryRequest) (*Inventory, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method UpdateInventory not implemented")
+
+func (c *marketplaceServiceClient) RemoveFromCart(ctx context.Context, in *RemoveFromCartRequest, opts ...grpc.CallOption) (*Cart, error) {
+	out := new(Cart)
+	err := c.cc.Invoke(ctx, MarketplaceService_RemoveFromCart_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
-func (UnimplementedMarketplaceServiceServer) GetInventory(context.Context, *GetInventoryRequest) (*Inventory, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetInventory not implemented")
+
+func (c *marketplaceServiceClient) GetCart(ctx context.Context, in *GetCartRequest, opts ...grpc.CallOption) (*Cart, error) {
+	out := new(Cart)
+	err := c.cc.Invoke(ctx, MarketplaceService_GetCart_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
-func (UnimplementedMarketplaceServiceServer) AddServiceableProduct(context.Context, *AddServiceableProductRequest) (*Shop, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method AddServiceableProduct not implemented")
+
+func (c *marketplaceServiceClient) Checkout(ctx context.Context, in *CheckoutRequest, opts ...grpc.CallOption) (*Orders, error) {
+	out := new(Orders)
+	err := c.cc.Invoke(ctx, MarketplaceService_Checkout_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) CreatePromotion(ctx context.Context, in *CreatePromotionRequest, opts ...grpc.CallOption) (*Promotion, error) {
+	out := new(Promotion)
+	err := c.cc.Invoke(ctx, MarketplaceService_CreatePromotion_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) ValidateCoupon(ctx context.Context, in *ValidateCouponRequest, opts ...grpc.CallOption) (*CouponValidation, error) {
+	out := new(CouponValidation)
+	err := c.cc.Invoke(ctx, MarketplaceService_ValidateCoupon_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) SetTaxRule(ctx context.Context, in *TaxRule, opts ...grpc.CallOption) (*TaxRule, error) {
+	out := new(TaxRule)
+	err := c.cc.Invoke(ctx, MarketplaceService_SetTaxRule_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) ListTaxRules(ctx context.Context, in *ListTaxRulesRequest, opts ...grpc.CallOption) (*TaxRules, error) {
+	out := new(TaxRules)
+	err := c.cc.Invoke(ctx, MarketplaceService_ListTaxRules_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) DeleteTaxRule(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*TaxRule, error) {
+	out := new(TaxRule)
+	err := c.cc.Invoke(ctx, MarketplaceService_DeleteTaxRule_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) SchedulePriceChange(ctx context.Context, in *SchedulePriceChangeRequest, opts ...grpc.CallOption) (*ScheduledPriceChange, error) {
+	out := new(ScheduledPriceChange)
+	err := c.cc.Invoke(ctx, MarketplaceService_SchedulePriceChange_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) GetPriceHistory(ctx context.Context, in *GetPriceHistoryRequest, opts ...grpc.CallOption) (*PriceHistory, error) {
+	out := new(PriceHistory)
+	err := c.cc.Invoke(ctx, MarketplaceService_GetPriceHistory_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*AuthTokens, error) {
+	out := new(AuthTokens)
+	err := c.cc.Invoke(ctx, MarketplaceService_Login_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) RefreshToken(ctx context.Context, in *RefreshTokenRequest, opts ...grpc.CallOption) (*AuthTokens, error) {
+	out := new(AuthTokens)
+	err := c.cc.Invoke(ctx, MarketplaceService_RefreshToken_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) SetPassword(ctx context.Context, in *SetPasswordRequest, opts ...grpc.CallOption) (*User, error) {
+	out := new(User)
+	err := c.cc.Invoke(ctx, MarketplaceService_SetPassword_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) InviteStaff(ctx context.Context, in *InviteStaffRequest, opts ...grpc.CallOption) (*StaffInvitation, error) {
+	out := new(StaffInvitation)
+	err := c.cc.Invoke(ctx, MarketplaceService_InviteStaff_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) RevokeStaffInvitation(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*StaffInvitation, error) {
+	out := new(StaffInvitation)
+	err := c.cc.Invoke(ctx, MarketplaceService_RevokeStaffInvitation_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) ListMyInvitations(ctx context.Context, in *ListMyInvitationsRequest, opts ...grpc.CallOption) (*StaffInvitations, error) {
+	out := new(StaffInvitations)
+	err := c.cc.Invoke(ctx, MarketplaceService_ListMyInvitations_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) AcceptStaffInvitation(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*StaffInvitation, error) {
+	out := new(StaffInvitation)
+	err := c.cc.Invoke(ctx, MarketplaceService_AcceptStaffInvitation_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) DeclineStaffInvitation(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*StaffInvitation, error) {
+	out := new(StaffInvitation)
+	err := c.cc.Invoke(ctx, MarketplaceService_DeclineStaffInvitation_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) ListShopStaff(ctx context.Context, in *ListShopStaffRequest, opts ...grpc.CallOption) (*ShopStaffList, error) {
+	out := new(ShopStaffList)
+	err := c.cc.Invoke(ctx, MarketplaceService_ListShopStaff_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) SetStaffPermissions(ctx context.Context, in *SetStaffPermissionsRequest, opts ...grpc.CallOption) (*ShopStaff, error) {
+	out := new(ShopStaff)
+	err := c.cc.Invoke(ctx, MarketplaceService_SetStaffPermissions_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) RemoveStaff(ctx context.Context, in *RemoveStaffRequest, opts ...grpc.CallOption) (*ShopStaff, error) {
+	out := new(ShopStaff)
+	err := c.cc.Invoke(ctx, MarketplaceService_RemoveStaff_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketplaceServiceClient) ListMyShops(ctx context.Context, in *ListMyShopsRequest, opts ...grpc.CallOption) (*MyShops, error) {
+	out := new(MyShops)
+	err := c.cc.Invoke(ctx, MarketplaceService_ListMyShops_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MarketplaceServiceServer is the server API for MarketplaceService service.
+// All implementations must embed UnimplementedMarketplaceServiceServer
+// for forward compatibility
+type MarketplaceServiceServer interface {
+	// Shop-related methods
+	CreateShop(context.Context, *CreateShopRequest) (*Shop, error)
+	GetShopByID(context.Context, *GetRequest) (*Shop, error)
+	GetShopsByServiceableProducts(context.Context, *GetShopsByServiceableProductsRequest) (*Shops, error)
+	GetShopForUser(context.Context, *GetShopForUserRequest) (*Shops, error)
+	SearchNearbyShops(context.Context, *SearchNearbyShopsRequest) (*SearchNearbyShopsResponse, error)
+	SearchShopsInArea(context.Context, *SearchShopsInAreaRequest) (*SearchShopsInAreaResponse, error)
+	SetDeliveryZones(context.Context, *SetDeliveryZonesRequest) (*DeliveryZones, error)
+	SetOperatingHours(context.Context, *SetOperatingHoursRequest) (*Shop, error)
+	IsShopOpen(context.Context, *IsShopOpenRequest) (*ShopOpenStatus, error)
+	GetDeliveryZones(context.Context, *GetDeliveryZonesRequest) (*DeliveryZones, error)
+	// Product-related methods
+	CreateProduct(context.Context, *CreateProductRequest) (*Product, error)
+	GetProductByID(context.Context, *GetRequest) (*Product, error)
+	SetProductCategories(context.Context, *SetProductCategoriesRequest) (*Product, error)
+	SearchProducts(context.Context, *SearchProductsRequest) (*SearchProductsResponse, error)
+	AddProductVariant(context.Context, *AddProductVariantRequest) (*Product, error)
+	// Category-related methods
+	CreateCategory(context.Context, *CreateCategoryRequest) (*Category, error)
+	ListCategories(context.Context, *ListCategoriesRequest) (*Categories, error)
+	ListProductsInCategory(context.Context, *ListProductsInCategoryRequest) (*ListProductsInCategoryResponse, error)
+	// Inventory-related methods
+	UpdateInventory(context.Context, *UpdateInventoryRequest) (*Inventory, error)
+	GetInventory(context.Context, *GetInventoryRequest) (*Inventory, error)
+	ListInventoryMovements(context.Context, *ListInventoryMovementsRequest) (*InventoryMovements, error)
+	GetStockAt(context.Context, *GetStockAtRequest) (*StockLevel, error)
+	TransferStock(context.Context, *TransferStockRequest) (*StockTransfer, error)
+	SetReorderThreshold(context.Context, *SetReorderThresholdRequest) (*Inventory, error)
+	ListLowStock(context.Context, *ListLowStockRequest) (*Inventories, error)
+	ListShopInventory(context.Context, *ListShopInventoryRequest) (*Inventories, error)
+	BulkUpdateInventory(MarketplaceService_BulkUpdateInventoryServer) error
+	// Reservation-related methods
+	ReserveStock(context.Context, *ReserveStockRequest) (*Reservation, error)
+	CommitReservation(context.Context, *GetRequest) (*Reservation, error)
+	ReleaseReservation(context.Context, *GetRequest) (*Reservation, error)
+	// Serviceable products methods
+	AddServiceableProduct(context.Context, *AddServiceableProductRequest) (*Shop, error)
+	GetServiceableProducts(context.Context, *GetServiceableProductsRequest) (*Products, error)
+	SetShopPrice(context.Context, *SetShopPriceRequest) (*ServiceableProduct, error)
+	// User-related methods
+	CreateUser(context.Context, *CreateUserRequest) (*User, error)
+	GetUserByID(context.Context, *GetRequest) (*User, error)
+	SetUserRole(context.Context, *SetUserRoleRequest) (*User, error)
+	// Neighbour-related methods
+	GetNearestNeighbour(context.Context, *GetNearestNeighbourRequest) (*User, error)
+	GetKNearestUsers(context.Context, *KNearestRequest) (*NearbyUsers, error)
+	GetKNearestShops(context.Context, *KNearestRequest) (*NearbyShops, error)
+	// Order-related methods
+	PlaceOrder(context.Context, *PlaceOrderRequest) (*Order, error)
+	GetOrder(context.Context, *GetRequest) (*Order, error)
+	ListOrdersForUser(context.Context, *ListOrdersForUserRequest) (*Orders, error)
+	ListOrdersForShop(context.Context, *ListOrdersForShopRequest) (*Orders, error)
+	UpdateOrderStatus(context.Context, *UpdateOrderStatusRequest) (*Order, error)
+	// Cart-related methods
+	AddToCart(context.Context, *AddToCartRequest) (*Cart, error)
+	RemoveFromCart(context.Context, *RemoveFromCartRequest) (*Cart, error)
+	GetCart(context.Context, *GetCartRequest) (*Cart, error)
+	Checkout(context.Context, *CheckoutRequest) (*Orders, error)
+	// Promotion-related methods
+	CreatePromotion(context.Context, *CreatePromotionRequest) (*Promotion, error)
+	ValidateCoupon(context.Context, *ValidateCouponRequest) (*CouponValidation, error)
+	// Tax-related methods
+	SetTaxRule(context.Context, *TaxRule) (*TaxRule, error)
+	ListTaxRules(context.Context, *ListTaxRulesRequest) (*TaxRules, error)
+	DeleteTaxRule(context.Context, *GetRequest) (*TaxRule, error)
+	// Price-related methods
+	SchedulePriceChange(context.Context, *SchedulePriceChangeRequest) (*ScheduledPriceChange, error)
+	GetPriceHistory(context.Context, *GetPriceHistoryRequest) (*PriceHistory, error)
+	// Auth-related methods
+	Login(context.Context, *LoginRequest) (*AuthTokens, error)
+	RefreshToken(context.Context, *RefreshTokenRequest) (*AuthTokens, error)
+	SetPassword(context.Context, *SetPasswordRequest) (*User, error)
+	// Staff-related methods
+	InviteStaff(context.Context, *InviteStaffRequest) (*StaffInvitation, error)
+	RevokeStaffInvitation(context.Context, *GetRequest) (*StaffInvitation, error)
+	ListMyInvitations(context.Context, *ListMyInvitationsRequest) (*StaffInvitations, error)
+	AcceptStaffInvitation(context.Context, *GetRequest) (*StaffInvitation, error)
+	DeclineStaffInvitation(context.Context, *GetRequest) (*StaffInvitation, error)
+	ListShopStaff(context.Context, *ListShopStaffRequest) (*ShopStaffList, error)
+	SetStaffPermissions(context.Context, *SetStaffPermissionsRequest) (*ShopStaff, error)
+	RemoveStaff(context.Context, *RemoveStaffRequest) (*ShopStaff, error)
+	ListMyShops(context.Context, *ListMyShopsRequest) (*MyShops, error)
+	mustEmbedUnimplementedMarketplaceServiceServer()
+}
+
+// UnimplementedMarketplaceServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedMarketplaceServiceServer struct {
+}
+
+func (UnimplementedMarketplaceServiceServer) CreateShop(context.Context, *CreateShopRequest) (*Shop, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateShop not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) GetShopByID(context.Context, *GetRequest) (*Shop, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetShopByID not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) GetShopsByServiceableProducts(context.Context, *GetShopsByServiceableProductsRequest) (*Shops, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetShopsByServiceableProducts not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) GetShopForUser(context.Context, *GetShopForUserRequest) (*Shops, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetShopForUser not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) SearchNearbyShops(context.Context, *SearchNearbyShopsRequest) (*SearchNearbyShopsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchNearbyShops not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) SearchShopsInArea(context.Context, *SearchShopsInAreaRequest) (*SearchShopsInAreaResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchShopsInArea not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) SetDeliveryZones(context.Context, *SetDeliveryZonesRequest) (*DeliveryZones, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetDeliveryZones not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) SetOperatingHours(context.Context, *SetOperatingHoursRequest) (*Shop, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetOperatingHours not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) IsShopOpen(context.Context, *IsShopOpenRequest) (*ShopOpenStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IsShopOpen not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) GetDeliveryZones(context.Context, *GetDeliveryZonesRequest) (*DeliveryZones, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDeliveryZones not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) CreateProduct(context.Context, *CreateProductRequest) (*Product, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateProduct not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) GetProductByID(context.Context, *GetRequest) (*Product, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetProductByID not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) SetProductCategories(context.Context, *SetProductCategoriesRequest) (*Product, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetProductCategories not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) SearchProducts(context.Context, *SearchProductsRequest) (*SearchProductsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchProducts not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) AddProductVariant(context.Context, *AddProductVariantRequest) (*Product, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddProductVariant not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) CreateCategory(context.Context, *CreateCategoryRequest) (*Category, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateCategory not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) ListCategories(context.Context, *ListCategoriesRequest) (*Categories, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCategories not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) ListProductsInCategory(context.Context, *ListProductsInCategoryRequest) (*ListProductsInCategoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListProductsInCategory not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) UpdateInventory(context.Context, *UpdateInventoryRequest) (*Inventory, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateInventory not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) GetInventory(context.Context, *GetInventoryRequest) (*Inventory, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetInventory not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) ListInventoryMovements(context.Context, *ListInventoryMovementsRequest) (*InventoryMovements, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListInventoryMovements not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) GetStockAt(context.Context, *GetStockAtRequest) (*StockLevel, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStockAt not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) TransferStock(context.Context, *TransferStockRequest) (*StockTransfer, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TransferStock not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) SetReorderThreshold(context.Context, *SetReorderThresholdRequest) (*Inventory, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetReorderThreshold not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) ListLowStock(context.Context, *ListLowStockRequest) (*Inventories, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListLowStock not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) ListShopInventory(context.Context, *ListShopInventoryRequest) (*Inventories, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListShopInventory not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) BulkUpdateInventory(MarketplaceService_BulkUpdateInventoryServer) error {
+	return status.Errorf(codes.Unimplemented, "method BulkUpdateInventory not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) ReserveStock(context.Context, *ReserveStockRequest) (*Reservation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReserveStock not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) CommitReservation(context.Context, *GetRequest) (*Reservation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CommitReservation not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) ReleaseReservation(context.Context, *GetRequest) (*Reservation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReleaseReservation not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) AddServiceableProduct(context.Context, *AddServiceableProductRequest) (*Shop, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddServiceableProduct not implemented")
 }
 func (UnimplementedMarketplaceServiceServer) GetServiceableProducts(context.Context, *GetServiceableProductsRequest) (*Products, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetServiceableProducts not implemented")
 }
-func (UnimplementedMarketplaceServiceServer) CreateUser(context.Context, *CreateUserRequest) (*User, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CreateUser not implemented")
+func (UnimplementedMarketplaceServiceServer) SetShopPrice(context.Context, *SetShopPriceRequest) (*ServiceableProduct, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetShopPrice not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) CreateUser(context.Context, *CreateUserRequest) (*User, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateUser not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) GetUserByID(context.Context, *GetRequest) (*User, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUserByID not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) SetUserRole(context.Context, *SetUserRoleRequest) (*User, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetUserRole not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) GetNearestNeighbour(context.Context, *GetNearestNeighbourRequest) (*User, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNearestNeighbour not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) GetKNearestUsers(context.Context, *KNearestRequest) (*NearbyUsers, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetKNearestUsers not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) GetKNearestShops(context.Context, *KNearestRequest) (*NearbyShops, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetKNearestShops not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) PlaceOrder(context.Context, *PlaceOrderRequest) (*Order, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PlaceOrder not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) GetOrder(context.Context, *GetRequest) (*Order, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOrder not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) ListOrdersForUser(context.Context, *ListOrdersForUserRequest) (*Orders, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListOrdersForUser not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) ListOrdersForShop(context.Context, *ListOrdersForShopRequest) (*Orders, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListOrdersForShop not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) UpdateOrderStatus(context.Context, *UpdateOrderStatusRequest) (*Order, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateOrderStatus not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) AddToCart(context.Context, *AddToCartRequest) (*Cart, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddToCart not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) RemoveFromCart(context.Context, *RemoveFromCartRequest) (*Cart, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveFromCart not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) GetCart(context.Context, *GetCartRequest) (*Cart, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCart not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) Checkout(context.Context, *CheckoutRequest) (*Orders, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Checkout not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) CreatePromotion(context.Context, *CreatePromotionRequest) (*Promotion, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreatePromotion not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) ValidateCoupon(context.Context, *ValidateCouponRequest) (*CouponValidation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ValidateCoupon not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) SetTaxRule(context.Context, *TaxRule) (*TaxRule, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetTaxRule not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) ListTaxRules(context.Context, *ListTaxRulesRequest) (*TaxRules, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTaxRules not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) DeleteTaxRule(context.Context, *GetRequest) (*TaxRule, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteTaxRule not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) SchedulePriceChange(context.Context, *SchedulePriceChangeRequest) (*ScheduledPriceChange, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SchedulePriceChange not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) GetPriceHistory(context.Context, *GetPriceHistoryRequest) (*PriceHistory, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPriceHistory not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) Login(context.Context, *LoginRequest) (*AuthTokens, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Login not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) RefreshToken(context.Context, *RefreshTokenRequest) (*AuthTokens, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RefreshToken not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) SetPassword(context.Context, *SetPasswordRequest) (*User, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetPassword not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) InviteStaff(context.Context, *InviteStaffRequest) (*StaffInvitation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InviteStaff not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) RevokeStaffInvitation(context.Context, *GetRequest) (*StaffInvitation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeStaffInvitation not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) ListMyInvitations(context.Context, *ListMyInvitationsRequest) (*StaffInvitations, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListMyInvitations not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) AcceptStaffInvitation(context.Context, *GetRequest) (*StaffInvitation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AcceptStaffInvitation not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) DeclineStaffInvitation(context.Context, *GetRequest) (*StaffInvitation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeclineStaffInvitation not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) ListShopStaff(context.Context, *ListShopStaffRequest) (*ShopStaffList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListShopStaff not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) SetStaffPermissions(context.Context, *SetStaffPermissionsRequest) (*ShopStaff, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetStaffPermissions not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) RemoveStaff(context.Context, *RemoveStaffRequest) (*ShopStaff, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveStaff not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) ListMyShops(context.Context, *ListMyShopsRequest) (*MyShops, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListMyShops not implemented")
+}
+func (UnimplementedMarketplaceServiceServer) mustEmbedUnimplementedMarketplaceServiceServer() {}
+
+// UnsafeMarketplaceServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MarketplaceServiceServer will
+// result in compilation errors.
+type UnsafeMarketplaceServiceServer interface {
+	mustEmbedUnimplementedMarketplaceServiceServer()
+}
+
+func RegisterMarketplaceServiceServer(s grpc.ServiceRegistrar, srv MarketplaceServiceServer) {
+	s.RegisterService(&MarketplaceService_ServiceDesc, srv)
+}
+
+func _MarketplaceService_CreateShop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateShopRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).CreateShop(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_CreateShop_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).CreateShop(ctx, req.(*CreateShopRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_GetShopByID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).GetShopByID(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_GetShopByID_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).GetShopByID(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_GetShopsByServiceableProducts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetShopsByServiceableProductsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).GetShopsByServiceableProducts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_GetShopsByServiceableProducts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).GetShopsByServiceableProducts(ctx, req.(*GetShopsByServiceableProductsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_GetShopForUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetShopForUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).GetShopForUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_GetShopForUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).GetShopForUser(ctx, req.(*GetShopForUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_SearchNearbyShops_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchNearbyShopsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).SearchNearbyShops(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_SearchNearbyShops_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).SearchNearbyShops(ctx, req.(*SearchNearbyShopsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_SearchShopsInArea_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchShopsInAreaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).SearchShopsInArea(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_SearchShopsInArea_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).SearchShopsInArea(ctx, req.(*SearchShopsInAreaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_SetDeliveryZones_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetDeliveryZonesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).SetDeliveryZones(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_SetDeliveryZones_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).SetDeliveryZones(ctx, req.(*SetDeliveryZonesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_SetOperatingHours_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetOperatingHoursRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).SetOperatingHours(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_SetOperatingHours_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).SetOperatingHours(ctx, req.(*SetOperatingHoursRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_IsShopOpen_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IsShopOpenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).IsShopOpen(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_IsShopOpen_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).IsShopOpen(ctx, req.(*IsShopOpenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_GetDeliveryZones_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDeliveryZonesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).GetDeliveryZones(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_GetDeliveryZones_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).GetDeliveryZones(ctx, req.(*GetDeliveryZonesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_CreateProduct_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateProductRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).CreateProduct(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_CreateProduct_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).CreateProduct(ctx, req.(*CreateProductRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_GetProductByID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).GetProductByID(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_GetProductByID_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).GetProductByID(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_SetProductCategories_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetProductCategoriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).SetProductCategories(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_SetProductCategories_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).SetProductCategories(ctx, req.(*SetProductCategoriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_SearchProducts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchProductsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).SearchProducts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_SearchProducts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).SearchProducts(ctx, req.(*SearchProductsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_AddProductVariant_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddProductVariantRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).AddProductVariant(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_AddProductVariant_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).AddProductVariant(ctx, req.(*AddProductVariantRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_CreateCategory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateCategoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).CreateCategory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_CreateCategory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).CreateCategory(ctx, req.(*CreateCategoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_ListCategories_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCategoriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).ListCategories(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_ListCategories_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).ListCategories(ctx, req.(*ListCategoriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_ListProductsInCategory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListProductsInCategoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).ListProductsInCategory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_ListProductsInCategory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).ListProductsInCategory(ctx, req.(*ListProductsInCategoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_UpdateInventory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateInventoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).UpdateInventory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_UpdateInventory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).UpdateInventory(ctx, req.(*UpdateInventoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_GetInventory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetInventoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).GetInventory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_GetInventory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).GetInventory(ctx, req.(*GetInventoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_ListInventoryMovements_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListInventoryMovementsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).ListInventoryMovements(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_ListInventoryMovements_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).ListInventoryMovements(ctx, req.(*ListInventoryMovementsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_GetStockAt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStockAtRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).GetStockAt(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_GetStockAt_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).GetStockAt(ctx, req.(*GetStockAtRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_TransferStock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransferStockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).TransferStock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_TransferStock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).TransferStock(ctx, req.(*TransferStockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_SetReorderThreshold_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetReorderThresholdRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).SetReorderThreshold(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_SetReorderThreshold_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).SetReorderThreshold(ctx, req.(*SetReorderThresholdRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_ListLowStock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListLowStockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).ListLowStock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_ListLowStock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).ListLowStock(ctx, req.(*ListLowStockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_ListShopInventory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListShopInventoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).ListShopInventory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_ListShopInventory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).ListShopInventory(ctx, req.(*ListShopInventoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_BulkUpdateInventory_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(MarketplaceServiceServer).BulkUpdateInventory(&marketplaceServiceBulkUpdateInventoryServer{stream})
+}
+
+type MarketplaceService_BulkUpdateInventoryServer interface {
+	SendAndClose(*BulkInventoryReport) error
+	Recv() (*BulkInventoryRow, error)
+	grpc.ServerStream
+}
+
+type marketplaceServiceBulkUpdateInventoryServer struct {
+	grpc.ServerStream
+}
+
+func (x *marketplaceServiceBulkUpdateInventoryServer) SendAndClose(m *BulkInventoryReport) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *marketplaceServiceBulkUpdateInventoryServer) Recv() (*BulkInventoryRow, error) {
+	m := new(BulkInventoryRow)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _MarketplaceService_ReserveStock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReserveStockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).ReserveStock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_ReserveStock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).ReserveStock(ctx, req.(*ReserveStockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_CommitReservation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).CommitReservation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_CommitReservation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).CommitReservation(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_ReleaseReservation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).ReleaseReservation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_ReleaseReservation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).ReleaseReservation(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_AddServiceableProduct_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddServiceableProductRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).AddServiceableProduct(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_AddServiceableProduct_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).AddServiceableProduct(ctx, req.(*AddServiceableProductRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_GetServiceableProducts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetServiceableProductsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).GetServiceableProducts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_GetServiceableProducts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).GetServiceableProducts(ctx, req.(*GetServiceableProductsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_SetShopPrice_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetShopPriceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).SetShopPrice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_SetShopPrice_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).SetShopPrice(ctx, req.(*SetShopPriceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_CreateUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).CreateUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_CreateUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).CreateUser(ctx, req.(*CreateUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_GetUserByID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).GetUserByID(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_GetUserByID_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).GetUserByID(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_SetUserRole_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetUserRoleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).SetUserRole(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_SetUserRole_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).SetUserRole(ctx, req.(*SetUserRoleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_GetNearestNeighbour_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetNearestNeighbourRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).GetNearestNeighbour(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_GetNearestNeighbour_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).GetNearestNeighbour(ctx, req.(*GetNearestNeighbourRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_GetKNearestUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KNearestRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).GetKNearestUsers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_GetKNearestUsers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).GetKNearestUsers(ctx, req.(*KNearestRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_GetKNearestShops_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KNearestRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).GetKNearestShops(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_GetKNearestShops_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).GetKNearestShops(ctx, req.(*KNearestRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_PlaceOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PlaceOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).PlaceOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_PlaceOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).PlaceOrder(ctx, req.(*PlaceOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_GetOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).GetOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_GetOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).GetOrder(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_ListOrdersForUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListOrdersForUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).ListOrdersForUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_ListOrdersForUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).ListOrdersForUser(ctx, req.(*ListOrdersForUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_ListOrdersForShop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListOrdersForShopRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).ListOrdersForShop(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_ListOrdersForShop_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).ListOrdersForShop(ctx, req.(*ListOrdersForShopRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_UpdateOrderStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateOrderStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).UpdateOrderStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_UpdateOrderStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).UpdateOrderStatus(ctx, req.(*UpdateOrderStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_AddToCart_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddToCartRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).AddToCart(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_AddToCart_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).AddToCart(ctx, req.(*AddToCartRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_RemoveFromCart_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveFromCartRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).RemoveFromCart(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_RemoveFromCart_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).RemoveFromCart(ctx, req.(*RemoveFromCartRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_GetCart_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCartRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).GetCart(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_GetCart_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).GetCart(ctx, req.(*GetCartRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_Checkout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckoutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).Checkout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_Checkout_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).Checkout(ctx, req.(*CheckoutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_CreatePromotion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreatePromotionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).CreatePromotion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_CreatePromotion_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).CreatePromotion(ctx, req.(*CreatePromotionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketplaceService_ValidateCoupon_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidateCouponRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).ValidateCoupon(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_ValidateCoupon_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).ValidateCoupon(ctx, req.(*ValidateCouponRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedMarketplaceServiceServer) GetUserByID(context.Context, *GetRequest) (*User, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetUserByID not implemented")
+
+func _MarketplaceService_SetTaxRule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TaxRule)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).SetTaxRule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_SetTaxRule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).SetTaxRule(ctx, req.(*TaxRule))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedMarketplaceServiceServer) GetNearestNeighbour(context.Context, *GetNearestNeighbourRequest) (*User, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetNearestNeighbour not implemented")
+
+func _MarketplaceService_ListTaxRules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTaxRulesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).ListTaxRules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_ListTaxRules_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).ListTaxRules(ctx, req.(*ListTaxRulesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedMarketplaceServiceServer) mustEmbedUnimplementedMarketplaceServiceServer() {}
 
-// UnsafeMarketplaceServiceServer may be embedded to opt out of forward compatibility for this service.
-// Use of this interface is not recommended, as added methods to MarketplaceServiceServer will
-// result in compilation errors.
-type UnsafeMarketplaceServiceServer interface {
-	mustEmbedUnimplementedMarketplaceServiceServer()
+func _MarketplaceService_DeleteTaxRule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).DeleteTaxRule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_DeleteTaxRule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).DeleteTaxRule(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func RegisterMarketplaceServiceServer(s grpc.ServiceRegistrar, srv MarketplaceServiceServer) {
-	s.RegisterService(&MarketplaceService_ServiceDesc, srv)
+func _MarketplaceService_SchedulePriceChange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SchedulePriceChangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketplaceServiceServer).SchedulePriceChange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketplaceService_SchedulePriceChange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketplaceServiceServer).SchedulePriceChange(ctx, req.(*SchedulePriceChangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func _MarketplaceService_CreateShop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CreateShopRequest)
+func _MarketplaceService_GetPriceHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPriceHistoryRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(MarketplaceServiceServer).CreateShop(ctx, in)
+		return srv.(MarketplaceServiceServer).GetPriceHistory(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: MarketplaceService_CreateShop_FullMethodName,
+		FullMethod: MarketplaceService_GetPriceHistory_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MarketplaceServiceServer).CreateShop(ctx, req.(*CreateShopRequest))
+		return srv.(MarketplaceServiceServer).GetPriceHistory(ctx, req.(*GetPriceHistoryRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _MarketplaceService_GetShopByID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetRequest)
+func _MarketplaceService_Login_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LoginRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(MarketplaceServiceServer).GetShopByID(ctx, in)
+		return srv.(MarketplaceServiceServer).Login(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: MarketplaceService_GetShopByID_FullMethodName,
+		FullMethod: MarketplaceService_Login_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MarketplaceServiceServer).GetShopByID(ctx, req.(*GetRequest))
+		return srv.(MarketplaceServiceServer).Login(ctx, req.(*LoginRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _MarketplaceService_GetShopsByServiceableProducts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetShopsByServiceableProductsRequest)
+func _MarketplaceService_RefreshToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RefreshTokenRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(MarketplaceServiceServer).GetShopsByServiceableProducts(ctx, in)
+		return srv.(MarketplaceServiceServer).RefreshToken(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: MarketplaceService_GetShopsByServiceableProducts_FullMethodName,
+		FullMethod: MarketplaceService_RefreshToken_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MarketplaceServiceServer).GetShopsByServiceableProducts(ctx, req.(*GetShopsByServiceableProductsRequest))
+		return srv.(MarketplaceServiceServer).RefreshToken(ctx, req.(*RefreshTokenRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _MarketplaceService_GetShopForUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetShopForUserRequest)
+func _MarketplaceService_SetPassword_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetPasswordRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(MarketplaceServiceServer).GetShopForUser(ctx, in)
+		return srv.(MarketplaceServiceServer).SetPassword(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: MarketplaceService_GetShopForUser_FullMethodName,
+		FullMethod: MarketplaceService_SetPassword_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MarketplaceServiceServer).GetShopForUser(ctx, req.(*GetShopForUserRequest))
+		return srv.(MarketplaceServiceServer).SetPassword(ctx, req.(*SetPasswordRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _MarketplaceService_CreateProduct_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CreateProductRequest)
+func _MarketplaceService_InviteStaff_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InviteStaffRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(MarketplaceServiceServer).CreateProduct(ctx, in)
+		return srv.(MarketplaceServiceServer).InviteStaff(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: MarketplaceService_CreateProduct_FullMethodName,
+		FullMethod: MarketplaceService_InviteStaff_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MarketplaceServiceServer).CreateProduct(ctx, req.(*CreateProductRequest))
+		return srv.(MarketplaceServiceServer).InviteStaff(ctx, req.(*InviteStaffRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _MarketplaceService_GetProductByID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+func _MarketplaceService_RevokeStaffInvitation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(MarketplaceServiceServer).GetProductByID(ctx, in)
+		return srv.(MarketplaceServiceServer).RevokeStaffInvitation(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: MarketplaceService_GetProductByID_FullMethodName,
+		FullMethod: MarketplaceService_RevokeStaffInvitation_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MarketplaceServiceServer).GetProductByID(ctx, req.(*GetRequest))
+		return srv.(MarketplaceServiceServer).RevokeStaffInvitation(ctx, req.(*GetRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _MarketplaceService_UpdateInventory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(UpdateInventoryRequest)
+func _MarketplaceService_ListMyInvitations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListMyInvitationsRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(MarketplaceServiceServer).UpdateInventory(ctx, in)
+		return srv.(MarketplaceServiceServer).ListMyInvitations(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: MarketplaceService_UpdateInventory_FullMethodName,
+		FullMethod: MarketplaceService_ListMyInvitations_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MarketplaceServiceServer).UpdateInventory(ctx, req.(*UpdateInventoryRequest))
+		return srv.(MarketplaceServiceServer).ListMyInvitations(ctx, req.(*ListMyInvitationsRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _MarketplaceService_GetInventory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetInventoryRequest)
+func _MarketplaceService_AcceptStaffInvitation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(MarketplaceServiceServer).GetInventory(ctx, in)
+		return srv.(MarketplaceServiceServer).AcceptStaffInvitation(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: MarketplaceService_GetInventory_FullMethodName,
+		FullMethod: MarketplaceService_AcceptStaffInvitation_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MarketplaceServiceServer).GetInventory(ctx, req.(*GetInventoryRequest))
+		return srv.(MarketplaceServiceServer).AcceptStaffInvitation(ctx, req.(*GetRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _MarketplaceService_AddServiceableProduct_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(AddServiceableProductRequest)
+func _MarketplaceService_DeclineStaffInvitation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(MarketplaceServiceServer).AddServiceableProduct(ctx, in)
+		return srv.(MarketplaceServiceServer).DeclineStaffInvitation(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: MarketplaceService_AddServiceableProduct_FullMethodName,
+		FullMethod: MarketplaceService_DeclineStaffInvitation_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MarketplaceServiceServer).AddServiceableProduct(ctx, req.(*AddServiceableProductRequest))
+		return srv.(MarketplaceServiceServer).DeclineStaffInvitation(ctx, req.(*GetRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _MarketplaceService_GetServiceableProducts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetServiceableProductsRequest)
+func _MarketplaceService_ListShopStaff_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListShopStaffRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(MarketplaceServiceServer).GetServiceableProducts(ctx, in)
+		return srv.(MarketplaceServiceServer).ListShopStaff(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: MarketplaceService_GetServiceableProducts_FullMethodName,
+		FullMethod: MarketplaceService_ListShopStaff_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MarketplaceServiceServer).GetServiceableProducts(ctx, req.(*GetServiceableProductsRequest))
+		return srv.(MarketplaceServiceServer).ListShopStaff(ctx, req.(*ListShopStaffRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _MarketplaceService_CreateUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CreateUserRequest)
+func _MarketplaceService_SetStaffPermissions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetStaffPermissionsRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(MarketplaceServiceServer).CreateUser(ctx, in)
+		return srv.(MarketplaceServiceServer).SetStaffPermissions(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: MarketplaceService_CreateUser_FullMethodName,
+		FullMethod: MarketplaceService_SetStaffPermissions_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MarketplaceServiceServer).CreateUser(ctx, req.(*CreateUserRequest))
+		return srv.(MarketplaceServiceServer).SetStaffPermissions(ctx, req.(*SetStaffPermissionsRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _MarketplaceService_GetUserByID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetRequest)
+func _MarketplaceService_RemoveStaff_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveStaffRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(MarketplaceServiceServer).GetUserByID(ctx, in)
+		return srv.(MarketplaceServiceServer).RemoveStaff(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: MarketplaceService_GetUserByID_FullMethodName,
+		FullMethod: MarketplaceService_RemoveStaff_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MarketplaceServiceServer).GetUserByID(ctx, req.(*GetRequest))
+		return srv.(MarketplaceServiceServer).RemoveStaff(ctx, req.(*RemoveStaffRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _MarketplaceService_GetNearestNeighbour_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetNearestNeighbourRequest)
+func _MarketplaceService_ListMyShops_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListMyShopsRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(MarketplaceServiceServer).GetNearestNeighbour(ctx, in)
+		return srv.(MarketplaceServiceServer).ListMyShops(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: MarketplaceService_GetNearestNeighbour_FullMethodName,
+		FullMethod: MarketplaceService_ListMyShops_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MarketplaceServiceServer).GetNearestNeighbour(ctx, req.(*GetNearestNeighbourRequest))
+		return srv.(MarketplaceServiceServer).ListMyShops(ctx, req.(*ListMyShopsRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -523,6 +2356,30 @@ var MarketplaceService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetShopForUser",
 			Handler:    _MarketplaceService_GetShopForUser_Handler,
 		},
+		{
+			MethodName: "SearchNearbyShops",
+			Handler:    _MarketplaceService_SearchNearbyShops_Handler,
+		},
+		{
+			MethodName: "SearchShopsInArea",
+			Handler:    _MarketplaceService_SearchShopsInArea_Handler,
+		},
+		{
+			MethodName: "SetDeliveryZones",
+			Handler:    _MarketplaceService_SetDeliveryZones_Handler,
+		},
+		{
+			MethodName: "SetOperatingHours",
+			Handler:    _MarketplaceService_SetOperatingHours_Handler,
+		},
+		{
+			MethodName: "IsShopOpen",
+			Handler:    _MarketplaceService_IsShopOpen_Handler,
+		},
+		{
+			MethodName: "GetDeliveryZones",
+			Handler:    _MarketplaceService_GetDeliveryZones_Handler,
+		},
 		{
 			MethodName: "CreateProduct",
 			Handler:    _MarketplaceService_CreateProduct_Handler,
@@ -531,6 +2388,30 @@ var MarketplaceService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetProductByID",
 			Handler:    _MarketplaceService_GetProductByID_Handler,
 		},
+		{
+			MethodName: "SetProductCategories",
+			Handler:    _MarketplaceService_SetProductCategories_Handler,
+		},
+		{
+			MethodName: "SearchProducts",
+			Handler:    _MarketplaceService_SearchProducts_Handler,
+		},
+		{
+			MethodName: "AddProductVariant",
+			Handler:    _MarketplaceService_AddProductVariant_Handler,
+		},
+		{
+			MethodName: "CreateCategory",
+			Handler:    _MarketplaceService_CreateCategory_Handler,
+		},
+		{
+			MethodName: "ListCategories",
+			Handler:    _MarketplaceService_ListCategories_Handler,
+		},
+		{
+			MethodName: "ListProductsInCategory",
+			Handler:    _MarketplaceService_ListProductsInCategory_Handler,
+		},
 		{
 			MethodName: "UpdateInventory",
 			Handler:    _MarketplaceService_UpdateInventory_Handler,
@@ -539,6 +2420,42 @@ var MarketplaceService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetInventory",
 			Handler:    _MarketplaceService_GetInventory_Handler,
 		},
+		{
+			MethodName: "ListInventoryMovements",
+			Handler:    _MarketplaceService_ListInventoryMovements_Handler,
+		},
+		{
+			MethodName: "GetStockAt",
+			Handler:    _MarketplaceService_GetStockAt_Handler,
+		},
+		{
+			MethodName: "TransferStock",
+			Handler:    _MarketplaceService_TransferStock_Handler,
+		},
+		{
+			MethodName: "SetReorderThreshold",
+			Handler:    _MarketplaceService_SetReorderThreshold_Handler,
+		},
+		{
+			MethodName: "ListLowStock",
+			Handler:    _MarketplaceService_ListLowStock_Handler,
+		},
+		{
+			MethodName: "ListShopInventory",
+			Handler:    _MarketplaceService_ListShopInventory_Handler,
+		},
+		{
+			MethodName: "ReserveStock",
+			Handler:    _MarketplaceService_ReserveStock_Handler,
+		},
+		{
+			MethodName: "CommitReservation",
+			Handler:    _MarketplaceService_CommitReservation_Handler,
+		},
+		{
+			MethodName: "ReleaseReservation",
+			Handler:    _MarketplaceService_ReleaseReservation_Handler,
+		},
 		{
 			MethodName: "AddServiceableProduct",
 			Handler:    _MarketplaceService_AddServiceableProduct_Handler,
@@ -547,6 +2464,10 @@ var MarketplaceService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetServiceableProducts",
 			Handler:    _MarketplaceService_GetServiceableProducts_Handler,
 		},
+		{
+			MethodName: "SetShopPrice",
+			Handler:    _MarketplaceService_SetShopPrice_Handler,
+		},
 		{
 			MethodName: "CreateUser",
 			Handler:    _MarketplaceService_CreateUser_Handler,
@@ -555,11 +2476,141 @@ var MarketplaceService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetUserByID",
 			Handler:    _MarketplaceService_GetUserByID_Handler,
 		},
+		{
+			MethodName: "SetUserRole",
+			Handler:    _MarketplaceService_SetUserRole_Handler,
+		},
 		{
 			MethodName: "GetNearestNeighbour",
 			Handler:    _MarketplaceService_GetNearestNeighbour_Handler,
 		},
+		{
+			MethodName: "GetKNearestUsers",
+			Handler:    _MarketplaceService_GetKNearestUsers_Handler,
+		},
+		{
+			MethodName: "GetKNearestShops",
+			Handler:    _MarketplaceService_GetKNearestShops_Handler,
+		},
+		{
+			MethodName: "PlaceOrder",
+			Handler:    _MarketplaceService_PlaceOrder_Handler,
+		},
+		{
+			MethodName: "GetOrder",
+			Handler:    _MarketplaceService_GetOrder_Handler,
+		},
+		{
+			MethodName: "ListOrdersForUser",
+			Handler:    _MarketplaceService_ListOrdersForUser_Handler,
+		},
+		{
+			MethodName: "ListOrdersForShop",
+			Handler:    _MarketplaceService_ListOrdersForShop_Handler,
+		},
+		{
+			MethodName: "UpdateOrderStatus",
+			Handler:    _MarketplaceService_UpdateOrderStatus_Handler,
+		},
+		{
+			MethodName: "AddToCart",
+			Handler:    _MarketplaceService_AddToCart_Handler,
+		},
+		{
+			MethodName: "RemoveFromCart",
+			Handler:    _MarketplaceService_RemoveFromCart_Handler,
+		},
+		{
+			MethodName: "GetCart",
+			Handler:    _MarketplaceService_GetCart_Handler,
+		},
+		{
+			MethodName: "Checkout",
+			Handler:    _MarketplaceService_Checkout_Handler,
+		},
+		{
+			MethodName: "CreatePromotion",
+			Handler:    _MarketplaceService_CreatePromotion_Handler,
+		},
+		{
+			MethodName: "ValidateCoupon",
+			Handler:    _MarketplaceService_ValidateCoupon_Handler,
+		},
+		{
+			MethodName: "SetTaxRule",
+			Handler:    _MarketplaceService_SetTaxRule_Handler,
+		},
+		{
+			MethodName: "ListTaxRules",
+			Handler:    _MarketplaceService_ListTaxRules_Handler,
+		},
+		{
+			MethodName: "DeleteTaxRule",
+			Handler:    _MarketplaceService_DeleteTaxRule_Handler,
+		},
+		{
+			MethodName: "SchedulePriceChange",
+			Handler:    _MarketplaceService_SchedulePriceChange_Handler,
+		},
+		{
+			MethodName: "GetPriceHistory",
+			Handler:    _MarketplaceService_GetPriceHistory_Handler,
+		},
+		{
+			MethodName: "Login",
+			Handler:    _MarketplaceService_Login_Handler,
+		},
+		{
+			MethodName: "RefreshToken",
+			Handler:    _MarketplaceService_RefreshToken_Handler,
+		},
+		{
+			MethodName: "SetPassword",
+			Handler:    _MarketplaceService_SetPassword_Handler,
+		},
+		{
+			MethodName: "InviteStaff",
+			Handler:    _MarketplaceService_InviteStaff_Handler,
+		},
+		{
+			MethodName: "RevokeStaffInvitation",
+			Handler:    _MarketplaceService_RevokeStaffInvitation_Handler,
+		},
+		{
+			MethodName: "ListMyInvitations",
+			Handler:    _MarketplaceService_ListMyInvitations_Handler,
+		},
+		{
+			MethodName: "AcceptStaffInvitation",
+			Handler:    _MarketplaceService_AcceptStaffInvitation_Handler,
+		},
+		{
+			MethodName: "DeclineStaffInvitation",
+			Handler:    _MarketplaceService_DeclineStaffInvitation_Handler,
+		},
+		{
+			MethodName: "ListShopStaff",
+			Handler:    _MarketplaceService_ListShopStaff_Handler,
+		},
+		{
+			MethodName: "SetStaffPermissions",
+			Handler:    _MarketplaceService_SetStaffPermissions_Handler,
+		},
+		{
+			MethodName: "RemoveStaff",
+			Handler:    _MarketplaceService_RemoveStaff_Handler,
+		},
+		{
+			MethodName: "ListMyShops",
+			Handler:    _MarketplaceService_ListMyShops_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "BulkUpdateInventory",
+			Handler:       _MarketplaceService_BulkUpdateInventory_Handler,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/service.proto",
 }
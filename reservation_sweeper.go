@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	defaultReservationTTL    = 15 * time.Minute
+	reservationSweepInterval = time.Minute
+)
+
+// sweepExpiredReservations periodically releases active reservations whose
+// expiry has passed, making their stock available again.
+func (s *GRPCMarketPlaceServer) sweepExpiredReservations(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		filter := bson.M{
+			"status":     ReservationStatusActive,
+			"expires_at": bson.M{"$lt": time.Now().Unix()},
+		}
+
+		expired, err := getItemOrError(s.svc.reservationRepo.Find(filter, nil, 0, 0))
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			continue
+		}
+
+		for _, reservation := range expired {
+			_, err := s.settleReservation(context.Background(), reservation.ID, ReservationStatusReleased)
+			if err != nil {
+				s.svc.logger.Printf("Error: failed to release reservation[%s]: %v", reservation.ID, err)
+				continue
+			}
+			s.svc.logger.Printf("released expired reservation[%s]", reservation.ID)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/NikhilSharmaWe/marketplace/proto"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// priceOf returns the price a shop sells a variant of the product at: its own
+// price for the variant, then its price for the product, then the catalog
+// price. sp may be nil for shops that have not priced the product.
+func (sp *ServiceableProduct) priceOf(product *Product, variantId string) Money {
+	if sp != nil {
+		if price, ok := sp.VariantPrices[variantId]; ok && variantId != "" {
+			return price
+		}
+		if sp.Price != nil {
+			return *sp.Price
+		}
+	}
+	return product.priceOf(variantId)
+}
+
+// applyTo replaces the catalog prices of product with the prices of the shop.
+func (sp *ServiceableProduct) applyTo(product *Product) {
+	for i := range product.Variants {
+		product.Variants[i].Price = sp.priceOf(product, product.Variants[i].ID)
+	}
+	product.Price = sp.priceOf(product, "")
+}
+
+// shopPrices returns the prices a shop has set, keyed by product id.
+func (s *GRPCMarketPlaceServer) shopPrices(shopId string) (map[string]*ServiceableProduct, error) {
+	serviceables, err := getItemOrError(s.svc.serviceableRepo.Find(bson.M{"shop_id": shopId}, nil, 0, 0))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to get shop prices")
+	}
+
+	prices := make(map[string]*ServiceableProduct)
+	for i := range serviceables {
+		prices[serviceables[i].ProductID] = &serviceables[i]
+	}
+	return prices, nil
+}
+
+func parseServiceableProduct(sp *ServiceableProduct) *proto.ServiceableProduct {
+	psp := &proto.ServiceableProduct{
+		Id:        sp.ID,
+		ProductId: sp.ProductID,
+		ShopId:    sp.ShopID,
+	}
+
+	if sp.Price != nil {
+		psp.Price = parseMoney(*sp.Price)
+	}
+
+	if len(sp.VariantPrices) > 0 {
+		psp.VariantPrices = make(map[string]*proto.Money)
+		for variantId, price := range sp.VariantPrices {
+			psp.VariantPrices[variantId] = parseMoney(price)
+		}
+	}
+
+	return psp
+}
@@ -0,0 +1,522 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/NikhilSharmaWe/marketplace/proto"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const staffInvitationTTL = 7 * 24 * time.Hour
+
+// errIndexNotFoundCode is returned by mongo when dropping an index that does
+// not exist.
+const errIndexNotFoundCode = 27
+
+var staffPermissions = []string{PermissionManageInventory, PermissionManageCatalog, PermissionManageOrders}
+
+// setupStaffIndexes lets the shops of a merchant be listed and keeps a single
+// pending invitation per shop and user. Invitations used to be accepted by
+// whoever logged in with their email, so the pending ones not bound to a user
+// are revoked.
+func (app *application) setupStaffIndexes() error {
+	_, err := getCollection(&app.shopRepo.AbstractRepository).Indexes().CreateMany(app.ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "owner_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "staff.user_id", Value: 1}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	collection := getCollection(&app.invitationRepo.AbstractRepository)
+	_, err = collection.UpdateMany(app.ctx,
+		bson.M{"status": InvitationStatusPending, "user_id": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"status": InvitationStatusRevoked, "respondedOn": time.Now().Unix()}},
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range []string{"shop_id_1_email_1", "email_1_status_1"} {
+		_, err = collection.Indexes().DropOne(app.ctx, name)
+		var cmdErr mongo.CommandError
+		if err != nil && !(errors.As(err, &cmdErr) && cmdErr.Code == errIndexNotFoundCode) {
+			return err
+		}
+	}
+
+	_, err = collection.Indexes().CreateMany(app.ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "shop_id", Value: 1}, {Key: "user_id", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+				"status": InvitationStatusPending,
+			}),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "status", Value: 1}},
+		},
+	})
+	return err
+}
+
+func (shop *Shop) staffMember(userId string) *ShopStaff {
+	for i := range shop.Staff {
+		if shop.Staff[i].UserID == userId {
+			return &shop.Staff[i]
+		}
+	}
+	return nil
+}
+
+func hasPermission(permissions []string, permission string) bool {
+	for _, p := range permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// newPermissions validates the permissions given to a staff member, of which
+// there must be at least one.
+func newPermissions(permissions []string) ([]string, error) {
+	permissions = uniqueStrings(permissions)
+	if len(permissions) == 0 {
+		return nil, errors.New("at least one permission is required")
+	}
+
+	for _, permission := range permissions {
+		if !hasPermission(staffPermissions, permission) {
+			return nil, fmt.Errorf("unknown permission %q", permission)
+		}
+	}
+	return permissions, nil
+}
+
+// InviteStaff invites the user with the given email to the staff of a shop.
+// The invitation is bound to the user having the email when it is sent, since
+// emails are not verified, so users have to sign up before being invited.
+// Inviting a user again renews the pending invitation with the new
+// permissions.
+func (s *GRPCMarketPlaceServer) InviteStaff(ctx context.Context, req *proto.InviteStaffRequest) (*proto.StaffInvitation, error) {
+	err := s.authorizeShopOwner(ctx, req.ShopId)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := newPermissions(req.Permissions)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	email := normalizeEmail(req.Email)
+	if _, err := mail.ParseAddress(email); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid email")
+	}
+
+	shop, err := getItemOrError(s.svc.shopRepo.FindOneById(req.ShopId))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, status.Error(codes.NotFound, "shop does not exists")
+	}
+
+	user, err := getItemOrError(s.svc.userRepo.FindOne(bson.M{"email": email}))
+	if err == mongo.ErrNoDocuments {
+		return nil, status.Errorf(codes.NotFound, "no user has the email %s", email)
+	}
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to get user")
+	}
+
+	if user.ID == shop.OwnerID {
+		return nil, status.Error(codes.FailedPrecondition, "the owner of the shop cannot be invited to its staff")
+	}
+	if shop.staffMember(user.ID) != nil {
+		return nil, status.Errorf(codes.AlreadyExists, "%s is already on the staff of shop[%s]", email, shop.ID)
+	}
+
+	now := time.Now()
+	invitation := &StaffInvitation{}
+	err = getCollection(&s.svc.invitationRepo.AbstractRepository).FindOneAndUpdate(ctx,
+		bson.M{"shop_id": shop.ID, "user_id": user.ID, "status": InvitationStatusPending},
+		bson.M{
+			"$set": bson.M{
+				"email":       email,
+				"permissions": permissions,
+				"invited_by":  callerFrom(ctx).ID,
+				"createdOn":   now.Unix(),
+				"expires_at":  now.Add(staffInvitationTTL).Unix(),
+			},
+			"$setOnInsert": bson.M{"_id": primitive.NewObjectID().Hex()},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(invitation)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, status.Errorf(codes.Aborted, "%s was invited to shop[%s] at the same time", email, shop.ID)
+	}
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to invite staff")
+	}
+
+	return parseStaffInvitation(invitation), nil
+}
+
+func (s *GRPCMarketPlaceServer) RevokeStaffInvitation(ctx context.Context, req *proto.GetRequest) (*proto.StaffInvitation, error) {
+	invitation, err := getItemOrError(s.svc.invitationRepo.FindOneById(req.Id))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, status.Error(codes.NotFound, "invitation does not exists")
+	}
+
+	err = s.authorizeShopOwner(ctx, invitation.ShopID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = getCollection(&s.svc.invitationRepo.AbstractRepository).FindOneAndUpdate(ctx,
+		bson.M{"_id": invitation.ID, "status": InvitationStatusPending},
+		bson.M{"$set": bson.M{"status": InvitationStatusRevoked, "respondedOn": time.Now().Unix()}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(invitation)
+	if err == mongo.ErrNoDocuments {
+		return nil, status.Errorf(codes.FailedPrecondition, "invitation is %s", invitation.Status)
+	}
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to revoke invitation")
+	}
+
+	return parseStaffInvitation(invitation), nil
+}
+
+// ListMyInvitations lists the pending invitations sent to the caller.
+func (s *GRPCMarketPlaceServer) ListMyInvitations(ctx context.Context, req *proto.ListMyInvitationsRequest) (*proto.StaffInvitations, error) {
+	result := &proto.StaffInvitations{}
+
+	invitations, err := getItemOrError(s.svc.invitationRepo.Find(
+		bson.M{
+			"user_id":    callerFrom(ctx).ID,
+			"status":     InvitationStatusPending,
+			"expires_at": bson.M{"$gt": time.Now().Unix()},
+		},
+		bson.D{{Key: "createdOn", Value: 1}}, 0, 0,
+	))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to get invitations")
+	}
+
+	for i := range invitations {
+		result.Invitations = append(result.Invitations, parseStaffInvitation(&invitations[i]))
+	}
+
+	return result, nil
+}
+
+// AcceptStaffInvitation adds the caller to the staff of the shop it was
+// invited to. The role of the caller is left as is: staff manage the shop
+// through their membership, and only admins make merchants, who open shops.
+func (s *GRPCMarketPlaceServer) AcceptStaffInvitation(ctx context.Context, req *proto.GetRequest) (*proto.StaffInvitation, error) {
+	caller := callerFrom(ctx)
+	invitation := &StaffInvitation{}
+
+	err := s.svc.withTransaction(ctx, func(sessCtx mongo.SessionContext) error {
+		var err error
+		invitation, err = s.respondToInvitation(sessCtx, req.Id, caller, InvitationStatusAccepted)
+		if err != nil {
+			return err
+		}
+
+		member := ShopStaff{
+			UserID:      caller.ID,
+			Permissions: invitation.Permissions,
+			AddedOn:     invitation.RespondedOn,
+		}
+
+		res, err := getCollection(&s.svc.shopRepo.AbstractRepository).UpdateOne(sessCtx,
+			bson.M{
+				"_id":           invitation.ShopID,
+				"owner_id":      bson.M{"$ne": caller.ID},
+				"staff.user_id": bson.M{"$ne": caller.ID},
+			},
+			bson.M{"$push": bson.M{"staff": member}},
+		)
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return errors.New("failed to add staff")
+		}
+
+		if res.MatchedCount == 0 {
+			return status.Errorf(codes.FailedPrecondition, "already managing shop[%s]", invitation.ShopID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return parseStaffInvitation(invitation), nil
+}
+
+func (s *GRPCMarketPlaceServer) DeclineStaffInvitation(ctx context.Context, req *proto.GetRequest) (*proto.StaffInvitation, error) {
+	invitation := &StaffInvitation{}
+
+	err := s.svc.withTransaction(ctx, func(sessCtx mongo.SessionContext) error {
+		var err error
+		invitation, err = s.respondToInvitation(sessCtx, req.Id, callerFrom(ctx), InvitationStatusDeclined)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return parseStaffInvitation(invitation), nil
+}
+
+// respondToInvitation moves a pending invitation sent to the user into status.
+// Invitations sent to someone else are reported as missing.
+func (s *GRPCMarketPlaceServer) respondToInvitation(sessCtx mongo.SessionContext, id string, user *User, to string) (*StaffInvitation, error) {
+	invitation := &StaffInvitation{}
+	collection := getCollection(&s.svc.invitationRepo.AbstractRepository)
+
+	err := collection.FindOne(sessCtx, bson.M{"_id": id}).Decode(invitation)
+	if err == mongo.ErrNoDocuments || (err == nil && invitation.UserID != user.ID) {
+		return nil, status.Error(codes.NotFound, "invitation does not exists")
+	}
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to get invitation")
+	}
+
+	if invitation.Status != InvitationStatusPending {
+		return nil, status.Errorf(codes.FailedPrecondition, "invitation is %s", invitation.Status)
+	}
+
+	now := time.Now().Unix()
+	if invitation.ExpiresAt <= now {
+		return nil, status.Error(codes.FailedPrecondition, "invitation has expired")
+	}
+
+	res, err := collection.UpdateOne(sessCtx,
+		bson.M{"_id": id, "status": InvitationStatusPending},
+		bson.M{"$set": bson.M{"status": to, "respondedOn": now}},
+	)
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to update invitation")
+	}
+
+	if res.MatchedCount == 0 {
+		return nil, status.Error(codes.Aborted, "invitation was updated at the same time")
+	}
+
+	invitation.Status = to
+	invitation.RespondedOn = now
+	return invitation, nil
+}
+
+// ListShopStaff lists the staff of a shop with the invitations still pending.
+func (s *GRPCMarketPlaceServer) ListShopStaff(ctx context.Context, req *proto.ListShopStaffRequest) (*proto.ShopStaffList, error) {
+	err := s.authorizeShopOwner(ctx, req.ShopId)
+	if err != nil {
+		return nil, err
+	}
+
+	shop, err := getItemOrError(s.svc.shopRepo.FindOneById(req.ShopId))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, status.Error(codes.NotFound, "shop does not exists")
+	}
+
+	users := make(map[string]*User)
+	if len(shop.Staff) > 0 {
+		var userIds []string
+		for _, member := range shop.Staff {
+			userIds = append(userIds, member.UserID)
+		}
+
+		staffUsers, err := getItemOrError(s.svc.userRepo.Find(bson.M{"_id": bson.M{"$in": userIds}}, nil, 0, 0))
+		if err != nil {
+			s.svc.logger.Println("Error: ", err)
+			return nil, errors.New("failed to get staff")
+		}
+
+		for i := range staffUsers {
+			users[staffUsers[i].ID] = &staffUsers[i]
+		}
+	}
+
+	invitations, err := getItemOrError(s.svc.invitationRepo.Find(
+		bson.M{
+			"shop_id":    shop.ID,
+			"status":     InvitationStatusPending,
+			"expires_at": bson.M{"$gt": time.Now().Unix()},
+		},
+		bson.D{{Key: "createdOn", Value: 1}}, 0, 0,
+	))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to get invitations")
+	}
+
+	result := &proto.ShopStaffList{}
+	for i := range shop.Staff {
+		result.Staff = append(result.Staff, parseShopStaff(&shop.Staff[i], users[shop.Staff[i].UserID]))
+	}
+	for i := range invitations {
+		result.PendingInvitations = append(result.PendingInvitations, parseStaffInvitation(&invitations[i]))
+	}
+
+	return result, nil
+}
+
+func (s *GRPCMarketPlaceServer) SetStaffPermissions(ctx context.Context, req *proto.SetStaffPermissionsRequest) (*proto.ShopStaff, error) {
+	err := s.authorizeShopOwner(ctx, req.ShopId)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := newPermissions(req.Permissions)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	shop := &Shop{}
+	err = getCollection(&s.svc.shopRepo.AbstractRepository).FindOneAndUpdate(ctx,
+		bson.M{"_id": req.ShopId, "staff.user_id": req.UserId},
+		bson.M{"$set": bson.M{"staff.$.permissions": permissions}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(shop)
+	if err == mongo.ErrNoDocuments {
+		return nil, status.Errorf(codes.NotFound, "user[%s] is not on the staff of shop[%s]", req.UserId, req.ShopId)
+	}
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to update staff permissions")
+	}
+
+	return s.parseStaffMember(shop.staffMember(req.UserId)), nil
+}
+
+// RemoveStaff takes a member off the staff of a shop. Members may remove
+// themselves to leave a shop.
+func (s *GRPCMarketPlaceServer) RemoveStaff(ctx context.Context, req *proto.RemoveStaffRequest) (*proto.ShopStaff, error) {
+	if !isCaller(ctx, req.UserId) {
+		err := s.authorizeShopOwner(ctx, req.ShopId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	shop := &Shop{}
+	err := getCollection(&s.svc.shopRepo.AbstractRepository).FindOneAndUpdate(ctx,
+		bson.M{"_id": req.ShopId, "staff.user_id": req.UserId},
+		bson.M{"$pull": bson.M{"staff": bson.M{"user_id": req.UserId}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(shop)
+	if err == mongo.ErrNoDocuments {
+		return nil, status.Errorf(codes.NotFound, "user[%s] is not on the staff of shop[%s]", req.UserId, req.ShopId)
+	}
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to remove staff")
+	}
+
+	return s.parseStaffMember(shop.staffMember(req.UserId)), nil
+}
+
+// ListMyShops lists the shops the caller owns or is on the staff of, with what
+// it may manage in each.
+func (s *GRPCMarketPlaceServer) ListMyShops(ctx context.Context, req *proto.ListMyShopsRequest) (*proto.MyShops, error) {
+	caller := callerFrom(ctx)
+
+	shops, err := getItemOrError(s.svc.shopRepo.Find(
+		bson.M{"$or": bson.A{
+			bson.M{"owner_id": caller.ID},
+			bson.M{"staff.user_id": caller.ID},
+		}},
+		bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}, 0, 0,
+	))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to get shops")
+	}
+
+	result := &proto.MyShops{}
+	for i := range shops {
+		shop := &shops[i]
+		pshop, err := s.ParseShop(ctx, shop)
+		if err != nil {
+			return nil, err
+		}
+
+		myShop := &proto.MyShop{Shop: pshop}
+		if shop.OwnerID == caller.ID {
+			myShop.Relation = "OWNER"
+			myShop.Permissions = staffPermissions
+		} else {
+			myShop.Relation = "STAFF"
+			myShop.Permissions = shop.staffMember(caller.ID).Permissions
+		}
+		result.Shops = append(result.Shops, myShop)
+	}
+
+	return result, nil
+}
+
+// parseStaffMember parses a member of a staff along with its user, leaving
+// the user out when it cannot be found.
+func (s *GRPCMarketPlaceServer) parseStaffMember(member *ShopStaff) *proto.ShopStaff {
+	user, err := getItemOrError(s.svc.userRepo.FindOneById(member.UserID))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return parseShopStaff(member, nil)
+	}
+	return parseShopStaff(member, user)
+}
+
+// parseShopStaff parses a member of a staff, user may be nil.
+func parseShopStaff(member *ShopStaff, user *User) *proto.ShopStaff {
+	pmember := &proto.ShopStaff{
+		UserId:      member.UserID,
+		Permissions: member.Permissions,
+		AddedOn:     member.AddedOn,
+	}
+
+	if user != nil {
+		pmember.Name = user.Name
+		pmember.Email = user.Email
+	}
+
+	return pmember
+}
+
+func parseStaffInvitation(invitation *StaffInvitation) *proto.StaffInvitation {
+	return &proto.StaffInvitation{
+		Id:          invitation.ID,
+		ShopId:      invitation.ShopID,
+		UserId:      invitation.UserID,
+		Email:       invitation.Email,
+		Permissions: invitation.Permissions,
+		Status:      invitation.Status,
+		InvitedBy:   invitation.InvitedBy,
+		CreatedOn:   invitation.CreatedOn,
+		ExpiresAt:   invitation.ExpiresAt,
+		RespondedOn: invitation.RespondedOn,
+	}
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NikhilSharmaWe/marketplace/proto"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"gopkg.in/yaml.v3"
+)
+
+// setupTaxIndexes lets the tax rules covering a shop be found by its position.
+func (app *application) setupTaxIndexes() error {
+	_, err := getCollection(&app.taxRuleRepo.AbstractRepository).Indexes().CreateMany(app.ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "area", Value: "2dsphere"}},
+		},
+		{
+			Keys: bson.D{{Key: "region", Value: 1}, {Key: "name", Value: 1}},
+		},
+	})
+	return err
+}
+
+// loadTaxRules stores the tax rules listed in the JSON or YAML file at
+// TAX-RULES-FILE, when it is set, telling them apart by the extension of the
+// file. The file holds an array of rules in the form the api takes them. Each
+// needs an id so that loading the file again replaces the rules instead of
+// adding them twice. Their categories are not checked, as the file may be
+// loaded before the categories are created.
+func (app *application) loadTaxRules() error {
+	path := os.Getenv("TAX-RULES-FILE")
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+	case ".yaml", ".yml":
+		data, err = yamlToJSON(data)
+	default:
+		return fmt.Errorf("tax rules file %s must be .json, .yaml or .yml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse tax rules in %s: %w", path, err)
+	}
+
+	prules, err := parseTaxRules(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse tax rules in %s: %w", path, err)
+	}
+
+	collection := getCollection(&app.taxRuleRepo.AbstractRepository)
+	for i, prule := range prules {
+		if prule.Id == "" {
+			return fmt.Errorf("tax rule %d in %s has no id", i+1, path)
+		}
+
+		rule, err := newTaxRule(prule)
+		if err != nil {
+			return fmt.Errorf("tax rule %s in %s: %w", prule.Id, path, err)
+		}
+
+		_, err = collection.ReplaceOne(app.ctx, bson.M{"_id": rule.ID}, rule, options.Replace().SetUpsert(true))
+		if err != nil {
+			return err
+		}
+	}
+
+	app.logger.Printf("loaded %d tax rules from %s", len(prules), path)
+	return nil
+}
+
+// parseTaxRules reads a JSON array of tax rules in the protobuf JSON mapping,
+// with the camelCase field names SetTaxRule takes. Unknown fields are
+// rejected, so that a misspelt one does not leave a rule without it.
+func parseTaxRules(data []byte) ([]*proto.TaxRule, error) {
+	var elements []json.RawMessage
+	err := json.Unmarshal(data, &elements)
+	if err != nil {
+		return nil, err
+	}
+
+	prules := make([]*proto.TaxRule, len(elements))
+	for i, element := range elements {
+		prules[i] = &proto.TaxRule{}
+		err = protojson.Unmarshal(element, prules[i])
+		if err != nil {
+			return nil, fmt.Errorf("tax rule %d: %w", i+1, err)
+		}
+	}
+	return prules, nil
+}
+
+// yamlToJSON converts a YAML document into JSON, so that the rules it holds
+// are read with the same field names as the ones of a JSON file.
+func yamlToJSON(data []byte) ([]byte, error) {
+	var doc interface{}
+	err := yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(doc)
+}
+
+// SetTaxRule creates a tax rule, or replaces the one with the given id.
+func (s *GRPCMarketPlaceServer) SetTaxRule(ctx context.Context, req *proto.TaxRule) (*proto.TaxRule, error) {
+	rule, err := newTaxRule(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	rule.CategoryIDs, err = s.checkCategories(rule.CategoryIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = getCollection(&s.svc.taxRuleRepo.AbstractRepository).ReplaceOne(ctx,
+		bson.M{"_id": rule.ID}, rule, options.Replace().SetUpsert(true),
+	)
+	if err != nil {
+		if isInvalidGeometry(err) {
+			return nil, status.Error(codes.InvalidArgument, "tax region boundary must not intersect itself")
+		}
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to save tax rule")
+	}
+
+	return parseTaxRule(&rule), nil
+}
+
+func (s *GRPCMarketPlaceServer) ListTaxRules(ctx context.Context, req *proto.ListTaxRulesRequest) (*proto.TaxRules, error) {
+	filter := bson.M{}
+	if req.Region != "" {
+		filter["region"] = req.Region
+	}
+
+	sort := bson.D{{Key: "region", Value: 1}, {Key: "name", Value: 1}}
+	rules, err := getItemOrError(s.svc.taxRuleRepo.Find(filter, sort, 0, 0))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to get tax rules")
+	}
+
+	result := &proto.TaxRules{}
+	for i := range rules {
+		result.Rules = append(result.Rules, parseTaxRule(&rules[i]))
+	}
+
+	return result, nil
+}
+
+func (s *GRPCMarketPlaceServer) DeleteTaxRule(ctx context.Context, req *proto.GetRequest) (*proto.TaxRule, error) {
+	rule := &TaxRule{}
+	err := getCollection(&s.svc.taxRuleRepo.AbstractRepository).FindOneAndDelete(ctx, bson.M{"_id": req.Id}).Decode(rule)
+	if err == mongo.ErrNoDocuments {
+		return nil, status.Error(codes.NotFound, "tax rule does not exists")
+	}
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, errors.New("failed to delete tax rule")
+	}
+
+	return parseTaxRule(rule), nil
+}
+
+// applyTaxes works out the tax on every item of an order from the shop, adding
+// the exclusive part to the total. The discounts of the order are spread over
+// the items in proportion to their amount before taxing them.
+func (s *GRPCMarketPlaceServer) applyTaxes(order *Order, shop *Shop, products map[string]*Product) error {
+	rules, err := getItemOrError(s.svc.taxRuleRepo.Find(
+		bson.M{"area": bson.M{"$geoIntersects": bson.M{"$geometry": shop.Position}}},
+		bson.D{{Key: "_id", Value: 1}}, 0, 0,
+	))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return errors.New("failed to get tax rules")
+	}
+
+	order.Tax = Money{CurrencyCode: order.Total.CurrencyCode}
+	if len(rules) == 0 {
+		return nil
+	}
+
+	var scoped bool
+	for _, rule := range rules {
+		scoped = scoped || len(rule.CategoryIDs) > 0
+	}
+
+	var categories map[string]map[string]int
+	if scoped {
+		ancestors, err := s.categoryAncestors(products)
+		if err != nil {
+			return err
+		}
+		categories = productCategoryDepths(products, ancestors)
+	}
+
+	return taxOrder(order, shop.ID, rules, categories)
+}
+
+// taxOrder is applyTaxes once the rules covering the shop and the categories
+// of the products are known.
+func taxOrder(order *Order, shopId string, rules []TaxRule, categories map[string]map[string]int) error {
+	regions := make(map[string][]*TaxRule)
+	var regionNames []string
+	for i := range rules {
+		rule := &rules[i]
+		if _, ok := regions[rule.Region]; !ok {
+			regionNames = append(regionNames, rule.Region)
+		}
+		regions[rule.Region] = append(regions[rule.Region], rule)
+	}
+	sort.Strings(regionNames)
+
+	var amounts, discounts []Money
+	for _, item := range order.Items {
+		amounts = append(amounts, item.Price.Mul(item.Quantity))
+	}
+	for _, discount := range order.Discounts {
+		discounts = append(discounts, discount.Amount)
+	}
+	subtotal, _ := sumMoney(amounts...)
+	discount, _ := sumMoney(discounts...)
+
+	exclusive := Money{CurrencyCode: order.Total.CurrencyCode}
+	remaining := discount
+	for i := range order.Items {
+		item := &order.Items[i]
+		item.Taxes = nil
+
+		// the last item takes what is left of the discount so that rounding
+		// does not lose any of it
+		share := remaining
+		if i < len(order.Items)-1 && subtotal.MinorUnits > 0 {
+			share = discount.Scale(amounts[i].MinorUnits, subtotal.MinorUnits)
+		}
+		remaining, _ = remaining.Sub(share)
+
+		base, err := amounts[i].Sub(share)
+		if err != nil {
+			return status.Errorf(codes.FailedPrecondition, "discounts of order from shop[%s] are in another currency than its items", shopId)
+		}
+
+		for _, region := range regionNames {
+			rule := taxRuleFor(regions[region], categories[item.ProductID])
+			if rule == nil {
+				continue
+			}
+
+			tax := LineTax{
+				TaxRuleID:       rule.ID,
+				Name:            rule.Name,
+				RateBasisPoints: rule.RateBasisPoints,
+				Mode:            rule.Mode,
+			}
+			if rule.Mode == TaxModeInclusive {
+				tax.Amount = base.Scale(int64(rule.RateBasisPoints), int64(10000+rule.RateBasisPoints))
+			} else {
+				tax.Amount = base.Scale(int64(rule.RateBasisPoints), 10000)
+				exclusive, _ = exclusive.Add(tax.Amount)
+			}
+
+			order.Tax, _ = order.Tax.Add(tax.Amount)
+			item.Taxes = append(item.Taxes, tax)
+		}
+	}
+
+	var err error
+	order.Total, err = order.Total.Add(exclusive)
+	if err != nil {
+		return status.Errorf(codes.FailedPrecondition, "order from shop[%s] mixes currencies", shopId)
+	}
+
+	return nil
+}
+
+// productCategoryDepths returns the categories each product is in, including
+// the ones above its own, along with their depth in the taxonomy.
+func productCategoryDepths(products map[string]*Product, ancestors map[string][]string) map[string]map[string]int {
+	categories := make(map[string]map[string]int)
+	for _, product := range products {
+		in := make(map[string]int)
+		for _, categoryId := range product.CategoryIDs {
+			in[categoryId] = len(ancestors[categoryId])
+			for depth, ancestorId := range ancestors[categoryId] {
+				in[ancestorId] = depth
+			}
+		}
+		categories[product.ID] = in
+	}
+
+	return categories
+}
+
+// taxRuleFor picks the rule of a region for a product in the given
+// categories: the one for its most specific category, falling back to the one
+// for all products. It returns nil when none applies.
+func taxRuleFor(rules []*TaxRule, categories map[string]int) *TaxRule {
+	var match *TaxRule
+	matchDepth := -2
+	for _, rule := range rules {
+		depth := -1
+		if len(rule.CategoryIDs) > 0 {
+			depth = -2
+			for _, categoryId := range rule.CategoryIDs {
+				if d, ok := categories[categoryId]; ok && d > depth {
+					depth = d
+				}
+			}
+		}
+
+		if depth > matchDepth {
+			match, matchDepth = rule, depth
+		}
+	}
+
+	if matchDepth == -2 {
+		return nil
+	}
+	return match
+}
+
+// newTaxRule validates a tax rule received over the api. Its categories are
+// left to the caller to check.
+func newTaxRule(prule *proto.TaxRule) (TaxRule, error) {
+	rule := TaxRule{
+		ID:              prule.Id,
+		Name:            strings.TrimSpace(prule.Name),
+		Region:          strings.TrimSpace(prule.Region),
+		CategoryIDs:     uniqueStrings(prule.CategoryIds),
+		RateBasisPoints: int(prule.RateBasisPoints),
+		Mode:            prule.Mode,
+		UpdatedOn:       time.Now().Unix(),
+	}
+
+	if rule.ID == "" {
+		rule.ID = primitive.NewObjectID().Hex()
+	}
+
+	if rule.Name == "" || rule.Region == "" {
+		return TaxRule{}, errors.New("name and region are required")
+	}
+
+	if rule.CategoryIDs == nil {
+		rule.CategoryIDs = []string{}
+	}
+
+	// zero rates are allowed so that categories can be exempted
+	if rule.RateBasisPoints < 0 || rule.RateBasisPoints > 10000 {
+		return TaxRule{}, errors.New("rate must be between 0 and 10000 basis points")
+	}
+
+	switch rule.Mode {
+	case "":
+		rule.Mode = TaxModeExclusive
+	case TaxModeExclusive, TaxModeInclusive:
+	default:
+		return TaxRule{}, fmt.Errorf("unknown tax mode %q", prule.Mode)
+	}
+
+	area, err := newGeoPolygon(prule.Boundary)
+	if err != nil {
+		return TaxRule{}, fmt.Errorf("boundary: %w", err)
+	}
+	rule.Area = area
+
+	return rule, nil
+}
+
+func parseTaxRule(rule *TaxRule) *proto.TaxRule {
+	prule := &proto.TaxRule{
+		Id:              rule.ID,
+		Name:            rule.Name,
+		Region:          rule.Region,
+		CategoryIds:     rule.CategoryIDs,
+		RateBasisPoints: int32(rule.RateBasisPoints),
+		Mode:            rule.Mode,
+		Boundary:        polygonBoundary(rule.Area),
+		UpdatedOn:       rule.UpdatedOn,
+	}
+
+	return prule
+}
+
+func parseLineTaxes(taxes []LineTax) []*proto.LineTax {
+	var ptaxes []*proto.LineTax
+	for _, tax := range taxes {
+		ptaxes = append(ptaxes, &proto.LineTax{
+			TaxRuleId:       tax.TaxRuleID,
+			Name:            tax.Name,
+			RateBasisPoints: int32(tax.RateBasisPoints),
+			Mode:            tax.Mode,
+			Amount:          parseMoney(tax.Amount),
+		})
+	}
+	return ptaxes
+}
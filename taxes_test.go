@@ -0,0 +1,205 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseTaxRules(t *testing.T) {
+	json := `[{
+		"id": "ca",
+		"name": "California sales tax",
+		"region": "state",
+		"boundary": [{"latitude": 42, "longitude": -124.4}, {"latitude": 32.5, "longitude": -124.4}, {"latitude": 32.5, "longitude": -114.1}],
+		"categoryIds": ["apparel"],
+		"rateBasisPoints": 725,
+		"mode": "EXCLUSIVE"
+	}]`
+
+	yaml := `
+- id: ca
+  name: California sales tax
+  region: state
+  boundary:
+    - {latitude: 42, longitude: -124.4}
+    - {latitude: 32.5, longitude: -124.4}
+    - {latitude: 32.5, longitude: -114.1}
+  categoryIds: [apparel]
+  rateBasisPoints: 725
+  mode: EXCLUSIVE
+`
+
+	yamlJSON, err := yamlToJSON([]byte(yaml))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, data := range map[string][]byte{"json": []byte(json), "yaml": yamlJSON} {
+		prules, err := parseTaxRules(data)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if len(prules) != 1 {
+			t.Errorf("%s: parsed %d rules, want 1", name, len(prules))
+			continue
+		}
+
+		prule := prules[0]
+		if prule.Id != "ca" || prule.Region != "state" || prule.RateBasisPoints != 725 || prule.Mode != TaxModeExclusive {
+			t.Errorf("%s: parsed %v", name, prule)
+		}
+		if len(prule.Boundary) != 3 || prule.Boundary[2].Longitude != -114.1 {
+			t.Errorf("%s: parsed boundary %v", name, prule.Boundary)
+		}
+		if len(prule.CategoryIds) != 1 || prule.CategoryIds[0] != "apparel" {
+			t.Errorf("%s: parsed categories %v", name, prule.CategoryIds)
+		}
+	}
+
+	invalid := []string{
+		`{"id": "ca"}`,
+		// snake_case is not a field of the rule
+		`[{"id": "ca", "rate_basis_points": 725}]`,
+		`[{"id": "ca", "rateBasisPoints": "a lot"}]`,
+	}
+	for _, data := range invalid {
+		if _, err := parseTaxRules([]byte(data)); err == nil {
+			t.Errorf("parseTaxRules(%s) succeeded, want an error", data)
+		}
+	}
+}
+
+func TestTaxRuleFor(t *testing.T) {
+	everything := &TaxRule{ID: "everything"}
+	apparel := &TaxRule{ID: "apparel", CategoryIDs: []string{"apparel"}}
+	shoes := &TaxRule{ID: "shoes", CategoryIDs: []string{"books", "shoes"}}
+
+	// running is under shoes, which is under apparel
+	running := map[string]int{"apparel": 0, "shoes": 1, "running": 2}
+	shirts := map[string]int{"apparel": 0, "shirts": 1}
+	food := map[string]int{"food": 0}
+
+	tests := []struct {
+		name       string
+		rules      []*TaxRule
+		categories map[string]int
+		want       *TaxRule
+	}{
+		{"most specific category", []*TaxRule{everything, apparel, shoes}, running, shoes},
+		{"most specific category in any order", []*TaxRule{shoes, apparel, everything}, running, shoes},
+		{"category above the product's", []*TaxRule{everything, apparel, shoes}, shirts, apparel},
+		{"all products when no category matches", []*TaxRule{everything, apparel, shoes}, food, everything},
+		{"all products without categories", []*TaxRule{everything, apparel}, nil, everything},
+		{"no rule for the product", []*TaxRule{apparel, shoes}, food, nil},
+		{"no rules", nil, running, nil},
+	}
+
+	for _, test := range tests {
+		if got := taxRuleFor(test.rules, test.categories); got != test.want {
+			t.Errorf("%s: taxRuleFor picked %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestTaxOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		items     []OrderItem
+		discount  int64
+		rules     []TaxRule
+		wantTaxes [][]int64
+		wantTax   int64
+		wantTotal int64
+	}{
+		{
+			// 8.25% of 950 is 78.375 and of 949 is 78.2925
+			name:      "exclusive tax rounded per line",
+			items:     []OrderItem{{ProductID: "a", Quantity: 1, Price: usd(1000)}, {ProductID: "b", Quantity: 3, Price: usd(333)}},
+			discount:  100,
+			rules:     []TaxRule{{ID: "state", Region: "state", RateBasisPoints: 825, Mode: TaxModeExclusive}},
+			wantTaxes: [][]int64{{78}, {78}},
+			wantTax:   156,
+			wantTotal: 1899 + 156,
+		},
+		{
+			// the tax inside 950 at 10% is 950 / 11 = 86.36
+			name:  "inclusive tax is not added to the total",
+			items: []OrderItem{{ProductID: "a", Quantity: 1, Price: usd(1000)}, {ProductID: "b", Quantity: 3, Price: usd(333)}},
+			rules: []TaxRule{
+				{ID: "state", Region: "state", RateBasisPoints: 825, Mode: TaxModeExclusive},
+				{ID: "city", Region: "city", RateBasisPoints: 1000, Mode: TaxModeInclusive},
+			},
+			discount: 100,
+			// regions are applied in the order of their names
+			wantTaxes: [][]int64{{86, 78}, {86, 78}},
+			wantTax:   156 + 172,
+			wantTotal: 1899 + 156,
+		},
+		{
+			// 0.25% of 200 is half a cent, rounded up
+			name:      "halves round up",
+			items:     []OrderItem{{ProductID: "a", Quantity: 2, Price: usd(100)}},
+			rules:     []TaxRule{{ID: "state", Region: "state", RateBasisPoints: 25, Mode: TaxModeExclusive}},
+			wantTaxes: [][]int64{{1}},
+			wantTax:   1,
+			wantTotal: 201,
+		},
+		{
+			// the discount of 100 is split 33/33/34, the last line taking
+			// what rounding left of it
+			name: "discount spread over the lines",
+			items: []OrderItem{
+				{ProductID: "a", Quantity: 1, Price: usd(1000)},
+				{ProductID: "b", Quantity: 1, Price: usd(1000)},
+				{ProductID: "c", Quantity: 1, Price: usd(1000)},
+			},
+			discount:  100,
+			rules:     []TaxRule{{ID: "state", Region: "state", RateBasisPoints: 1000, Mode: TaxModeExclusive}},
+			wantTaxes: [][]int64{{97}, {97}, {97}},
+			wantTax:   291,
+			wantTotal: 2900 + 291,
+		},
+	}
+
+	for _, test := range tests {
+		order := &Order{Items: test.items}
+		for _, item := range test.items {
+			order.Total, _ = order.Total.Add(item.Price.Mul(item.Quantity))
+		}
+		if test.discount > 0 {
+			order.Discounts = []OrderDiscount{{PromotionID: "p", Amount: usd(test.discount)}}
+			order.Total, _ = order.Total.Sub(usd(test.discount))
+		}
+		order.Tax = Money{CurrencyCode: order.Total.CurrencyCode}
+
+		err := taxOrder(order, "shop", test.rules, nil)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		for i, item := range order.Items {
+			var got []int64
+			for _, tax := range item.Taxes {
+				got = append(got, tax.Amount.MinorUnits)
+			}
+			if len(got) != len(test.wantTaxes[i]) {
+				t.Errorf("%s: item %d is taxed %v, want %v", test.name, i, got, test.wantTaxes[i])
+				continue
+			}
+			for j := range got {
+				if got[j] != test.wantTaxes[i][j] {
+					t.Errorf("%s: item %d is taxed %v, want %v", test.name, i, got, test.wantTaxes[i])
+					break
+				}
+			}
+		}
+
+		if order.Tax != usd(test.wantTax) {
+			t.Errorf("%s: tax is %v, want %v", test.name, order.Tax, usd(test.wantTax))
+		}
+		if order.Total != usd(test.wantTotal) {
+			t.Errorf("%s: total is %v, want %v", test.name, order.Total, usd(test.wantTotal))
+		}
+	}
+}
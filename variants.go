@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/NikhilSharmaWe/marketplace/proto"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// resolveVariant returns the id of the variant that stock of the product is
+// kept for when variantId is asked for. Products without variants are stocked
+// under an empty variant id and products with a single one stand for it, so
+// that callers unaware of variants keep working for them.
+func (p *Product) resolveVariant(variantId string) (string, error) {
+	if variantId == "" {
+		switch len(p.Variants) {
+		case 0:
+			return "", nil
+		case 1:
+			return p.Variants[0].ID, nil
+		}
+		return "", status.Errorf(codes.InvalidArgument, "product[%s] has %d variants, a variant id is required", p.ID, len(p.Variants))
+	}
+
+	if p.variant(variantId) == nil {
+		return "", status.Errorf(codes.NotFound, "variant[%s] of product[%s] does not exists", variantId, p.ID)
+	}
+	return variantId, nil
+}
+
+func (p *Product) variant(variantId string) *Variant {
+	for i := range p.Variants {
+		if p.Variants[i].ID == variantId {
+			return &p.Variants[i]
+		}
+	}
+	return nil
+}
+
+// priceOf returns the catalog price of a variant of the product, which is the
+// price of the product itself when it has no variants.
+func (p *Product) priceOf(variantId string) Money {
+	if variant := p.variant(variantId); variant != nil {
+		return variant.Price
+	}
+	return p.Price
+}
+
+// variantIDs returns the ids stock of the product is kept under.
+func (p *Product) variantIDs() []string {
+	if len(p.Variants) == 0 {
+		return []string{""}
+	}
+
+	var ids []string
+	for _, variant := range p.Variants {
+		ids = append(ids, variant.ID)
+	}
+	return ids
+}
+
+// productVariant loads a product and resolves the variant asked for.
+func (s *GRPCMarketPlaceServer) productVariant(productId, variantId string) (*Product, string, error) {
+	product, err := getItemOrError(s.svc.productRepo.FindOneById(productId))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return nil, "", status.Errorf(codes.NotFound, "product[%s] does not exists", productId)
+	}
+
+	variantId, err = product.resolveVariant(variantId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return product, variantId, nil
+}
+
+// stockedVariant returns the variant whose stock an item stored with variantId
+// was taken from. Items without a variant stored before their product gained
+// variants were taken from the stock that moved to its first variant.
+func (s *GRPCMarketPlaceServer) stockedVariant(productId, variantId string) (string, error) {
+	if variantId != "" {
+		return variantId, nil
+	}
+
+	product, err := getItemOrError(s.svc.productRepo.FindOneById(productId))
+	if err != nil {
+		s.svc.logger.Println("Error: ", err)
+		return "", status.Errorf(codes.NotFound, "product[%s] does not exists", productId)
+	}
+
+	if len(product.Variants) == 0 {
+		return "", nil
+	}
+	return product.Variants[0].ID, nil
+}
+
+func inventoryFilter(shopId, productId, variantId string) bson.M {
+	return bson.M{"shop_id": shopId, "product_id": productId, "variant_id": variantId}
+}
+
+func newVariant(pvariant *proto.Variant) (Variant, error) {
+	if pvariant == nil || pvariant.Sku == "" {
+		return Variant{}, errors.New("variant sku is required")
+	}
+
+	price, err := newMoney(pvariant.Price)
+	if err != nil {
+		return Variant{}, fmt.Errorf("price of variant %s: %w", pvariant.Sku, err)
+	}
+
+	return Variant{
+		ID:         primitive.NewObjectID().Hex(),
+		SKU:        pvariant.Sku,
+		Attributes: pvariant.Attributes,
+		Price:      price,
+	}, nil
+}
+
+func parseVariant(variant *Variant) *proto.Variant {
+	return &proto.Variant{
+		Id:         variant.ID,
+		Sku:        variant.SKU,
+		Attributes: variant.Attributes,
+		Price:      parseMoney(variant.Price),
+	}
+}
+
+// migrateInventoryVariants keys the inventories, reservations and movements
+// stored before products had variants by the empty variant id that products
+// without variants are stocked under.
+func (app *application) migrateInventoryVariants() error {
+	collections := []*mongo.Collection{
+		getCollection(&app.inventoryRepo.AbstractRepository),
+		getCollection(&app.reservationRepo.AbstractRepository),
+		getCollection(&app.movementRepo.AbstractRepository),
+	}
+
+	for _, collection := range collections {
+		_, err := collection.UpdateMany(app.ctx,
+			bson.M{"variant_id": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"variant_id": ""}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
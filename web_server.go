@@ -1,11 +1,18 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/NikhilSharmaWe/marketplace/proto"
 	"github.com/julienschmidt/httprouter"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 func (app *application) setupWebserver() {
@@ -13,23 +20,155 @@ func (app *application) setupWebserver() {
 	router.NotFound = http.HandlerFunc(app.notFoundResponse)
 	router.MethodNotAllowed = http.HandlerFunc(app.methodNotAllowed)
 
+	router.HandlerFunc(http.MethodPost, "/login", app.HandleLogin)
+	router.HandlerFunc(http.MethodPost, "/refreshToken", app.HandleRefreshToken)
+	router.HandlerFunc(http.MethodPost, "/password", app.HandleSetPassword)
+
 	router.HandlerFunc(http.MethodPost, "/user", app.HandleCreateUser)
 	router.HandlerFunc(http.MethodGet, "/user/:id", app.HandleGetUser)
+	router.HandlerFunc(http.MethodPost, "/userRole", app.HandleSetUserRole)
 	router.HandlerFunc(http.MethodGet, "/neighbour/:userId", app.HandleGetNearestNeighbour)
+	router.HandlerFunc(http.MethodGet, "/nearestUsers", app.HandleGetKNearestUsers)
+	router.HandlerFunc(http.MethodGet, "/nearestShops", app.HandleGetKNearestShops)
 
 	router.HandlerFunc(http.MethodPost, "/shop", app.HandleCreateShop)
 	router.HandlerFunc(http.MethodGet, "/shop/:id", app.HandleGetShop)
 	router.HandlerFunc(http.MethodGet, "/shopForUser/:userId/:maxDist", app.HandleGetShopForUser)
+	router.HandlerFunc(http.MethodGet, "/searchShops", app.HandleSearchNearbyShops)
+	router.HandlerFunc(http.MethodGet, "/shops", app.HandleSearchShopsInArea)
+	router.HandlerFunc(http.MethodPost, "/deliveryZones", app.HandleSetDeliveryZones)
+	router.HandlerFunc(http.MethodGet, "/deliveryZones/:shopId", app.HandleGetDeliveryZones)
+	router.HandlerFunc(http.MethodPost, "/operatingHours", app.HandleSetOperatingHours)
+	router.HandlerFunc(http.MethodGet, "/shopOpen/:shopId", app.HandleIsShopOpen)
 
 	router.HandlerFunc(http.MethodPost, "/product", app.HandleCreateProduct)
 	router.HandlerFunc(http.MethodGet, "/product/:id", app.HandleGetProduct)
+	router.HandlerFunc(http.MethodGet, "/products/search", app.HandleSearchProducts)
 	router.HandlerFunc(http.MethodPost, "/addProduct", app.HandleAddProductToShop)
 	router.HandlerFunc(http.MethodGet, "/serviceableProducts/:shopId", app.HandleGetServiceableProducts)
+	router.HandlerFunc(http.MethodPost, "/shopPrice", app.HandleSetShopPrice)
+	router.HandlerFunc(http.MethodPost, "/productCategories", app.HandleSetProductCategories)
+	router.HandlerFunc(http.MethodPost, "/productVariant", app.HandleAddProductVariant)
+
+	router.HandlerFunc(http.MethodPost, "/category", app.HandleCreateCategory)
+	router.HandlerFunc(http.MethodGet, "/categories", app.HandleListCategories)
+	router.HandlerFunc(http.MethodGet, "/categoryProducts/:categoryId", app.HandleListProductsInCategory)
 
 	router.HandlerFunc(http.MethodGet, "/inventory/:shopId/:productId", app.HandleGetInventory)
 	router.HandlerFunc(http.MethodPost, "/inventory", app.HandleUpdateInventory)
+	router.HandlerFunc(http.MethodGet, "/inventoryMovements/:shopId", app.HandleListInventoryMovements)
+	router.HandlerFunc(http.MethodGet, "/stockAt/:shopId/:productId/:at", app.HandleGetStockAt)
+	router.HandlerFunc(http.MethodPost, "/transfer", app.HandleTransferStock)
+	router.HandlerFunc(http.MethodPost, "/reorderThreshold", app.HandleSetReorderThreshold)
+	router.HandlerFunc(http.MethodGet, "/lowStock/:shopId", app.HandleListLowStock)
+	router.HandlerFunc(http.MethodPost, "/bulkInventory", app.HandleBulkUpdateInventory)
+	router.HandlerFunc(http.MethodGet, "/inventoryExport/:shopId", app.HandleExportInventory)
+
+	router.HandlerFunc(http.MethodPost, "/reservation", app.HandleReserveStock)
+	router.HandlerFunc(http.MethodPost, "/commitReservation/:id", app.HandleCommitReservation)
+	router.HandlerFunc(http.MethodPost, "/releaseReservation/:id", app.HandleReleaseReservation)
+
+	router.HandlerFunc(http.MethodPost, "/order", app.HandlePlaceOrder)
+	router.HandlerFunc(http.MethodGet, "/order/:id", app.HandleGetOrder)
+	router.HandlerFunc(http.MethodGet, "/ordersForUser/:userId", app.HandleListOrdersForUser)
+	router.HandlerFunc(http.MethodGet, "/ordersForShop/:shopId", app.HandleListOrdersForShop)
+	router.HandlerFunc(http.MethodPost, "/orderStatus", app.HandleUpdateOrderStatus)
+
+	router.HandlerFunc(http.MethodPost, "/cart", app.HandleAddToCart)
+	router.HandlerFunc(http.MethodPost, "/removeFromCart", app.HandleRemoveFromCart)
+	router.HandlerFunc(http.MethodGet, "/cart/:userId", app.HandleGetCart)
+	router.HandlerFunc(http.MethodPost, "/checkout", app.HandleCheckout)
+
+	router.HandlerFunc(http.MethodPost, "/promotion", app.HandleCreatePromotion)
+	router.HandlerFunc(http.MethodPost, "/validateCoupon", app.HandleValidateCoupon)
+
+	router.HandlerFunc(http.MethodPost, "/taxRule", app.HandleSetTaxRule)
+	router.HandlerFunc(http.MethodGet, "/taxRules", app.HandleListTaxRules)
+	router.HandlerFunc(http.MethodDelete, "/taxRule/:id", app.HandleDeleteTaxRule)
+
+	router.HandlerFunc(http.MethodPost, "/priceSchedule", app.HandleSchedulePriceChange)
+	router.HandlerFunc(http.MethodGet, "/priceHistory/:productId", app.HandleGetPriceHistory)
+
+	router.HandlerFunc(http.MethodPost, "/staffInvitation", app.HandleInviteStaff)
+	router.HandlerFunc(http.MethodDelete, "/staffInvitation/:id", app.HandleRevokeStaffInvitation)
+	router.HandlerFunc(http.MethodPost, "/staffInvitation/:id/accept", app.HandleAcceptStaffInvitation)
+	router.HandlerFunc(http.MethodPost, "/staffInvitation/:id/decline", app.HandleDeclineStaffInvitation)
+	router.HandlerFunc(http.MethodGet, "/myInvitations", app.HandleListMyInvitations)
+	router.HandlerFunc(http.MethodGet, "/shopStaff/:shopId", app.HandleListShopStaff)
+	router.HandlerFunc(http.MethodPost, "/staffPermissions", app.HandleSetStaffPermissions)
+	router.HandlerFunc(http.MethodDelete, "/shopStaff/:shopId/:userId", app.HandleRemoveStaff)
+	router.HandlerFunc(http.MethodGet, "/myShops", app.HandleListMyShops)
+
+	app.goApiBoot.WebServer.Handler = app.forwardAuthorization(router)
+}
+
+// forwardAuthorization passes the bearer token of a request on to the grpc
+// server, which authenticates it. Handlers must call the grpc client with the
+// context of the request for the token to be sent.
+func (app *application) forwardAuthorization(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authorization := r.Header.Get("Authorization"); authorization != "" {
+			ctx := metadata.AppendToOutgoingContext(r.Context(), "authorization", authorization)
+			r = r.WithContext(ctx)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (app *application) HandleLogin(w http.ResponseWriter, r *http.Request) {
+	loginReq := &proto.LoginRequest{}
+	err := app.readJSON(w, r, loginReq)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tokens, err := app.grpcClient.Login(r.Context(), loginReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("user[%s] logged in", tokens.User.Id)
+	app.writeJSON(w, http.StatusOK, tokens)
+}
+
+func (app *application) HandleRefreshToken(w http.ResponseWriter, r *http.Request) {
+	refreshReq := &proto.RefreshTokenRequest{}
+	err := app.readJSON(w, r, refreshReq)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tokens, err := app.grpcClient.RefreshToken(r.Context(), refreshReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("refreshed tokens of user[%s]", tokens.User.Id)
+	app.writeJSON(w, http.StatusOK, tokens)
+}
+
+func (app *application) HandleSetPassword(w http.ResponseWriter, r *http.Request) {
+	passwordReq := &proto.SetPasswordRequest{}
+	err := app.readJSON(w, r, passwordReq)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, err := app.grpcClient.SetPassword(r.Context(), passwordReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
 
-	app.goApiBoot.WebServer.Handler = router
+	app.logger.Printf("set password of user[%s]", user.Id)
+	app.writeJSON(w, http.StatusOK, map[string]any{
+		"user": user,
+	})
 }
 
 func (app *application) HandleCreateUser(w http.ResponseWriter, r *http.Request) {
@@ -40,9 +179,9 @@ func (app *application) HandleCreateUser(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	user, err := app.grpcClient.CreateUser(app.ctx, createReq)
+	user, err := app.grpcClient.CreateUser(r.Context(), createReq)
 	if err != nil {
-		app.errorResponse(w, r, http.StatusInternalServerError, err.Error())
+		app.grpcErrorResponse(w, r, err)
 		return
 	}
 
@@ -59,9 +198,9 @@ func (app *application) HandleGetUser(w http.ResponseWriter, r *http.Request) {
 		Id: id,
 	}
 
-	user, err := app.grpcClient.GetUserByID(app.ctx, &protoReq)
+	user, err := app.grpcClient.GetUserByID(r.Context(), &protoReq)
 	if err != nil {
-		app.errorResponse(w, r, http.StatusInternalServerError, err.Error())
+		app.grpcErrorResponse(w, r, err)
 		return
 	}
 
@@ -71,6 +210,26 @@ func (app *application) HandleGetUser(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (app *application) HandleSetUserRole(w http.ResponseWriter, r *http.Request) {
+	roleReq := &proto.SetUserRoleRequest{}
+	err := app.readJSON(w, r, roleReq)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, err := app.grpcClient.SetUserRole(r.Context(), roleReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("set role of user[%s] to %s", user.Id, user.Role)
+	app.writeJSON(w, http.StatusOK, map[string]any{
+		"user": user,
+	})
+}
+
 func (app *application) HandleCreateShop(w http.ResponseWriter, r *http.Request) {
 	createReq := &proto.CreateShopRequest{}
 	err := app.readJSON(w, r, createReq)
@@ -79,7 +238,7 @@ func (app *application) HandleCreateShop(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	shop, err := app.grpcClient.CreateShop(app.ctx, createReq)
+	shop, err := app.grpcClient.CreateShop(r.Context(), createReq)
 	if err != nil {
 		app.errorResponse(w, r, http.StatusInternalServerError, err.Error())
 		return
@@ -99,7 +258,7 @@ func (app *application) HandleGetShop(w http.ResponseWriter, r *http.Request) {
 		Id: id,
 	}
 
-	shop, err := app.grpcClient.GetShopByID(app.ctx, &protoReq)
+	shop, err := app.grpcClient.GetShopByID(r.Context(), &protoReq)
 	if err != nil {
 		app.errorResponse(w, r, http.StatusInternalServerError, err.Error())
 		return
@@ -119,9 +278,9 @@ func (app *application) HandleCreateProduct(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	product, err := app.grpcClient.CreateProduct(app.ctx, createReq)
+	product, err := app.grpcClient.CreateProduct(r.Context(), createReq)
 	if err != nil {
-		app.errorResponse(w, r, http.StatusInternalServerError, err.Error())
+		app.grpcErrorResponse(w, r, err)
 		return
 	}
 
@@ -138,7 +297,7 @@ func (app *application) HandleGetProduct(w http.ResponseWriter, r *http.Request)
 		Id: id,
 	}
 
-	product, err := app.grpcClient.GetProductByID(app.ctx, &protoReq)
+	product, err := app.grpcClient.GetProductByID(r.Context(), &protoReq)
 	if err != nil {
 		app.errorResponse(w, r, http.StatusInternalServerError, err.Error())
 		return
@@ -158,7 +317,7 @@ func (app *application) HandleAddProductToShop(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	shop, err := app.grpcClient.AddServiceableProduct(app.ctx, addProduct)
+	shop, err := app.grpcClient.AddServiceableProduct(r.Context(), addProduct)
 	if err != nil {
 		app.errorResponse(w, r, http.StatusInternalServerError, err.Error())
 		return
@@ -182,7 +341,7 @@ func (app *application) HandleGetServiceableProducts(w http.ResponseWriter, r *h
 		ShopId: id,
 	}
 
-	products, err := app.grpcClient.GetServiceableProducts(app.ctx, &protoReq)
+	products, err := app.grpcClient.GetServiceableProducts(r.Context(), &protoReq)
 	if err != nil {
 		app.errorResponse(w, r, http.StatusInternalServerError, err.Error())
 		return
@@ -192,6 +351,26 @@ func (app *application) HandleGetServiceableProducts(w http.ResponseWriter, r *h
 	app.writeJSON(w, http.StatusOK, products)
 }
 
+func (app *application) HandleSetShopPrice(w http.ResponseWriter, r *http.Request) {
+	priceReq := &proto.SetShopPriceRequest{}
+	err := app.readJSON(w, r, priceReq)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	serviceableProduct, err := app.grpcClient.SetShopPrice(r.Context(), priceReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("set price of product[%s] for shop[%s]", priceReq.ProductId, priceReq.ShopId)
+	app.writeJSON(w, http.StatusOK, map[string]any{
+		"serviceableProduct": serviceableProduct,
+	})
+}
+
 func (app *application) HandleGetInventory(w http.ResponseWriter, r *http.Request) {
 	params := httprouter.ParamsFromContext(r.Context())
 
@@ -201,9 +380,10 @@ func (app *application) HandleGetInventory(w http.ResponseWriter, r *http.Reques
 	protoReq := proto.GetInventoryRequest{
 		ShopId:    shopId,
 		ProductId: productId,
+		VariantId: r.URL.Query().Get("variantId"),
 	}
 
-	inventory, err := app.grpcClient.GetInventory(app.ctx, &protoReq)
+	inventory, err := app.grpcClient.GetInventory(r.Context(), &protoReq)
 	if err != nil {
 		app.errorResponse(w, r, http.StatusInternalServerError, err.Error())
 		return
@@ -227,9 +407,9 @@ func (app *application) HandleUpdateInventory(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	inventory, err := app.grpcClient.UpdateInventory(app.ctx, updateReq)
+	inventory, err := app.grpcClient.UpdateInventory(r.Context(), updateReq)
 	if err != nil {
-		app.errorResponse(w, r, http.StatusInternalServerError, err.Error())
+		app.grpcErrorResponse(w, r, err)
 		return
 	}
 
@@ -247,7 +427,7 @@ func (app *application) HandleGetNearestNeighbour(w http.ResponseWriter, r *http
 		UserId: userId,
 	}
 
-	neighbour, err := app.grpcClient.GetNearestNeighbour(app.ctx, &protoReq)
+	neighbour, err := app.grpcClient.GetNearestNeighbour(r.Context(), &protoReq)
 	if err != nil {
 		app.errorResponse(w, r, http.StatusInternalServerError, err.Error())
 		return
@@ -273,7 +453,22 @@ func (app *application) HandleGetShopForUser(w http.ResponseWriter, r *http.Requ
 		MaxDistanceInKM: maxDist,
 	}
 
-	shop, err := app.grpcClient.GetShopForUser(app.ctx, &protoReq)
+	protoReq.IncludeShopsWithoutZones, err = readFlag(r.URL.Query(), "includeShopsWithoutZones")
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// openAt is either unix seconds or now
+	if openAt := r.URL.Query().Get("openAt"); openAt != "" {
+		protoReq.OpenAt, err = readUnixTime(openAt)
+		if err != nil {
+			app.errorResponse(w, r, http.StatusBadRequest, fmt.Sprintf("invalid openAt: %v", err))
+			return
+		}
+	}
+
+	shop, err := app.grpcClient.GetShopForUser(r.Context(), &protoReq)
 	if err != nil {
 		app.errorResponse(w, r, http.StatusInternalServerError, err.Error())
 		return
@@ -284,3 +479,1182 @@ func (app *application) HandleGetShopForUser(w http.ResponseWriter, r *http.Requ
 		"nearbyShops": shop,
 	})
 }
+
+func (app *application) HandlePlaceOrder(w http.ResponseWriter, r *http.Request) {
+	placeReq := &proto.PlaceOrderRequest{}
+	err := app.readJSON(w, r, placeReq)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	order, err := app.grpcClient.PlaceOrder(r.Context(), placeReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("placed order[%s] for user[%s] at shop[%s]", order.Id, order.UserId, order.ShopId)
+	app.writeJSON(w, http.StatusOK, map[string]any{
+		"order": order,
+	})
+}
+
+func (app *application) HandleGetOrder(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id := params.ByName("id")
+
+	protoReq := proto.GetRequest{
+		Id: id,
+	}
+
+	order, err := app.grpcClient.GetOrder(r.Context(), &protoReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("got order[%s]", id)
+	app.writeJSON(w, http.StatusOK, map[string]any{
+		"order": order,
+	})
+}
+
+func (app *application) HandleListOrdersForUser(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	userId := params.ByName("userId")
+
+	protoReq := proto.ListOrdersForUserRequest{
+		UserId: userId,
+	}
+
+	orders, err := app.grpcClient.ListOrdersForUser(r.Context(), &protoReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("got orders for user[%s]", userId)
+	app.writeJSON(w, http.StatusOK, orders)
+}
+
+func (app *application) HandleListOrdersForShop(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	shopId := params.ByName("shopId")
+
+	protoReq := proto.ListOrdersForShopRequest{
+		ShopId: shopId,
+	}
+
+	orders, err := app.grpcClient.ListOrdersForShop(r.Context(), &protoReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("got orders for shop[%s]", shopId)
+	app.writeJSON(w, http.StatusOK, orders)
+}
+
+func (app *application) HandleUpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
+	updateReq := &proto.UpdateOrderStatusRequest{}
+	err := app.readJSON(w, r, updateReq)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	order, err := app.grpcClient.UpdateOrderStatus(r.Context(), updateReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("moved order[%s] to %s", order.Id, order.Status)
+	app.writeJSON(w, http.StatusOK, map[string]any{
+		"updatedOrder": order,
+	})
+}
+
+func (app *application) HandleAddToCart(w http.ResponseWriter, r *http.Request) {
+	addReq := &proto.AddToCartRequest{}
+	err := app.readJSON(w, r, addReq)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	cart, err := app.grpcClient.AddToCart(r.Context(), addReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("added product[%s] of shop[%s] to cart of user[%s]", addReq.ProductId, addReq.ShopId, addReq.UserId)
+	app.writeJSON(w, http.StatusOK, map[string]any{
+		"cart": cart,
+	})
+}
+
+func (app *application) HandleRemoveFromCart(w http.ResponseWriter, r *http.Request) {
+	removeReq := &proto.RemoveFromCartRequest{}
+	err := app.readJSON(w, r, removeReq)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	cart, err := app.grpcClient.RemoveFromCart(r.Context(), removeReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("removed product[%s] of shop[%s] from cart of user[%s]", removeReq.ProductId, removeReq.ShopId, removeReq.UserId)
+	app.writeJSON(w, http.StatusOK, map[string]any{
+		"cart": cart,
+	})
+}
+
+func (app *application) HandleGetCart(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	userId := params.ByName("userId")
+
+	protoReq := proto.GetCartRequest{
+		UserId: userId,
+	}
+
+	cart, err := app.grpcClient.GetCart(r.Context(), &protoReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("got cart of user[%s]", userId)
+	app.writeJSON(w, http.StatusOK, map[string]any{
+		"cart": cart,
+	})
+}
+
+func (app *application) HandleCheckout(w http.ResponseWriter, r *http.Request) {
+	checkoutReq := &proto.CheckoutRequest{}
+	err := app.readJSON(w, r, checkoutReq)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	orders, err := app.grpcClient.Checkout(r.Context(), checkoutReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("checked out cart of user[%s] into %d orders", checkoutReq.UserId, len(orders.Orders))
+	app.writeJSON(w, http.StatusOK, orders)
+}
+
+func (app *application) HandleReserveStock(w http.ResponseWriter, r *http.Request) {
+	reserveReq := &proto.ReserveStockRequest{}
+	err := app.readJSON(w, r, reserveReq)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	reservation, err := app.grpcClient.ReserveStock(r.Context(), reserveReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("reserved %d of product[%s] in shop[%s] as reservation[%s]", reservation.Quantity, reservation.ProductId, reservation.ShopId, reservation.Id)
+	app.writeJSON(w, http.StatusOK, map[string]any{
+		"reservation": reservation,
+	})
+}
+
+func (app *application) HandleCommitReservation(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	id := params.ByName("id")
+
+	reservation, err := app.grpcClient.CommitReservation(r.Context(), &proto.GetRequest{Id: id})
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("committed reservation[%s]", id)
+	app.writeJSON(w, http.StatusOK, map[string]any{
+		"reservation": reservation,
+	})
+}
+
+func (app *application) HandleReleaseReservation(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	id := params.ByName("id")
+
+	reservation, err := app.grpcClient.ReleaseReservation(r.Context(), &proto.GetRequest{Id: id})
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("released reservation[%s]", id)
+	app.writeJSON(w, http.StatusOK, map[string]any{
+		"reservation": reservation,
+	})
+}
+
+func (app *application) HandleListInventoryMovements(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	shopId := params.ByName("shopId")
+	query := r.URL.Query()
+
+	protoReq := proto.ListInventoryMovementsRequest{
+		ShopId:    shopId,
+		ProductId: query.Get("productId"),
+		VariantId: query.Get("variantId"),
+	}
+
+	var err error
+	if from := query.Get("from"); from != "" {
+		protoReq.From, err = strconv.ParseInt(from, 10, 64)
+		if err != nil {
+			app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	if to := query.Get("to"); to != "" {
+		protoReq.To, err = strconv.ParseInt(to, 10, 64)
+		if err != nil {
+			app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	movements, err := app.grpcClient.ListInventoryMovements(r.Context(), &protoReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("got inventory movements for shop[%s]", shopId)
+	app.writeJSON(w, http.StatusOK, movements)
+}
+
+func (app *application) HandleGetStockAt(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	shopId := params.ByName("shopId")
+	productId := params.ByName("productId")
+	at, err := strconv.ParseInt(params.ByName("at"), 10, 64)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	protoReq := proto.GetStockAtRequest{
+		ShopId:    shopId,
+		ProductId: productId,
+		VariantId: r.URL.Query().Get("variantId"),
+		At:        at,
+	}
+
+	stock, err := app.grpcClient.GetStockAt(r.Context(), &protoReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("got stock of product[%s] for shop[%s] at %d", productId, shopId, at)
+	app.writeJSON(w, http.StatusOK, map[string]any{
+		"stock": stock,
+	})
+}
+
+func (app *application) HandleTransferStock(w http.ResponseWriter, r *http.Request) {
+	transferReq := &proto.TransferStockRequest{}
+	err := app.readJSON(w, r, transferReq)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	transfer, err := app.grpcClient.TransferStock(r.Context(), transferReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("transferred %d of product[%s] from shop[%s] to shop[%s]", transfer.Quantity, transfer.ProductId, transfer.FromShopId, transfer.ToShopId)
+	app.writeJSON(w, http.StatusOK, map[string]any{
+		"transfer": transfer,
+	})
+}
+
+func (app *application) HandleSetReorderThreshold(w http.ResponseWriter, r *http.Request) {
+	thresholdReq := &proto.SetReorderThresholdRequest{}
+	err := app.readJSON(w, r, thresholdReq)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	inventory, err := app.grpcClient.SetReorderThreshold(r.Context(), thresholdReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("set reorder threshold of product[%s] for shop[%s] to %d", thresholdReq.ProductId, thresholdReq.ShopId, thresholdReq.ReorderThreshold)
+	app.writeJSON(w, http.StatusOK, map[string]any{
+		"updatedInventory": inventory,
+	})
+}
+
+func (app *application) HandleListLowStock(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	shopId := params.ByName("shopId")
+
+	protoReq := proto.ListLowStockRequest{
+		ShopId: shopId,
+	}
+
+	inventories, err := app.grpcClient.ListLowStock(r.Context(), &protoReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("got low stock inventories for shop[%s]", shopId)
+	app.writeJSON(w, http.StatusOK, inventories)
+}
+
+// HandleBulkUpdateInventory accepts a csv or jsonl upload of inventory rows and
+// streams them to the grpc server, responding with a report for every row.
+func (app *application) HandleBulkUpdateInventory(w http.ResponseWriter, r *http.Request) {
+	format, err := bulkFormat(r, "")
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	stream, err := app.grpcClient.BulkUpdateInventory(ctx)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	rejected, err := readInventoryRows(format, r.Body, stream.Send)
+	if err != nil {
+		// a failed Send only reports io.EOF, the cause comes with the response
+		if _, recvErr := stream.CloseAndRecv(); recvErr != nil {
+			app.grpcErrorResponse(w, r, recvErr)
+			return
+		}
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report, err := stream.CloseAndRecv()
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	report.Failed += int32(len(rejected))
+	report.Results = append(report.Results, rejected...)
+	sort.SliceStable(report.Results, func(i, j int) bool {
+		return report.Results[i].Line < report.Results[j].Line
+	})
+
+	app.logger.Printf("bulk updated inventory: %d rows succeeded, %d failed", report.Succeeded, report.Failed)
+	app.writeJSON(w, http.StatusOK, report)
+}
+
+func (app *application) HandleExportInventory(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	shopId := params.ByName("shopId")
+
+	format, err := bulkFormat(r, bulkFormatCSV)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	protoReq := proto.ListShopInventoryRequest{
+		ShopId: shopId,
+	}
+
+	inventories, err := app.grpcClient.ListShopInventory(r.Context(), &protoReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	contentType := "text/csv"
+	write := writeInventoryCSV
+	if format == bulkFormatJSONL {
+		contentType = "application/x-ndjson"
+		write = writeInventoryJSONL
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"inventory-%s.%s\"", shopId, format))
+	w.WriteHeader(http.StatusOK)
+
+	if err := write(w, inventories.Inventories); err != nil {
+		app.logger.Println("Error: ", err)
+		return
+	}
+
+	app.logger.Printf("exported inventory of shop[%s] as %s", shopId, format)
+}
+
+func (app *application) HandleGetKNearestUsers(w http.ResponseWriter, r *http.Request) {
+	protoReq, err := readKNearestRequest(r)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	users, err := app.grpcClient.GetKNearestUsers(r.Context(), protoReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("got the %d nearest users", len(users.Users))
+	app.writeJSON(w, http.StatusOK, map[string]any{
+		"nearestUsers": users.Users,
+	})
+}
+
+func (app *application) HandleGetKNearestShops(w http.ResponseWriter, r *http.Request) {
+	protoReq, err := readKNearestRequest(r)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	shops, err := app.grpcClient.GetKNearestShops(r.Context(), protoReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("got the %d nearest shops", len(shops.Shops))
+	app.writeJSON(w, http.StatusOK, map[string]any{
+		"nearestShops": shops.Shops,
+	})
+}
+
+// readKNearestRequest reads the userId or lat and lng, k and maxDist query
+// parameters of a k nearest request.
+func readKNearestRequest(r *http.Request) (*proto.KNearestRequest, error) {
+	query := r.URL.Query()
+	protoReq := &proto.KNearestRequest{
+		UserId: query.Get("userId"),
+	}
+
+	k, err := strconv.ParseInt(query.Get("k"), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid k: %w", err)
+	}
+	protoReq.K = int32(k)
+
+	if maxDist := query.Get("maxDist"); maxDist != "" {
+		protoReq.MaxDistanceInKM, err = strconv.ParseFloat(maxDist, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid maxDist: %w", err)
+		}
+	}
+
+	protoReq.Coordinates, err = readCoordinates(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return protoReq, nil
+}
+
+// readCoordinates reads the lat and lng query parameters, returning nil when
+// neither is set.
+func readCoordinates(query url.Values) (*proto.Coordinates, error) {
+	if !query.Has("lat") && !query.Has("lng") {
+		return nil, nil
+	}
+
+	lat, err := strconv.ParseFloat(query.Get("lat"), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid lat: %w", err)
+	}
+
+	lng, err := strconv.ParseFloat(query.Get("lng"), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid lng: %w", err)
+	}
+
+	return &proto.Coordinates{
+		Latitude:  lat,
+		Longitude: lng,
+	}, nil
+}
+
+// readFlag reads a boolean query parameter, false when missing.
+func readFlag(query url.Values, name string) (bool, error) {
+	value := query.Get(name)
+	if value == "" {
+		return false, nil
+	}
+
+	flag, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return flag, nil
+}
+
+func (app *application) HandleSearchNearbyShops(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	protoReq := proto.SearchNearbyShopsRequest{
+		UserId:     query.Get("userId"),
+		ProductIds: query["productId"],
+		Sort:       query.Get("sort"),
+		PageToken:  query.Get("pageToken"),
+	}
+
+	var err error
+	protoReq.Coordinates, err = readCoordinates(query)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	protoReq.IncludeShopsWithoutZones, err = readFlag(query, "includeShopsWithoutZones")
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if maxDist := query.Get("maxDist"); maxDist != "" {
+		protoReq.MaxDistanceInKM, err = strconv.ParseFloat(maxDist, 64)
+		if err != nil {
+			app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	for name, field := range map[string]*int32{"minQuantity": &protoReq.MinQuantity, "pageSize": &protoReq.PageSize} {
+		if value := query.Get(name); value != "" {
+			parsed, err := strconv.ParseInt(value, 10, 32)
+			if err != nil {
+				app.errorResponse(w, r, http.StatusBadRequest, fmt.Sprintf("invalid %s: %v", name, err))
+				return
+			}
+			*field = int32(parsed)
+		}
+	}
+
+	shops, err := app.grpcClient.SearchNearbyShops(r.Context(), &protoReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("found %d nearby shops", len(shops.Shops))
+	app.writeJSON(w, http.StatusOK, shops)
+}
+
+// HandleSearchShopsInArea expects the area either as bbox=west,south,east,north
+// or as polygon=lat,lng;lat,lng;... with the vertices in order.
+func (app *application) HandleSearchShopsInArea(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	protoReq := proto.SearchShopsInAreaRequest{}
+
+	if bbox := query.Get("bbox"); bbox != "" {
+		edges, err := parseFloats(bbox, ",")
+		if err != nil || len(edges) != 4 {
+			app.errorResponse(w, r, http.StatusBadRequest, "bbox must be west,south,east,north")
+			return
+		}
+
+		protoReq.BoundingBox = &proto.BoundingBox{
+			SouthWest: &proto.Coordinates{Latitude: edges[1], Longitude: edges[0]},
+			NorthEast: &proto.Coordinates{Latitude: edges[3], Longitude: edges[2]},
+		}
+	}
+
+	if polygon := query.Get("polygon"); polygon != "" {
+		for _, vertex := range strings.Split(polygon, ";") {
+			coordinates, err := parseFloats(vertex, ",")
+			if err != nil || len(coordinates) != 2 {
+				app.errorResponse(w, r, http.StatusBadRequest, "polygon must be a list of lat,lng separated by ;")
+				return
+			}
+
+			protoReq.Polygon = append(protoReq.Polygon, &proto.Coordinates{
+				Latitude:  coordinates[0],
+				Longitude: coordinates[1],
+			})
+		}
+	}
+
+	for name, field := range map[string]*int32{"limit": &protoReq.Limit, "gridSize": &protoReq.GridSize} {
+		if value := query.Get(name); value != "" {
+			parsed, err := strconv.ParseInt(value, 10, 32)
+			if err != nil {
+				app.errorResponse(w, r, http.StatusBadRequest, fmt.Sprintf("invalid %s: %v", name, err))
+				return
+			}
+			*field = int32(parsed)
+		}
+	}
+
+	shops, err := app.grpcClient.SearchShopsInArea(r.Context(), &protoReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("found %d shops in area", shops.Total)
+	app.writeJSON(w, http.StatusOK, shops)
+}
+
+func (app *application) HandleSearchProducts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	protoReq := proto.SearchProductsRequest{
+		Query:      query.Get("q"),
+		CategoryId: query.Get("categoryId"),
+		UserId:     query.Get("userId"),
+		PageToken:  query.Get("pageToken"),
+	}
+
+	// bounds are given in major units, e.g. minPrice=19.99&currency=USD
+	for name, field := range map[string]**proto.Money{"minPrice": &protoReq.MinPrice, "maxPrice": &protoReq.MaxPrice} {
+		if value := query.Get(name); value != "" {
+			price, err := parseAmount(value, query.Get("currency"))
+			if err != nil {
+				app.errorResponse(w, r, http.StatusBadRequest, fmt.Sprintf("invalid %s: %v", name, err))
+				return
+			}
+			*field = price
+		}
+	}
+
+	if maxDist := query.Get("maxDist"); maxDist != "" {
+		var err error
+		protoReq.MaxDistanceInKM, err = strconv.ParseFloat(maxDist, 64)
+		if err != nil {
+			app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	var err error
+	protoReq.IncludeShopsWithoutZones, err = readFlag(query, "includeShopsWithoutZones")
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if pageSize := query.Get("pageSize"); pageSize != "" {
+		parsed, err := strconv.ParseInt(pageSize, 10, 32)
+		if err != nil {
+			app.errorResponse(w, r, http.StatusBadRequest, fmt.Sprintf("invalid pageSize: %v", err))
+			return
+		}
+		protoReq.PageSize = int32(parsed)
+	}
+
+	products, err := app.grpcClient.SearchProducts(r.Context(), &protoReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("found %d products for %q", len(products.Products), protoReq.Query)
+	app.writeJSON(w, http.StatusOK, products)
+}
+
+func (app *application) HandleSetProductCategories(w http.ResponseWriter, r *http.Request) {
+	categoriesReq := &proto.SetProductCategoriesRequest{}
+	err := app.readJSON(w, r, categoriesReq)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	product, err := app.grpcClient.SetProductCategories(r.Context(), categoriesReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("set categories of product[%s]", categoriesReq.ProductId)
+	app.writeJSON(w, http.StatusOK, map[string]any{
+		"product": product,
+	})
+}
+
+func (app *application) HandleAddProductVariant(w http.ResponseWriter, r *http.Request) {
+	variantReq := &proto.AddProductVariantRequest{}
+	err := app.readJSON(w, r, variantReq)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	product, err := app.grpcClient.AddProductVariant(r.Context(), variantReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("added variant to product[%s]", variantReq.ProductId)
+	app.writeJSON(w, http.StatusOK, map[string]any{
+		"product": product,
+	})
+}
+
+func (app *application) HandleCreateCategory(w http.ResponseWriter, r *http.Request) {
+	createReq := &proto.CreateCategoryRequest{}
+	err := app.readJSON(w, r, createReq)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	category, err := app.grpcClient.CreateCategory(r.Context(), createReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("new category created %+v", category)
+	app.writeJSON(w, http.StatusOK, map[string]any{
+		"category": category,
+	})
+}
+
+func (app *application) HandleListCategories(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	protoReq := proto.ListCategoriesRequest{
+		ParentId: query.Get("parentId"),
+	}
+
+	if recursive := query.Get("recursive"); recursive != "" {
+		var err error
+		protoReq.Recursive, err = strconv.ParseBool(recursive)
+		if err != nil {
+			app.errorResponse(w, r, http.StatusBadRequest, fmt.Sprintf("invalid recursive: %v", err))
+			return
+		}
+	}
+
+	categories, err := app.grpcClient.ListCategories(r.Context(), &protoReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("listed %d categories", len(categories.Categories))
+	app.writeJSON(w, http.StatusOK, categories)
+}
+
+func (app *application) HandleListProductsInCategory(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	categoryId := params.ByName("categoryId")
+
+	query := r.URL.Query()
+	protoReq := proto.ListProductsInCategoryRequest{
+		CategoryId: categoryId,
+		PageToken:  query.Get("pageToken"),
+	}
+
+	if pageSize := query.Get("pageSize"); pageSize != "" {
+		parsed, err := strconv.ParseInt(pageSize, 10, 32)
+		if err != nil {
+			app.errorResponse(w, r, http.StatusBadRequest, fmt.Sprintf("invalid pageSize: %v", err))
+			return
+		}
+		protoReq.PageSize = int32(parsed)
+	}
+
+	products, err := app.grpcClient.ListProductsInCategory(r.Context(), &protoReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("got products in category[%s]", categoryId)
+	app.writeJSON(w, http.StatusOK, products)
+}
+
+func (app *application) HandleSetOperatingHours(w http.ResponseWriter, r *http.Request) {
+	hoursReq := &proto.SetOperatingHoursRequest{}
+	err := app.readJSON(w, r, hoursReq)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	shop, err := app.grpcClient.SetOperatingHours(r.Context(), hoursReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("set operating hours for shop[%s]", hoursReq.ShopId)
+	app.writeJSON(w, http.StatusOK, map[string]any{
+		"updatedShop": shop,
+	})
+}
+
+func (app *application) HandleIsShopOpen(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	shopId := params.ByName("shopId")
+
+	protoReq := proto.IsShopOpenRequest{
+		ShopId: shopId,
+	}
+
+	if at := r.URL.Query().Get("at"); at != "" {
+		var err error
+		protoReq.At, err = readUnixTime(at)
+		if err != nil {
+			app.errorResponse(w, r, http.StatusBadRequest, fmt.Sprintf("invalid at: %v", err))
+			return
+		}
+	}
+
+	openStatus, err := app.grpcClient.IsShopOpen(r.Context(), &protoReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("got open status of shop[%s]", shopId)
+	app.writeJSON(w, http.StatusOK, openStatus)
+}
+
+func (app *application) HandleSetDeliveryZones(w http.ResponseWriter, r *http.Request) {
+	zonesReq := &proto.SetDeliveryZonesRequest{}
+	err := app.readJSON(w, r, zonesReq)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	zones, err := app.grpcClient.SetDeliveryZones(r.Context(), zonesReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("set %d delivery zones for shop[%s]", len(zones.Zones), zonesReq.ShopId)
+	app.writeJSON(w, http.StatusOK, zones)
+}
+
+func (app *application) HandleGetDeliveryZones(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	shopId := params.ByName("shopId")
+
+	protoReq := proto.GetDeliveryZonesRequest{
+		ShopId: shopId,
+	}
+
+	zones, err := app.grpcClient.GetDeliveryZones(r.Context(), &protoReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("got delivery zones for shop[%s]", shopId)
+	app.writeJSON(w, http.StatusOK, zones)
+}
+
+func (app *application) HandleCreatePromotion(w http.ResponseWriter, r *http.Request) {
+	createReq := &proto.CreatePromotionRequest{}
+	err := app.readJSON(w, r, createReq)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	promotion, err := app.grpcClient.CreatePromotion(r.Context(), createReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("created promotion[%s]", promotion.Id)
+	app.writeJSON(w, http.StatusOK, map[string]any{
+		"promotion": promotion,
+	})
+}
+
+func (app *application) HandleValidateCoupon(w http.ResponseWriter, r *http.Request) {
+	validateReq := &proto.ValidateCouponRequest{}
+	err := app.readJSON(w, r, validateReq)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	validation, err := app.grpcClient.ValidateCoupon(r.Context(), validateReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("validated coupon %s for user[%s]", validateReq.Code, validateReq.UserId)
+	app.writeJSON(w, http.StatusOK, map[string]any{
+		"validation": validation,
+	})
+}
+
+func (app *application) HandleSetTaxRule(w http.ResponseWriter, r *http.Request) {
+	ruleReq := &proto.TaxRule{}
+	err := app.readJSON(w, r, ruleReq)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rule, err := app.grpcClient.SetTaxRule(r.Context(), ruleReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("set tax rule[%s]", rule.Id)
+	app.writeJSON(w, http.StatusOK, map[string]any{
+		"taxRule": rule,
+	})
+}
+
+func (app *application) HandleListTaxRules(w http.ResponseWriter, r *http.Request) {
+	protoReq := proto.ListTaxRulesRequest{
+		Region: r.URL.Query().Get("region"),
+	}
+
+	rules, err := app.grpcClient.ListTaxRules(r.Context(), &protoReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("got %d tax rules", len(rules.Rules))
+	app.writeJSON(w, http.StatusOK, rules)
+}
+
+func (app *application) HandleDeleteTaxRule(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	id := params.ByName("id")
+
+	rule, err := app.grpcClient.DeleteTaxRule(r.Context(), &proto.GetRequest{Id: id})
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("deleted tax rule[%s]", id)
+	app.writeJSON(w, http.StatusOK, map[string]any{
+		"taxRule": rule,
+	})
+}
+
+func (app *application) HandleSchedulePriceChange(w http.ResponseWriter, r *http.Request) {
+	scheduleReq := &proto.SchedulePriceChangeRequest{}
+	err := app.readJSON(w, r, scheduleReq)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	schedule, err := app.grpcClient.SchedulePriceChange(r.Context(), scheduleReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("scheduled price change[%s] of product[%s]", schedule.Id, schedule.ProductId)
+	app.writeJSON(w, http.StatusOK, map[string]any{
+		"scheduledPriceChange": schedule,
+	})
+}
+
+func (app *application) HandleGetPriceHistory(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	productId := params.ByName("productId")
+	query := r.URL.Query()
+
+	protoReq := proto.GetPriceHistoryRequest{
+		ProductId: productId,
+		VariantId: query.Get("variantId"),
+		ShopId:    query.Get("shopId"),
+		PageToken: query.Get("pageToken"),
+	}
+
+	for name, field := range map[string]*int64{"from": &protoReq.From, "to": &protoReq.To} {
+		if value := query.Get(name); value != "" {
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				app.errorResponse(w, r, http.StatusBadRequest, fmt.Sprintf("invalid %s: %v", name, err))
+				return
+			}
+			*field = parsed
+		}
+	}
+
+	if pageSize := query.Get("pageSize"); pageSize != "" {
+		parsed, err := strconv.ParseInt(pageSize, 10, 32)
+		if err != nil {
+			app.errorResponse(w, r, http.StatusBadRequest, fmt.Sprintf("invalid pageSize: %v", err))
+			return
+		}
+		protoReq.PageSize = int32(parsed)
+	}
+
+	history, err := app.grpcClient.GetPriceHistory(r.Context(), &protoReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("got price history of product[%s]", productId)
+	app.writeJSON(w, http.StatusOK, history)
+}
+
+func (app *application) HandleInviteStaff(w http.ResponseWriter, r *http.Request) {
+	inviteReq := &proto.InviteStaffRequest{}
+	err := app.readJSON(w, r, inviteReq)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	invitation, err := app.grpcClient.InviteStaff(r.Context(), inviteReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("invited %s to shop[%s]", invitation.Email, invitation.ShopId)
+	app.writeJSON(w, http.StatusOK, map[string]any{
+		"invitation": invitation,
+	})
+}
+
+func (app *application) HandleRevokeStaffInvitation(w http.ResponseWriter, r *http.Request) {
+	app.handleStaffInvitation(w, r, "revoked", app.grpcClient.RevokeStaffInvitation)
+}
+
+func (app *application) HandleAcceptStaffInvitation(w http.ResponseWriter, r *http.Request) {
+	app.handleStaffInvitation(w, r, "accepted", app.grpcClient.AcceptStaffInvitation)
+}
+
+func (app *application) HandleDeclineStaffInvitation(w http.ResponseWriter, r *http.Request) {
+	app.handleStaffInvitation(w, r, "declined", app.grpcClient.DeclineStaffInvitation)
+}
+
+// handleStaffInvitation calls one of the methods acting on the invitation
+// named in the path.
+func (app *application) handleStaffInvitation(w http.ResponseWriter, r *http.Request, action string,
+	call func(ctx context.Context, req *proto.GetRequest, opts ...grpc.CallOption) (*proto.StaffInvitation, error),
+) {
+	params := httprouter.ParamsFromContext(r.Context())
+	id := params.ByName("id")
+
+	invitation, err := call(r.Context(), &proto.GetRequest{Id: id})
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("%s staff invitation[%s]", action, id)
+	app.writeJSON(w, http.StatusOK, map[string]any{
+		"invitation": invitation,
+	})
+}
+
+func (app *application) HandleListMyInvitations(w http.ResponseWriter, r *http.Request) {
+	invitations, err := app.grpcClient.ListMyInvitations(r.Context(), &proto.ListMyInvitationsRequest{})
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("got %d staff invitations", len(invitations.Invitations))
+	app.writeJSON(w, http.StatusOK, invitations)
+}
+
+func (app *application) HandleListShopStaff(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	shopId := params.ByName("shopId")
+
+	staff, err := app.grpcClient.ListShopStaff(r.Context(), &proto.ListShopStaffRequest{ShopId: shopId})
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("got staff of shop[%s]", shopId)
+	app.writeJSON(w, http.StatusOK, staff)
+}
+
+func (app *application) HandleSetStaffPermissions(w http.ResponseWriter, r *http.Request) {
+	permissionsReq := &proto.SetStaffPermissionsRequest{}
+	err := app.readJSON(w, r, permissionsReq)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	member, err := app.grpcClient.SetStaffPermissions(r.Context(), permissionsReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("set permissions of user[%s] in shop[%s]", permissionsReq.UserId, permissionsReq.ShopId)
+	app.writeJSON(w, http.StatusOK, map[string]any{
+		"staff": member,
+	})
+}
+
+func (app *application) HandleRemoveStaff(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	protoReq := proto.RemoveStaffRequest{
+		ShopId: params.ByName("shopId"),
+		UserId: params.ByName("userId"),
+	}
+
+	member, err := app.grpcClient.RemoveStaff(r.Context(), &protoReq)
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("removed user[%s] from the staff of shop[%s]", protoReq.UserId, protoReq.ShopId)
+	app.writeJSON(w, http.StatusOK, map[string]any{
+		"staff": member,
+	})
+}
+
+func (app *application) HandleListMyShops(w http.ResponseWriter, r *http.Request) {
+	shops, err := app.grpcClient.ListMyShops(r.Context(), &proto.ListMyShopsRequest{})
+	if err != nil {
+		app.grpcErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Printf("got %d shops", len(shops.Shops))
+	app.writeJSON(w, http.StatusOK, shops)
+}